@@ -3,10 +3,10 @@ package main
 import (
 	"errors"
 	"strings"
-
-	"github.com/aws/aws-sdk-go/aws"
 )
 
+// asgParams is a flag which holds a comma separated list, e.g. of auto-scaling group names or regions. It can
+// only be set once.
 type asgParams []string
 
 func (a *asgParams) String() string {
@@ -15,7 +15,7 @@ func (a *asgParams) String() string {
 
 func (a *asgParams) Set(value string) error {
 	if len(*a) > 0 {
-		return errors.New("autoScalingGroups flag already set")
+		return errors.New("flag already set")
 	}
 	for _, v := range strings.Split(value, ",") {
 		*a = append(*a, v)
@@ -23,14 +23,3 @@ func (a *asgParams) Set(value string) error {
 
 	return nil
 }
-
-func (a *asgParams) ToAwsStrings() []*string {
-	var b = []string(*a)
-	var result = make([]*string, len(b))
-
-	for i, str := range b {
-		result[i] = aws.String(str)
-	}
-
-	return result
-}
@@ -19,3 +19,33 @@ func TestAutoscalingGroups(t *testing.T) {
 		t.Error("Lost data during conversion.")
 	}
 }
+
+func TestAutoscalingGroupsCanOnlyBeSetOnce(t *testing.T) {
+	grps := asgParams{}
+
+	if err := grps.Set("a,b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := grps.Set("c"); err == nil {
+		t.Error("Expected setting the flag again to be rejected.")
+	}
+
+	if grps.String() != "a,b" {
+		t.Errorf("Expected the first value to be kept, but got %q", grps.String())
+	}
+}
+
+func TestAutoscalingGroupsRoundTrip(t *testing.T) {
+	grps := asgParams{}
+	grps.Set("asg_api,asg_web")
+
+	copied := asgParams{}
+	if err := copied.Set(grps.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(copied) != 2 || copied[0] != "asg_api" || copied[1] != "asg_web" {
+		t.Errorf("Expected [asg_api asg_web], but got %v", copied)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// fileConfig is the YAML document at --config. The keys are the names of the flags, and flags which are set on
+// the command line override the values in the file, e.g.
+//
+//	region: eu-west-2
+//	autoScalingGroups: [asg_api, asg_web]
+//	canonical: 1.2.0
+type fileConfig struct {
+	Region                   *string  `yaml:"region"`
+	Regions                  []string `yaml:"regions"`
+	AWSEndpoint              *string  `yaml:"awsEndpoint"`
+	Profile                  *string  `yaml:"profile"`
+	ECSCluster               *string  `yaml:"ecsCluster"`
+	AssumeRoleARN            *string  `yaml:"assumeRoleArn"`
+	ExternalID               *string  `yaml:"externalId"`
+	IsDryRun                 *bool    `yaml:"isDryRun"`
+	Scheme                   *string  `yaml:"scheme"`
+	Port                     *int     `yaml:"port"`
+	VersionURL               *string  `yaml:"path"`
+	VersionSource            *string  `yaml:"versionSource"`
+	VersionTagKey            *string  `yaml:"versionTagKey"`
+	VersionScheme            *string  `yaml:"versionScheme"`
+	MinimumInstanceCount     *int     `yaml:"minimumInstanceCount"`
+	MinimumHealthyPercentage *float64 `yaml:"minimumHealthyPercentage"`
+	MaxTerminations          *int     `yaml:"maxTerminations"`
+	MaxProbeFailureRate      *float64 `yaml:"maxProbeFailureRate"`
+	Canonical                *string  `yaml:"canonical"`
+	CanonicalRange           *string  `yaml:"canonicalRange"`
+	CanonicalURL             *string  `yaml:"canonicalURL"`
+	Mode                     *string  `yaml:"mode"`
+	TerminateDirection       *string  `yaml:"terminateDirection"`
+	AutoScalingGroups        []string `yaml:"autoScalingGroups"`
+	AutoScalingGroupsRegex   *string  `yaml:"autoScalingGroupsRegex"`
+	ExcludeAutoScalingGroups []string `yaml:"excludeAutoScalingGroups"`
+	SelectorTag              *string  `yaml:"selectorTag"`
+	TerminationBatchSize     *int     `yaml:"terminationBatchSize"`
+	TerminationCooldown      *string  `yaml:"terminationCooldown"`
+	RunTimeout               *string  `yaml:"runTimeout"`
+	Rolling                  *bool    `yaml:"rolling"`
+	Canary                   *bool    `yaml:"canary"`
+	SNSTopicARN              *string  `yaml:"snsTopicArn"`
+	SlackWebhookURL          *string  `yaml:"slackWebhookURL"`
+	LogLevel                 *string  `yaml:"logLevel"`
+	LogFormat                *string  `yaml:"logFormat"`
+	FreezeWindows            []string `yaml:"freezeWindows"`
+	// GroupConfig is the canonical version and minimum instance count of individual groups, e.g.
+	//
+	//	groupConfig:
+	//	  asg_api:
+	//	    canonical: 1.3.0
+	//	    minimumInstanceCount: 3
+	GroupConfig map[string]groupConfig `yaml:"groupConfig"`
+}
+
+// conflictingFlags are the pairs of flags which can't be used together.
+var conflictingFlags = [][2]string{
+	{"region", "regions"},
+	{"canonical", "canonicalRange"},
+	{"canonical", "canonicalURL"},
+	{"canonicalRange", "canonicalURL"},
+	{"autoScalingGroups", "autoScalingGroupsRegex"},
+}
+
+// loadConfigFile reads the YAML configuration file. Unknown keys are rejected, so that typos aren't ignored.
+func loadConfigFile(path string) (c fileConfig, err error) {
+	b, err := os.ReadFile(path)
+
+	if err != nil {
+		return c, err
+	}
+
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s, %v", path, err)
+	}
+
+	return c, nil
+}
+
+// apply sets the flags to the values in the file, unless they, or a flag they can't be used with, were set on the
+// command line. The values are validated by the flags in the same way as command-line arguments.
+func (c fileConfig) apply(fs *flag.FlagSet) error {
+	values := map[string]string{}
+	setString := func(name string, v *string) {
+		if v != nil {
+			values[name] = *v
+		}
+	}
+	setBool := func(name string, v *bool) {
+		if v != nil {
+			values[name] = strconv.FormatBool(*v)
+		}
+	}
+	setInt := func(name string, v *int) {
+		if v != nil {
+			values[name] = strconv.Itoa(*v)
+		}
+	}
+	setList := func(name string, v []string) {
+		if len(v) > 0 {
+			values[name] = strings.Join(v, ",")
+		}
+	}
+
+	setString("region", c.Region)
+	setList("regions", c.Regions)
+	setString("awsEndpoint", c.AWSEndpoint)
+	setString("profile", c.Profile)
+	setString("ecsCluster", c.ECSCluster)
+	setString("assumeRoleArn", c.AssumeRoleARN)
+	setString("externalId", c.ExternalID)
+	setBool("isDryRun", c.IsDryRun)
+	setString("scheme", c.Scheme)
+	setInt("port", c.Port)
+	setString("path", c.VersionURL)
+	setString("versionSource", c.VersionSource)
+	setString("versionTagKey", c.VersionTagKey)
+	setString("versionScheme", c.VersionScheme)
+	setInt("minimumInstanceCount", c.MinimumInstanceCount)
+	if c.MinimumHealthyPercentage != nil {
+		values["minimumHealthyPercentage"] = strconv.FormatFloat(*c.MinimumHealthyPercentage, 'g', -1, 64)
+	}
+	setInt("maxTerminations", c.MaxTerminations)
+	if c.MaxProbeFailureRate != nil {
+		values["maxProbeFailureRate"] = strconv.FormatFloat(*c.MaxProbeFailureRate, 'g', -1, 64)
+	}
+	setString("canonical", c.Canonical)
+	setString("canonicalRange", c.CanonicalRange)
+	setString("canonicalURL", c.CanonicalURL)
+	setString("mode", c.Mode)
+	setString("terminateDirection", c.TerminateDirection)
+	setList("autoScalingGroups", c.AutoScalingGroups)
+	setString("autoScalingGroupsRegex", c.AutoScalingGroupsRegex)
+	setList("excludeAutoScalingGroups", c.ExcludeAutoScalingGroups)
+	setString("selectorTag", c.SelectorTag)
+	setInt("terminationBatchSize", c.TerminationBatchSize)
+	setString("terminationCooldown", c.TerminationCooldown)
+	setString("runTimeout", c.RunTimeout)
+	setBool("rolling", c.Rolling)
+	setBool("canary", c.Canary)
+	setString("snsTopicArn", c.SNSTopicARN)
+	setString("slackWebhookURL", c.SlackWebhookURL)
+	setString("logLevel", c.LogLevel)
+	setString("logFormat", c.LogFormat)
+	setList("freezeWindow", c.FreezeWindows)
+	if len(c.GroupConfig) > 0 {
+		b, err := json.Marshal(c.GroupConfig)
+		if err != nil {
+			return fmt.Errorf("invalid groupConfig, %v", err)
+		}
+		values["groupConfig"] = string(b)
+	}
+
+	setOnCommandLine := commandLineFlags(fs)
+
+	// A value which conflicts with a flag set on the command line is left out, so the command line wins.
+	for _, pair := range conflictingFlags {
+		if setOnCommandLine[pair[0]] {
+			delete(values, pair[1])
+		}
+		if setOnCommandLine[pair[1]] {
+			delete(values, pair[0])
+		}
+	}
+
+	for _, pair := range conflictingFlags {
+		_, first := values[pair[0]]
+		_, second := values[pair[1]]
+		if first && second {
+			return fmt.Errorf("invalid %s, it can't be used with %s", pair[1], pair[0])
+		}
+	}
+
+	for name, value := range values {
+		if setOnCommandLine[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s %q, %v", name, value, err)
+		}
+	}
+
+	return nil
+}
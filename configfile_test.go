@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "terminator.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFileIsOverriddenByFlags(t *testing.T) {
+	path := writeConfigFile(t, `
+region: eu-west-2
+port: 8080
+isDryRun: false
+canonical: 1.2.0
+autoScalingGroups:
+  - asg_api
+  - asg_web
+`)
+
+	fs := flag.NewFlagSet("terminator", flag.ContinueOnError)
+	region := fs.String("region", "eu-west-1", "")
+	port := fs.Int("port", 80, "")
+	isDryRun := fs.Bool("isDryRun", true, "")
+	canonical := fs.String("canonical", "1.0.0", "")
+	scheme := fs.String("scheme", "http", "")
+	var groups asgParams
+	fs.Var(&groups, "autoScalingGroups", "")
+
+	if err := fs.Parse([]string{"-canonical=1.3.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.apply(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if *canonical != "1.3.0" {
+		t.Errorf("Expected the canonical flag to override the file, but got %s", *canonical)
+	}
+	if *region != "eu-west-2" || *port != 8080 || *isDryRun {
+		t.Errorf("Expected the region, port and isDryRun to be read from the file, but got %s, %d, %v", *region, *port, *isDryRun)
+	}
+	if expected := []string{"asg_api", "asg_web"}; !reflect.DeepEqual([]string(groups), expected) {
+		t.Errorf("Expected the groups %v to be read from the file, but got %v", expected, groups)
+	}
+	if *scheme != "http" {
+		t.Errorf("Expected values missing from the file to keep their defaults, but got %s", *scheme)
+	}
+}
+
+func TestConfigFileGroupConfig(t *testing.T) {
+	path := writeConfigFile(t, `
+canonical: 1.2.0
+groupConfig:
+  asg_api:
+    canonical: 1.3.0
+  asg_web:
+    minimumInstanceCount: 3
+`)
+
+	fs := flag.NewFlagSet("terminator", flag.ContinueOnError)
+	fs.String("canonical", "1.0.0", "")
+	configs := groupConfigParams{}
+	fs.Var(configs, "groupConfig", "")
+
+	c, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.apply(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if configs["asg_api"].Canonical != "1.3.0" || configs["asg_api"].MinimumInstanceCount != nil {
+		t.Errorf("Expected the canonical version of asg_api to be overridden, but got %+v", configs["asg_api"])
+	}
+	if n := configs["asg_web"].MinimumInstanceCount; n == nil || *n != 3 {
+		t.Errorf("Expected the minimum instance count of asg_web to be overridden, but got %+v", configs["asg_web"])
+	}
+}
+
+func TestInvalidConfigFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{
+			name:          "Unknown keys are rejected.",
+			content:       "regoin: eu-west-2\n",
+			expectedError: "failed to parse",
+		},
+		{
+			name:          "Values of the wrong type are rejected.",
+			content:       "port: eighty\n",
+			expectedError: "failed to parse",
+		},
+		{
+			name:          "Values which the flag rejects are rejected.",
+			content:       "terminationCooldown: soon\n",
+			expectedError: "invalid terminationCooldown",
+		},
+		{
+			name:          "Flags which can't be used together are rejected.",
+			content:       "region: eu-west-2\nregions: [us-east-1]\n",
+			expectedError: "invalid regions, it can't be used with region",
+		},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("terminator", flag.ContinueOnError)
+		fs.Int("port", 80, "")
+		fs.Duration("terminationCooldown", 0, "")
+		fs.String("region", "eu-west-1", "")
+		fs.String("regions", "", "")
+
+		c, err := loadConfigFile(writeConfigFile(t, test.content))
+		if err == nil {
+			err = c.apply(fs)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf("For test \"%s\", expected an error containing %q, but got %v", test.name, test.expectedError, err)
+		}
+	}
+}
+
+func TestConfigFileValuesWhichConflictWithFlagsAreIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		args     []string
+		expected map[string]string
+	}{
+		{
+			name:     "The file's region is ignored when regions is set on the command line.",
+			content:  "region: eu-west-2\n",
+			args:     []string{"-regions=us-east-1,us-west-2"},
+			expected: map[string]string{"region": "eu-west-1", "regions": "us-east-1,us-west-2"},
+		},
+		{
+			name:     "The file's canonical version is ignored when canonicalRange is set on the command line.",
+			content:  "canonical: 1.2.0\n",
+			args:     []string{"-canonicalRange=>=1.2.0 <2.0.0"},
+			expected: map[string]string{"canonical": "", "canonicalRange": ">=1.2.0 <2.0.0"},
+		},
+		{
+			name:     "The file's canonical version and range are ignored when canonicalURL is set on the command line.",
+			content:  "canonical: 1.2.0\ncanonicalRange: '>=1.2.0'\n",
+			args:     []string{"-canonicalURL=https://example.com/version"},
+			expected: map[string]string{"canonical": "", "canonicalRange": "", "canonicalURL": "https://example.com/version"},
+		},
+	}
+
+	for _, test := range tests {
+		fs := flag.NewFlagSet("terminator", flag.ContinueOnError)
+		fs.String("region", "eu-west-1", "")
+		fs.String("regions", "", "")
+		fs.String("canonical", "", "")
+		fs.String("canonicalRange", "", "")
+		fs.String("canonicalURL", "", "")
+
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := loadConfigFile(writeConfigFile(t, test.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.apply(fs); err != nil {
+			t.Errorf("For test \"%s\", expected no error, but got %v", test.name, err)
+			continue
+		}
+
+		for name, expected := range test.expected {
+			if actual := fs.Lookup(name).Value.String(); actual != expected {
+				t.Errorf("For test \"%s\", expected %s to be %q, but got %q", test.name, name, expected, actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// sleep waits for the duration, or until the context is cancelled, tests replace it to avoid waiting.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runEvery calls run, then waits for the interval before calling it again, until the context is cancelled.
+// Runs never overlap, since the next run doesn't start until the previous run has returned.
+func runEvery(ctx context.Context, interval time.Duration, run func(ctx context.Context)) {
+	for ctx.Err() == nil {
+		run(ctx)
+
+		if err := sleep(ctx, interval); err != nil {
+			return
+		}
+	}
+}
+
+// newMetricsHandler serves the metrics of the registry at /metrics.
+func newMetricsHandler(reg *prometheus.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// serveMetrics serves the metrics of the registry at /metrics on the address until the context is cancelled.
+func serveMetrics(ctx context.Context, addr string, reg *prometheus.Registry) {
+	server := &http.Server{Addr: addr, Handler: newMetricsHandler(reg)}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	slog.Info("serving metrics", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("failed to serve metrics", "addr", addr, "error", err)
+	}
+}
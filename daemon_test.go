@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/terminate"
+	"github.com/a-h/terminator/terminate/terminatetest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRunEveryStopsWhenCancelled(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The fake clock advances by the wait, and cancels the context after the third run.
+	var now time.Time
+	var waits []time.Duration
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return ctx.Err()
+	}
+
+	var started []time.Time
+	running := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runEvery(ctx, time.Minute, func(ctx context.Context) {
+			if running {
+				t.Error("Expected runs not to overlap")
+			}
+			running = true
+			started = append(started, now)
+			if len(started) == 3 {
+				cancel()
+			}
+			running = false
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the loop to stop when the context was cancelled")
+	}
+
+	if len(started) != 3 {
+		t.Fatalf("Expected 3 runs, but got %d", len(started))
+	}
+
+	for i, s := range started {
+		if expected := (time.Time{}).Add(time.Duration(i) * time.Minute); !s.Equal(expected) {
+			t.Errorf("Expected run %d to start after %v, but it started after %v", i+1, expected.Sub(time.Time{}), s.Sub(time.Time{}))
+		}
+	}
+
+	for _, w := range waits {
+		if w != time.Minute {
+			t.Errorf("Expected to wait for the interval between runs, but waited %v", w)
+		}
+	}
+}
+
+func TestRunEveryDoesntStartWhenAlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runEvery(ctx, time.Minute, func(ctx context.Context) {
+		t.Error("Expected no runs once the context is cancelled")
+	})
+}
+
+func TestMetricsAreServed(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := terminate.NewPrometheusMetrics(reg)
+
+	for i := 0; i < 2; i++ {
+		mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+		terminate.Run(context.Background(), mp, terminate.Options{
+			MinimumInstanceCount: 2,
+			Canonical:            "1.1.0",
+			Prometheus:           metrics,
+		})
+	}
+
+	server := httptest.NewServer(newMetricsHandler(reg))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"terminator_runs_total 2",
+		`terminator_instances_terminated_total{group="Group2"} 4`,
+		`terminator_mismatched_instances{group="Group1"} 3`,
+		`terminator_mismatched_instances{group="Group2"} 2`,
+		`terminator_probe_failures_total{group="Group2"} 0`,
+	} {
+		if !strings.Contains(string(body), expected+"\n") {
+			t.Errorf("Expected the metrics to include %q, but got:\n%s", expected, body)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+// The exit codes of terminator. Automation such as CI pipelines and cron jobs can rely on these
+// to tell the outcome of a run without parsing the output.
+const (
+	// exitOK is returned when the run completed, whether or not any instances needed terminating.
+	exitOK = 0
+	// exitError is returned when an operational error, such as an AWS API failure, stopped the run.
+	exitError = 1
+	// exitDryRunWouldChange is returned when a dry run found instances that would be terminated.
+	exitDryRunWouldChange = 2
+	// exitSafetyAbort is returned when a safety check stopped the run from terminating instances.
+	exitSafetyAbort = 3
+	// exitInvalidArguments is returned when the command-line flags are invalid.
+	exitInvalidArguments = 4
+	// exitVersionSkew is returned when an advisory run found instances which aren't running the expected version.
+	exitVersionSkew = 5
+	// exitInterrupted is returned when the run was interrupted, e.g. by Ctrl-C, before it completed. A run which
+	// reaches the runTimeout returns exitError instead.
+	exitInterrupted = 6
+)
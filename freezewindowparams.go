@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/a-h/terminator/terminate"
+)
+
+// freezeWindowParams collects the --freezeWindow flag, a comma-separated list of the periods when nothing is
+// terminated, e.g. "Fri 17:00-Mon 09:00,Mon-Fri 22:00-06:00". The flag can be repeated.
+type freezeWindowParams []terminate.FreezeWindow
+
+func (f *freezeWindowParams) String() string {
+	var specs []string
+
+	for _, w := range *f {
+		specs = append(specs, w.String())
+	}
+
+	return strings.Join(specs, ",")
+}
+
+func (f *freezeWindowParams) Set(value string) error {
+	for _, spec := range strings.Split(value, ",") {
+		w, err := terminate.ParseFreezeWindow(strings.TrimSpace(spec))
+
+		if err != nil {
+			return err
+		}
+
+		*f = append(*f, w)
+	}
+
+	return nil
+}
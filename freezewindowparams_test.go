@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFreezeWindowParams(t *testing.T) {
+	var windows freezeWindowParams
+
+	if err := windows.Set("Fri 17:00-Mon 09:00, Mon-Fri 22:00-06:00"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := windows.Set("Sat 00:00-Sun 00:00 Europe/London"); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "Fri 17:00-Mon 09:00,Mon-Fri 22:00-06:00,Sat 00:00-Sun 00:00 Europe/London"; windows.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, windows.String())
+	}
+
+	for _, invalid := range []string{"Fri", "Fri 17:00-Mon 09:00,", "Mon-Fri 9-5"} {
+		if err := windows.Set(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a-h/terminator/terminate"
+)
+
+// groupConfig is the canonical version and minimum instance count of a single group, which replace the values
+// of the flags for that group.
+type groupConfig struct {
+	Canonical            string `json:"canonical,omitempty" yaml:"canonical"`
+	MinimumInstanceCount *int   `json:"minimumInstanceCount,omitempty" yaml:"minimumInstanceCount"`
+}
+
+// groupConfigParams collects the --groupConfig flag, a JSON object of group names and their configuration, e.g.
+// {"asg_api":{"canonical":"1.2.0","minimumInstanceCount":3}}. It's usually set by the groupConfig mapping of
+// the config file. The flag can be repeated.
+type groupConfigParams map[string]terminate.GroupOverride
+
+func (g groupConfigParams) String() string {
+	var names []string
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var configs []string
+	for _, name := range names {
+		o := g[name]
+		config := name + ":"
+		if o.Canonical != "" {
+			config += " canonical=" + o.Canonical
+		}
+		if o.MinimumInstanceCount != nil {
+			config += fmt.Sprintf(" minimumInstanceCount=%d", *o.MinimumInstanceCount)
+		}
+		configs = append(configs, config)
+	}
+
+	return strings.Join(configs, ", ")
+}
+
+func (g groupConfigParams) Set(value string) error {
+	var configs map[string]groupConfig
+
+	d := json.NewDecoder(strings.NewReader(value))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&configs); err != nil {
+		return fmt.Errorf("expected a JSON object of group names and their canonical and minimumInstanceCount, %v", err)
+	}
+
+	for name, c := range configs {
+		if c.Canonical == "" && c.MinimumInstanceCount == nil {
+			return fmt.Errorf("the configuration of %s doesn't set canonical or minimumInstanceCount", name)
+		}
+		g[name] = terminate.GroupOverride{Canonical: c.Canonical, MinimumInstanceCount: c.MinimumInstanceCount}
+	}
+
+	return nil
+}
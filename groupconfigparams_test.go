@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGroupConfigParams(t *testing.T) {
+	configs := groupConfigParams{}
+
+	if err := configs.Set(`{"asg_api":{"canonical":"1.2.0"},"asg_web":{"minimumInstanceCount":3}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := configs.Set(`{"asg_worker":{"canonical":"1.1.0","minimumInstanceCount":0}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "asg_api: canonical=1.2.0, asg_web: minimumInstanceCount=3, asg_worker: canonical=1.1.0 minimumInstanceCount=0"
+	if actual := configs.String(); actual != expected {
+		t.Errorf("Expected %q, but got %q", expected, actual)
+	}
+
+	for _, invalid := range []string{`asg_api=1.2.0`, `{"asg_api":{"canonicl":"1.2.0"}}`, `{"asg_api":{}}`} {
+		if err := configs.Set(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
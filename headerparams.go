@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// headerParams collects the repeatable --header flag. Environment variables within header values are expanded,
+// so that secrets don't need to be passed on the command line, e.g. --header 'Authorization: Bearer ${TOKEN}'
+type headerParams http.Header
+
+func (h headerParams) String() string {
+	var headers []string
+
+	for name, values := range h {
+		for _, v := range values {
+			headers = append(headers, name+": "+v)
+		}
+	}
+
+	return strings.Join(headers, ", ")
+}
+
+func (h headerParams) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("header %q should be in the form \"Name: value\"", value)
+	}
+
+	http.Header(h).Add(strings.TrimSpace(parts[0]), os.ExpandEnv(strings.TrimSpace(parts[1])))
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestHeaderParams(t *testing.T) {
+	os.Setenv("TERMINATOR_TEST_TOKEN", "xyz")
+	defer os.Unsetenv("TERMINATOR_TEST_TOKEN")
+
+	headers := headerParams{}
+
+	if err := headers.Set("Authorization: Bearer ${TERMINATOR_TEST_TOKEN}"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := headers.Set("x-tenant:blue"); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := http.Header(headers).Get("Authorization"); actual != "Bearer xyz" {
+		t.Errorf("Expected the token to be read from the environment, but got %q", actual)
+	}
+
+	if actual := http.Header(headers).Get("X-Tenant"); actual != "blue" {
+		t.Errorf("Expected the X-Tenant header to be blue, but got %q", actual)
+	}
+
+	if err := headers.Set("no separator"); err == nil {
+		t.Error("Expected an error for a header without a name.")
+	}
+}
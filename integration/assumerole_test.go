@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type mockSTS struct {
+	inputs []*sts.AssumeRoleInput
+}
+
+func (m *mockSTS) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	return m.AssumeRoleWithContext(aws.BackgroundContext(), input)
+}
+
+func (m *mockSTS) AssumeRoleWithContext(ctx aws.Context, input *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("AKIDMEMBER"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	m := &mockSTS{}
+	creds := newAssumeRoleCredentials(m, AssumeRole{
+		ARN:        "arn:aws:iam::123456789012:role/terminator",
+		ExternalID: "central",
+	})
+
+	value, err := creds.Get()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value.AccessKeyID != "AKIDMEMBER" {
+		t.Errorf("Expected the credentials of the assumed role, but got %q", value.AccessKeyID)
+	}
+
+	if len(m.inputs) != 1 {
+		t.Fatalf("Expected the role to be assumed once, but got %d calls", len(m.inputs))
+	}
+
+	if arn := aws.StringValue(m.inputs[0].RoleArn); arn != "arn:aws:iam::123456789012:role/terminator" {
+		t.Errorf("Expected the role ARN to be passed to STS, but got %q", arn)
+	}
+
+	if id := aws.StringValue(m.inputs[0].ExternalId); id != "central" {
+		t.Errorf("Expected the external ID to be passed to STS, but got %q", id)
+	}
+}
+
+func TestNewAWSProviderAssumesTheRole(t *testing.T) {
+	p, err := NewAWSProvider("eu-west-1", AssumeRole{ARN: "arn:aws:iam::123456789012:role/terminator"}, "", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.session.Config.Credentials == nil {
+		t.Fatal("Expected the session to have credentials")
+	}
+
+	base, err := NewAWSProvider("eu-west-1", AssumeRole{}, "", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.session.Config.Credentials == base.session.Config.Credentials {
+		t.Error("Expected the credentials of the assumed role to replace the default credentials")
+	}
+}
+
+func TestNewAWSProviderUsesTheEndpoint(t *testing.T) {
+	p, err := NewAWSProvider("eu-west-1", AssumeRole{}, "http://localhost:4566", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if endpoint := aws.StringValue(p.session.Config.Endpoint); endpoint != "http://localhost:4566" {
+		t.Errorf("Expected the session to use the endpoint, but got %q", endpoint)
+	}
+
+	if endpoint := p.ec2.(*ec2.EC2).Endpoint; endpoint != "http://localhost:4566" {
+		t.Errorf("Expected the EC2 client to use the endpoint, but got %q", endpoint)
+	}
+
+	if endpoint := p.autoScaling.(*autoscaling.AutoScaling).Endpoint; endpoint != "http://localhost:4566" {
+		t.Errorf("Expected the auto-scaling client to use the endpoint, but got %q", endpoint)
+	}
+
+	base, err := NewAWSProvider("eu-west-1", AssumeRole{}, "", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if endpoint := base.ec2.(*ec2.EC2).Endpoint; endpoint != "https://ec2.eu-west-1.amazonaws.com" {
+		t.Errorf("Expected the regional endpoint by default, but got %q", endpoint)
+	}
+}
+
+func TestSessionOptionsUseTheProfile(t *testing.T) {
+	opts := sessionOptions("eu-west-1", "", "staging")
+
+	if opts.Profile != "staging" {
+		t.Errorf("Expected the session to use the staging profile, but got %q", opts.Profile)
+	}
+
+	if opts.SharedConfigState != session.SharedConfigEnable {
+		t.Error("Expected the shared config file to be loaded for the profile")
+	}
+
+	if region := aws.StringValue(opts.Config.Region); region != "eu-west-1" {
+		t.Errorf("Expected the region to be kept, but got %q", region)
+	}
+
+	if opts := sessionOptions("eu-west-1", "", ""); opts.Profile != "" || opts.SharedConfigState != session.SharedConfigStateFromEnv {
+		t.Errorf("Expected the default credentials without a profile, but got %+v", opts)
+	}
+}
+
+func TestNewAWSProviderUsesTheProfile(t *testing.T) {
+	dir := t.TempDir()
+	credentials := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(credentials, []byte("[staging]\naws_access_key_id = AKIDSTAGING\naws_secret_access_key = secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	p, err := NewAWSProvider("eu-west-1", AssumeRole{}, "", "staging")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := p.session.Config.Credentials.Get()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value.AccessKeyID != "AKIDSTAGING" {
+		t.Errorf("Expected the credentials of the staging profile, but got %q", value.AccessKeyID)
+	}
+}
@@ -2,6 +2,10 @@ package integration
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -14,6 +18,31 @@ type AutoScalingGroup struct {
 	Name      			string
 	Instances 			[]Instance
 	InstanceDetails InstanceDetails
+	// DesiredCapacity is the number of instances the group is trying to run.
+	DesiredCapacity int
+	// MinSize is the smallest number of instances the group is allowed to run.
+	MinSize int
+	// UseMinSize is set when MinSize is the number of healthy instances to leave, instead of the minimum
+	// instance count passed to GetTargetInstances.
+	UseMinSize bool
+	// AllowPartialDetails is set when instances can be terminated even though the details of some healthy
+	// instances couldn't be read. Instances without details are never terminated, and don't count as healthy
+	// instances, otherwise the group is left alone until all of the details can be read.
+	AllowPartialDetails bool
+	// StandbyCountsAsHealthy is set when healthy instances in standby count as healthy instances, otherwise
+	// they're ignored, like pending instances.
+	StandbyCountsAsHealthy bool
+	// MinimumAge, when greater than zero, spares instances launched more recently than the age from termination.
+	MinimumAge time.Duration
+	// MaximumAge, when greater than zero, makes instances launched longer ago than the age candidates for
+	// termination, even when they run the expected version.
+	MaximumAge time.Duration
+	// UseWeightedCapacity is set when the minimum instance count is the capacity to leave, where each instance
+	// provides its Weight, e.g. in groups of mixed instance types, instead of a number of instances.
+	UseWeightedCapacity bool
+	// Direction limits the instances which are terminated to those whose version is below, or above, the
+	// canonical version. Both are terminated when it's empty.
+	Direction Direction
 }
 
 func NewAutoScalingGroup(name string, instances []*autoscaling.Instance, instanceDetails InstanceDetails) AutoScalingGroup {
@@ -24,78 +53,535 @@ func NewAutoScalingGroup(name string, instances []*autoscaling.Instance, instanc
 	}
 
 	for i, awsInstance := range instances {
-		asg.Instances[i] = Instance {
-			ID:							aws.StringValue(awsInstance.InstanceId),
-			HealthStatus: 	aws.StringValue(awsInstance.HealthStatus),
-			LifecycleState: aws.StringValue(awsInstance.LifecycleState),
+		asg.Instances[i] = Instance{
+			ID:                      aws.StringValue(awsInstance.InstanceId),
+			HealthStatus:            aws.StringValue(awsInstance.HealthStatus),
+			LifecycleState:          aws.StringValue(awsInstance.LifecycleState),
+			ProtectedFromScaleIn:    aws.BoolValue(awsInstance.ProtectedFromScaleIn),
+			AvailabilityZone:        aws.StringValue(awsInstance.AvailabilityZone),
+			Weight:                  parseWeight(aws.StringValue(awsInstance.WeightedCapacity)),
+			InstanceType:            aws.StringValue(awsInstance.InstanceType),
+			LaunchConfigurationName: aws.StringValue(awsInstance.LaunchConfigurationName),
+		}
+		if lt := awsInstance.LaunchTemplate; lt != nil {
+			asg.Instances[i].LaunchTemplateName = aws.StringValue(lt.LaunchTemplateName)
+			if asg.Instances[i].LaunchTemplateName == "" {
+				asg.Instances[i].LaunchTemplateName = aws.StringValue(lt.LaunchTemplateId)
+			}
+			asg.Instances[i].LaunchTemplateVersion = aws.StringValue(lt.Version)
 		}
 	}
 
 	return asg
 }
 
-func (group AutoScalingGroup) GetTargetInstances(canonical semver.Version, minimumInstanceCount int) ([]string, error) {
+// SafetyError is returned when instances can't safely be terminated from a group, e.g. because too few
+// instances are healthy.
+type SafetyError struct {
+	Group  string
+	Reason string
+	// PartialDetails is set when the group is left alone because some of its instances are unhealthy, or their
+	// details couldn't be read, rather than because too few instances are healthy.
+	PartialDetails bool
+}
+
+func (e SafetyError) Error() string {
+	return e.Reason
+}
+
+// GetTargetInstances returns the instances whose version doesn't match the canonical version, leaving at least
+// minimumInstanceCount healthy instances, or minimumHealthyPercentage percent of the healthy instances if that's more.
+// The rest of the instances are returned as kept, with the reason each of them was kept, even when an error stops
+// any instances being targeted.
+func (group AutoScalingGroup) GetTargetInstances(canonical semver.Version, minimumInstanceCount int, minimumHealthyPercentage float64) ([]TerminationDecision, []KeepDecision, error) {
+	return group.GetMismatchedInstances(canonical.String(), SemverComparator{}, minimumInstanceCount, minimumHealthyPercentage)
+}
+
+// GetMismatchedInstances returns the instances whose version doesn't match the canonical version when compared
+// by the comparator, e.g. to compare build numbers rather than semantic versions. The instances are kept in the
+// same way as GetTargetInstances.
+func (group AutoScalingGroup) GetMismatchedInstances(canonical string, comparator VersionComparator, minimumInstanceCount int, minimumHealthyPercentage float64) ([]TerminationDecision, []KeepDecision, error) {
+	slog.Info("finding instances that don't match version", "group", group.Name, "version", canonical)
+	var compareErr error
+	decisions, kept, err := group.getTargetInstances(func(d InstanceDetail) TerminationReason {
+		n, err := comparator.Compare(d.Version(), canonical)
+		if err != nil {
+			compareErr = fmt.Errorf("failed to compare the version of instance %s, %v", d.ID, err)
+			return ""
+		}
+		if n < 0 {
+			return ReasonBelowCanonical
+		}
+		if n > 0 {
+			return ReasonAboveCanonical
+		}
+		return ""
+	}, minimumInstanceCount, minimumHealthyPercentage)
+	if compareErr != nil {
+		return nil, nil, compareErr
+	}
+	return decisions, kept, err
+}
+
+// GetOutdatedInstances returns the instances whose version is lower than the minimum, e.g. instances running
+// an old operating system when the version is read from SSM inventory.
+func (group AutoScalingGroup) GetOutdatedInstances(minimum semver.Version, minimumInstanceCount int, minimumHealthyPercentage float64) ([]TerminationDecision, []KeepDecision, error) {
+	slog.Info("finding instances with a lower version", "group", group.Name, "version", minimum.String())
+	return group.getTargetInstances(func(d InstanceDetail) TerminationReason {
+		if d.VersionNumber.LT(minimum) {
+			return ReasonBelowCanonical
+		}
+		return ""
+	}, minimumInstanceCount, minimumHealthyPercentage)
+}
+
+// GetInstancesOutsideRange returns the instances whose version is outside the range, e.g. to keep any instance
+// running a 1.x version.
+func (group AutoScalingGroup) GetInstancesOutsideRange(r semver.Range, minimumInstanceCount int, minimumHealthyPercentage float64) ([]TerminationDecision, []KeepDecision, error) {
+	slog.Info("finding instances with a version outside the range", "group", group.Name)
+	return group.getTargetInstances(func(d InstanceDetail) TerminationReason {
+		if !r(d.VersionNumber) {
+			return ReasonOutsideRange
+		}
+		return ""
+	}, minimumInstanceCount, minimumHealthyPercentage)
+}
+
+// getTargetInstances selects the instances to terminate, and the instances to keep. reasonFor returns why an
+// instance's version makes it a target, or an empty reason if it isn't one.
+func (group AutoScalingGroup) getTargetInstances(reasonFor func(d InstanceDetail) TerminationReason, minimumInstanceCount int, minimumHealthyPercentage float64) ([]TerminationDecision, []KeepDecision, error) {
 	start := time.Now()
-	healthy, unhealthy := categoriseInstances(group.Instances, minimumInstanceCount)
+	healthy, unhealthy, terminating, transitioning := group.categoriseInstances()
+
+	if group.AllowPartialDetails {
+		var unknown []Instance
+		healthy, unknown = splitByDetails(healthy, group.InstanceDetails)
+		if len(unknown) > 0 {
+			slog.Warn("ignoring instances whose details couldn't be read", "group", group.Name, "instances", getInstanceIDs(unknown))
+		}
+	}
+
+	if group.UseMinSize {
+		minimumInstanceCount = group.MinSize
+	}
+
+	capacity := group.capacityOf(healthy)
+
+	if floor := minimumFromPercentage(capacity, minimumHealthyPercentage); floor > minimumInstanceCount {
+		slog.Info("keeping a percentage of the healthy instances", "group", group.Name, "minimum", floor, "percentage", minimumHealthyPercentage, "healthy", len(healthy))
+		minimumInstanceCount = floor
+	}
+
+	slog.Info("categorised instances", "group", group.Name, "healthy", len(healthy), "unhealthy", len(unhealthy))
+	if group.UseWeightedCapacity {
+		slog.Info("using the weighted capacity of the instances", "group", group.Name, "capacity", capacity, "minimum", minimumInstanceCount)
+	}
+	slog.Debug("categorised instances", "group", group.Name, "healthy", healthy, "unhealthy", unhealthy)
+
+	if len(terminating) > 0 {
+		slog.Info("ignoring instances which are already terminating", "group", group.Name, "instances", getInstanceIDs(terminating))
+	}
+
+	if len(transitioning) > 0 {
+		slog.Info("ignoring instances which are pending or in standby", "group", group.Name, "instances", getInstanceIDs(transitioning))
+	}
+
+	if capacity <= float64(minimumInstanceCount) {
+		return nil, group.keptInstances(reasonFor, nil, KeepMinimumCount), SafetyError{Group: group.Name, Reason: "not enough healthy instances"}
+	}
 
-  fmt.Printf("%s => %d healthy instances, %d unhealthy instances\n\thealthy: %+v\n\tunhealthy: %+v\n",
-    group.Name, len(healthy), len(unhealthy),
-    healthy,
-    unhealthy)
+	details := excludeInstanceDetails(group.InstanceDetails, append(terminating, transitioning...))
 
-	if len(healthy) <= minimumInstanceCount {
-    fmt.Printf("%s => not enough healthy instances\n", group.Name)
-    return []string{}, nil
-  }
+	if len(unhealthy) > 0 || len(healthy) != len(details) {
+		return nil, group.keptInstances(reasonFor, nil, KeepGroupUnsafe), SafetyError{Group: group.Name, Reason: "couldn't get all instance details, some instances may still be starting", PartialDetails: true}
+	}
+
+	protected := map[string]bool{}
+	zones := map[string]string{}
+	for _, instance := range healthy {
+		protected[instance.ID] = instance.ProtectedFromScaleIn
+		zones[instance.ID] = instance.AvailabilityZone
+	}
 
-	if len(unhealthy) > 0 || len(healthy) != len(group.InstanceDetails) {
-		fmt.Printf("%s => couldn't get all instance details, some instances may still be starting\n", group.Name)
-		return []string{}, nil
+	// Instances which are protected or too young are never terminated.
+	spared := map[string]bool{}
+	for _, d := range details {
+		if protected[d.ID] {
+			spared[d.ID] = true
+		}
+		if group.MinimumAge > 0 && time.Since(d.LaunchTime) < group.MinimumAge {
+			spared[d.ID] = true
+		}
 	}
 
-	fmt.Printf("%s => finding instances that don't match version %s\n", group.Name, canonical)
 	var mismatchedInstances []string
+	reasons := map[string]TerminationReason{}
+	excluded := map[string]bool{}
+
+	for _, details := range details {
+		expired := group.MaximumAge > 0 && time.Since(details.LaunchTime) > group.MaximumAge
+		reason := reasonFor(details)
+		if !group.Direction.Allows(reason) {
+			slog.Info("skipping instance whose version is excluded by the direction", "group", group.Name, "instance", details.ID, "reason", reason, "direction", group.Direction)
+			excluded[details.ID] = true
+			reason = ""
+		}
+		if reason != "" || expired {
+			if protected[details.ID] {
+				slog.Info("skipping instance which is protected from scale in", "group", group.Name, "instance", details.ID)
+				continue
+			}
+			if spared[details.ID] {
+				slog.Info("skipping instance which was launched too recently", "group", group.Name, "instance", details.ID, "launchTime", details.LaunchTime, "minimumAge", group.MinimumAge)
+				continue
+			}
+			if reason == "" {
+				slog.Info("targeting instance which has exceeded the maximum age", "group", group.Name, "instance", details.ID, "launchTime", details.LaunchTime, "maximumAge", group.MaximumAge)
+				reason = ReasonAgeExceeded
+			}
+			mismatchedInstances = append(mismatchedInstances, details.ID)
+			reasons[details.ID] = reason
+		}
+	}
 
-	for i, details := range group.InstanceDetails {
-		if details.VersionNumber.LT(canonical) || details.VersionNumber.GT(canonical) {
-			mismatchedInstances = append(mismatchedInstances, group.Instances[i].ID)
+	// Instances excluded by the direction aren't trimmed either, unless they've exceeded the maximum age.
+	for id := range excluded {
+		if _, ok := reasons[id]; !ok {
+			spared[id] = true
 		}
 	}
 
 	if len(mismatchedInstances) == 0 {
-		fmt.Println("time: AutoScalingGroup.GetTargetInstances() ", time.Since(start))
-		fmt.Printf("%s => no mismatched instances detected\n", group.Name)
-		return []string{}, nil
+		slog.Debug("time: AutoScalingGroup.GetTargetInstances()", "group", group.Name, "duration", time.Since(start))
+		slog.Info("no mismatched instances detected", "group", group.Name)
+		return []TerminationDecision{}, group.keptInstances(reasonFor, nil, KeepMinimumCount), nil
 	}
 
-	maximum := len(group.Instances) - minimumInstanceCount
+	mismatchedInstances = balanceZones(oldestFirst(mismatchedInstances, details), zones)
+
+	// Instances which are already terminating don't count towards the minimum.
+	maximum := capacity - float64(minimumInstanceCount)
 
 	// Priority order to keep (NOT terminate) instances:
 	// - Healthy, Mismatched, Unhealthy
-	instanceIdsToTerminate := removeDuplicates(append(mismatchedInstances, getInstanceIDs(healthy[minimumInstanceCount:])...))
+	floor := group.floorIndex(healthy, minimumInstanceCount)
+	instanceIdsToTerminate := removeDuplicates(append(mismatchedInstances, getInstanceIDs(excludeSpared(healthy[floor:], spared))...))
+
+	slog.Debug("time: AutoScalingGroup.GetTargetInstances()", "group", group.Name, "duration", time.Since(start))
+
+	instanceIdsToTerminate = group.withinCapacity(instanceIdsToTerminate, healthy, maximum)
+
+	versions := map[string]semver.Version{}
+	for _, d := range details {
+		versions[d.ID] = d.VersionNumber
+	}
+
+	decisions := make([]TerminationDecision, len(instanceIdsToTerminate))
+	for i, id := range instanceIdsToTerminate {
+		reason, ok := reasons[id]
+		if !ok {
+			reason = ReasonTrimmed
+		}
+		decisions[i] = TerminationDecision{ID: id, Reason: reason, Version: versions[id]}
+	}
+
+	return decisions, group.keptInstances(reasonFor, instanceIdsToTerminate, KeepMinimumCount), nil
+}
+
+// keptInstances returns the instances of the group which aren't targeted, and the reason each of them is kept.
+// mismatched is the reason healthy instances which would otherwise be targeted are kept.
+func (group AutoScalingGroup) keptInstances(reasonFor func(d InstanceDetail) TerminationReason, targets []string, mismatched KeepReason) []KeepDecision {
+	targeted := map[string]bool{}
+	for _, id := range targets {
+		targeted[id] = true
+	}
+
+	details := map[string]InstanceDetail{}
+	for _, d := range group.InstanceDetails {
+		details[d.ID] = d
+	}
+
+	kept := []KeepDecision{}
+	for _, instance := range group.Instances {
+		if targeted[instance.ID] {
+			continue
+		}
+
+		d, known := details[instance.ID]
+		decision := KeepDecision{ID: instance.ID, Reason: mismatched}
+		var reason TerminationReason
+		if known {
+			decision.Version = d.Version()
+			reason = reasonFor(d)
+		}
+		expired := group.MaximumAge > 0 && time.Since(d.LaunchTime) > group.MaximumAge
+
+		switch {
+		case group.IsHealthy(instance):
+			switch {
+			case !known:
+				decision.Reason = KeepUnknownVersion
+			case instance.ProtectedFromScaleIn:
+				decision.Reason = KeepProtected
+			case group.MinimumAge > 0 && time.Since(d.LaunchTime) < group.MinimumAge:
+				decision.Reason = KeepTooYoung
+			case reason == "" && !expired:
+				decision.Reason = KeepVersionMatches
+			case !group.Direction.Allows(reason) && !expired:
+				decision.Reason = KeepExcludedDirection
+			}
+		case instance.IsTerminating():
+			decision.Reason = KeepTerminating
+		case instance.IsPending() || instance.IsStandby():
+			decision.Reason = KeepTransitioning
+		default:
+			decision.Reason = KeepUnhealthy
+		}
+
+		kept = append(kept, decision)
+	}
+
+	return kept
+}
+
+// oldestFirst orders the instances by their launch time, oldest first, so that repeated runs terminate
+// instances in a predictable order. Instances launched at the same time are ordered by ID.
+func oldestFirst(ids []string, details InstanceDetails) []string {
+	launched := map[string]time.Time{}
+	for _, d := range details {
+		launched[d.ID] = d.LaunchTime
+	}
 
-	fmt.Println("time: AutoScalingGroup.GetTargetInstances() ", time.Since(start))
+	ordered := append([]string{}, ids...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := launched[ordered[i]], launched[ordered[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return ordered[i] < ordered[j]
+	})
+
+	return ordered
+}
+
+// balanceZones orders the instances so that each is taken from the availability zone which has the most
+// remaining instances, keeping the group balanced across its zones when only some of the instances are
+// terminated. When zones have as many remaining instances, the instance which comes first is taken, so the
+// order of the instances is kept within each zone. zones maps the ID of each healthy instance to its zone.
+func balanceZones(ids []string, zones map[string]string) []string {
+	remaining := map[string]int{}
+	for _, zone := range zones {
+		remaining[zone]++
+	}
+
+	position := map[string]int{}
+	candidates := map[string][]string{}
+	var names []string
+	for i, id := range ids {
+		position[id] = i
+		zone := zones[id]
+		if _, ok := candidates[zone]; !ok {
+			names = append(names, zone)
+		}
+		candidates[zone] = append(candidates[zone], id)
+	}
+
+	ordered := make([]string, 0, len(ids))
+	for len(ordered) < len(ids) {
+		next := ""
+		found := false
+		for _, zone := range names {
+			if len(candidates[zone]) == 0 {
+				continue
+			}
+			if !found || remaining[zone] > remaining[next] ||
+				(remaining[zone] == remaining[next] && position[candidates[zone][0]] < position[candidates[next][0]]) {
+				next, found = zone, true
+			}
+		}
+
+		ordered = append(ordered, candidates[next][0])
+		candidates[next] = candidates[next][1:]
+		remaining[next]--
+	}
+
+	return ordered
+}
+
+// minimumFromPercentage returns the number of instances, or the capacity when weighted, needed to keep percentage
+// percent of the healthy capacity.
+func minimumFromPercentage(healthy float64, percentage float64) int {
+	return int(math.Ceil(healthy * percentage / 100))
+}
+
+// weightOf returns the capacity the instance provides, which is 1 unless UseWeightedCapacity is set.
+func (group AutoScalingGroup) weightOf(instance Instance) float64 {
+	if !group.UseWeightedCapacity || instance.Weight <= 0 {
+		return 1
+	}
+	return instance.Weight
+}
+
+// capacityOf returns the total capacity of the instances, which is the number of instances unless
+// UseWeightedCapacity is set.
+func (group AutoScalingGroup) capacityOf(instances []Instance) float64 {
+	capacity := 0.0
+	for _, instance := range instances {
+		capacity += group.weightOf(instance)
+	}
+	return capacity
+}
+
+// floorIndex returns the number of the healthy instances, taken in order, which provide the minimum capacity.
+func (group AutoScalingGroup) floorIndex(healthy []Instance, minimum int) int {
+	capacity := 0.0
+	for i, instance := range healthy {
+		if capacity >= float64(minimum) {
+			return i
+		}
+		capacity += group.weightOf(instance)
+	}
+	return len(healthy)
+}
+
+// withinCapacity returns the ids, in order, whose total capacity is at most maximum, so that the capacity of the
+// instances which are left doesn't drop below the minimum. An instance which would take the capacity below the
+// minimum is skipped, so that smaller instances after it can still be taken.
+func (group AutoScalingGroup) withinCapacity(ids []string, healthy []Instance, maximum float64) []string {
+	weights := map[string]float64{}
+	for _, instance := range healthy {
+		weights[instance.ID] = group.weightOf(instance)
+	}
+
+	taken := []string{}
+	removed := 0.0
+	for _, id := range ids {
+		if removed+weights[id] > maximum {
+			continue
+		}
+		taken = append(taken, id)
+		removed += weights[id]
+	}
+	return taken
+}
+
+// Floor returns the healthy capacity to leave in the group, which is the larger of minimumInstanceCount, or
+// MinSize when UseMinSize is set, and minimumHealthyPercentage percent of the group's healthy capacity.
+func (group AutoScalingGroup) Floor(minimumInstanceCount int, minimumHealthyPercentage float64) int {
+	if group.UseMinSize {
+		minimumInstanceCount = group.MinSize
+	}
+	if floor := minimumFromPercentage(group.HealthyCapacity(nil), minimumHealthyPercentage); floor > minimumInstanceCount {
+		return floor
+	}
+	return minimumInstanceCount
+}
 
-	if len(instanceIdsToTerminate) <= maximum {
-		return instanceIdsToTerminate, nil
+// HealthyCapacity returns the capacity of the group's healthy instances, leaving out the excluded instances, e.g.
+// to find the capacity which would be left after they're terminated.
+func (group AutoScalingGroup) HealthyCapacity(excluding []string) float64 {
+	excluded := map[string]bool{}
+	for _, id := range excluding {
+		excluded[id] = true
+	}
+
+	capacity := 0.0
+	for _, instance := range group.Instances {
+		if group.IsHealthy(instance) && !excluded[instance.ID] {
+			capacity += group.weightOf(instance)
+		}
+	}
+	return capacity
+}
+
+// HealthyCount returns the number of instances in the group which are healthy.
+func (group AutoScalingGroup) HealthyCount() int {
+	count := 0
+	for _, instance := range group.Instances {
+		if group.IsHealthy(instance) {
+			count++
+		}
 	}
+	return count
+}
 
-	return instanceIdsToTerminate[:maximum], nil
+// IsHealthy returns true if the instance is healthy and in service, or when StandbyCountsAsHealthy is set,
+// healthy and in standby.
+func (group AutoScalingGroup) IsHealthy(instance Instance) bool {
+	if group.StandbyCountsAsHealthy && instance.IsStandby() {
+		return !instance.FailedHealthCheck && strings.EqualFold(instance.HealthStatus, "Healthy")
+	}
+	return instance.IsHealthy()
 }
 
-func categoriseInstances(instances []Instance, minimumInstanceCount int) (healthyInstances []Instance, otherInstances []Instance) {
+// categoriseInstances splits the instances of the group into healthy instances, instances which are already
+// terminating, instances which are pending or in standby, and any other (unhealthy) instances.
+func (group AutoScalingGroup) categoriseInstances() (healthyInstances []Instance, otherInstances []Instance, terminatingInstances []Instance, transitioningInstances []Instance) {
 	healthyInstances = []Instance{}
 	otherInstances = []Instance{}
+	terminatingInstances = []Instance{}
+	transitioningInstances = []Instance{}
 
-	for _, instance := range instances {
-		if instance.IsHealthy() {
+	for _, instance := range group.Instances {
+		if group.IsHealthy(instance) {
 			healthyInstances = append(healthyInstances, instance)
+		} else if instance.IsTerminating() {
+			terminatingInstances = append(terminatingInstances, instance)
+		} else if instance.IsPending() || instance.IsStandby() {
+			transitioningInstances = append(transitioningInstances, instance)
 		} else {
 			otherInstances = append(otherInstances, instance)
 		}
 	}
 
-	return healthyInstances, otherInstances
+	return healthyInstances, otherInstances, terminatingInstances, transitioningInstances
+}
+
+// excludeInstanceDetails returns the details of all instances except the excluded instances.
+func excludeInstanceDetails(details InstanceDetails, excluded []Instance) InstanceDetails {
+	ids := map[string]bool{}
+	for _, instance := range excluded {
+		ids[instance.ID] = true
+	}
+
+	result := InstanceDetails{}
+	for _, d := range details {
+		if !ids[d.ID] {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
+
+// splitByDetails splits the instances into the instances which have details, and those which don't.
+func splitByDetails(instances []Instance, details InstanceDetails) (known []Instance, unknown []Instance) {
+	ids := map[string]bool{}
+	for _, d := range details {
+		ids[d.ID] = true
+	}
+
+	known = []Instance{}
+	for _, instance := range instances {
+		if ids[instance.ID] {
+			known = append(known, instance)
+		} else {
+			unknown = append(unknown, instance)
+		}
+	}
+
+	return known, unknown
+}
+
+// excludeSpared returns the instances which aren't spared from termination.
+func excludeSpared(instances []Instance, spared map[string]bool) []Instance {
+	result := []Instance{}
+	for _, instance := range instances {
+		if !spared[instance.ID] {
+			result = append(result, instance)
+		}
+	}
+	return result
 }
 
 func getInstanceIDs(instances []Instance) []string {
@@ -0,0 +1,834 @@
+package integration
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/blang/semver"
+)
+
+func TestProtectedInstancesAreNeverTargeted(t *testing.T) {
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService", ProtectedFromScaleIn: true},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService", ProtectedFromScaleIn: true},
+			{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0")},
+			{ID: "i-2", VersionNumber: semver.MustParse("1.0.0")},
+			{ID: "i-3", VersionNumber: semver.MustParse("2.0.0")},
+			{ID: "i-4", VersionNumber: semver.MustParse("2.0.0")},
+		},
+	}
+
+	decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 1, 0)
+	targets := DecisionIDs(decisions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range targets {
+		if id == "i-1" || id == "i-3" {
+			t.Errorf("Expected protected instances to be skipped, but got %v", targets)
+		}
+	}
+
+	if len(targets) == 0 || targets[0] != "i-2" {
+		t.Errorf("Expected the unprotected mismatched instance i-2 to be targeted, but got %v", targets)
+	}
+}
+
+func TestLifecycleStates(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lifecycleState         string
+		healthStatus           string
+		hasDetail              bool
+		standbyCountsAsHealthy bool
+		expected               []string
+		expectedSafetyError    bool
+	}{
+		{
+			name:           "in service instances count towards the minimum",
+			lifecycleState: "InService",
+			healthStatus:   "Healthy",
+			hasDetail:      true,
+			expected:       []string{"A", "B"},
+		},
+		{
+			name:           "pending instances are ignored",
+			lifecycleState: "Pending",
+			healthStatus:   "Healthy",
+			expected:       []string{"A"},
+		},
+		{
+			name:           "pending instances waiting on a lifecycle hook are ignored",
+			lifecycleState: "Pending:Wait",
+			healthStatus:   "Healthy",
+			hasDetail:      true,
+			expected:       []string{"A"},
+		},
+		{
+			name:           "standby instances are ignored",
+			lifecycleState: "Standby",
+			healthStatus:   "Healthy",
+			hasDetail:      true,
+			expected:       []string{"A"},
+		},
+		{
+			name:           "instances entering standby are ignored",
+			lifecycleState: "EnteringStandby",
+			healthStatus:   "Healthy",
+			hasDetail:      true,
+			expected:       []string{"A"},
+		},
+		{
+			name:                   "standby instances count as healthy when configured",
+			lifecycleState:         "Standby",
+			healthStatus:           "Healthy",
+			hasDetail:              true,
+			standbyCountsAsHealthy: true,
+			expected:               []string{"A", "B"},
+		},
+		{
+			name:                   "unhealthy standby instances are ignored when standby counts as healthy",
+			lifecycleState:         "Standby",
+			healthStatus:           "Unhealthy",
+			hasDetail:              true,
+			standbyCountsAsHealthy: true,
+			expected:               []string{"A"},
+		},
+		{
+			name:           "terminating instances are ignored",
+			lifecycleState: "Terminating:Wait",
+			healthStatus:   "Healthy",
+			hasDetail:      true,
+			expected:       []string{"A"},
+		},
+		{
+			name:                "unhealthy instances stop terminations",
+			lifecycleState:      "InService",
+			healthStatus:        "Unhealthy",
+			hasDetail:           true,
+			expectedSafetyError: true,
+		},
+	}
+
+	for _, test := range tests {
+		group := AutoScalingGroup{
+			Name: "asg_api",
+			Instances: []Instance{
+				{ID: "A", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "B", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "C", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "X", HealthStatus: test.healthStatus, LifecycleState: test.lifecycleState},
+			},
+			InstanceDetails: InstanceDetails{
+				{ID: "A", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "B", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "C", VersionNumber: semver.MustParse("1.0.0")},
+			},
+			StandbyCountsAsHealthy: test.standbyCountsAsHealthy,
+		}
+		if test.hasDetail {
+			group.InstanceDetails = append(group.InstanceDetails, InstanceDetail{ID: "X", VersionNumber: semver.MustParse("1.0.0")})
+		}
+
+		decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 2, 0)
+		targets := DecisionIDs(decisions)
+
+		_, isSafetyError := err.(SafetyError)
+		if isSafetyError != test.expectedSafetyError {
+			t.Errorf("For test \"%s\", expected safety error %v, but got %v", test.name, test.expectedSafetyError, err)
+			continue
+		}
+		if err != nil && !isSafetyError {
+			t.Errorf("For test \"%s\", unexpected error %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("For test \"%s\", expected targets %v, but got %v", test.name, test.expected, targets)
+		}
+	}
+}
+
+func TestTargetsAreBalancedAcrossAvailabilityZones(t *testing.T) {
+	zones := map[string]string{
+		"i-1": "eu-west-1c",
+		"i-2": "eu-west-1b",
+		"i-3": "eu-west-1b",
+		"i-4": "eu-west-1a",
+		"i-5": "eu-west-1a",
+		"i-6": "eu-west-1a",
+		"i-7": "eu-west-1a",
+	}
+
+	group := AutoScalingGroup{Name: "asg_api"}
+	for _, id := range []string{"i-1", "i-2", "i-3", "i-4", "i-5", "i-6", "i-7"} {
+		group.Instances = append(group.Instances, Instance{ID: id, HealthStatus: "Healthy", LifecycleState: "InService", AvailabilityZone: zones[id]})
+		group.InstanceDetails = append(group.InstanceDetails, InstanceDetail{ID: id, VersionNumber: semver.MustParse("1.0.0")})
+	}
+
+	decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 3, 0)
+	targets := DecisionIDs(decisions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(targets) != 4 {
+		t.Fatalf("Expected 4 targets, but got %v", targets)
+	}
+
+	remaining := map[string]int{"eu-west-1a": 4, "eu-west-1b": 2, "eu-west-1c": 1}
+	for _, id := range targets {
+		remaining[zones[id]]--
+	}
+
+	for zone, count := range remaining {
+		if count != 1 {
+			t.Errorf("Expected a single instance to remain in each zone, but %s has %d after terminating %v", zone, count, targets)
+		}
+	}
+}
+
+func TestBalanceZones(t *testing.T) {
+	zones := map[string]string{"A": "a", "B": "a", "C": "b", "D": "c", "E": "c", "F": "c"}
+
+	actual := balanceZones([]string{"A", "C", "D", "E"}, zones)
+
+	if expected := []string{"D", "A", "E", "C"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+}
+
+func TestOldestMismatchedInstancesAreTargetedFirst(t *testing.T) {
+	now := time.Now()
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-5", HealthStatus: "Healthy", LifecycleState: "InService"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-time.Minute)},
+			{ID: "i-2", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-3 * time.Hour)},
+			{ID: "i-3", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-time.Hour)},
+			{ID: "i-4", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-2 * time.Hour)},
+			{ID: "i-5", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-4 * time.Hour)},
+		},
+	}
+
+	decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 3, 0)
+	targets := DecisionIDs(decisions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"i-2", "i-4"}; !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected the oldest mismatched instances %v to be targeted, but got %v", expected, targets)
+	}
+}
+
+func TestRecentlyLaunchedInstancesAreSpared(t *testing.T) {
+	now := time.Now()
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-30 * time.Second)},
+			{ID: "i-2", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-time.Hour)},
+			{ID: "i-3", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-2 * time.Hour)},
+			{ID: "i-4", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-3 * time.Hour)},
+		},
+		MinimumAge: 5 * time.Minute,
+	}
+
+	decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 0, 0)
+	targets := DecisionIDs(decisions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range targets {
+		if id == "i-1" {
+			t.Errorf("Expected the instance launched 30 seconds ago to be spared, but got %v", targets)
+		}
+	}
+
+	if len(targets) == 0 || targets[0] != "i-3" {
+		t.Errorf("Expected the older mismatched instances to be targeted, but got %v", targets)
+	}
+}
+
+func TestInstancesOlderThanTheMaximumAgeAreTargeted(t *testing.T) {
+	tests := []struct {
+		name                 string
+		maximumAge           time.Duration
+		minimumInstanceCount int
+		expected             []string
+	}{
+		{
+			name:                 "expired instances are targeted alongside mismatched instances, oldest first",
+			maximumAge:           24 * time.Hour,
+			minimumInstanceCount: 2,
+			expected:             []string{"i-1", "i-4", "i-5"},
+		},
+		{
+			name:                 "the minimum instance count is kept",
+			maximumAge:           24 * time.Hour,
+			minimumInstanceCount: 4,
+			expected:             []string{"i-1"},
+		},
+		{
+			name:                 "only mismatched instances are targeted when no instances have expired",
+			maximumAge:           100 * time.Hour,
+			minimumInstanceCount: 3,
+			expected:             []string{"i-5", "i-2"},
+		},
+	}
+
+	now := time.Now()
+	for _, test := range tests {
+		group := AutoScalingGroup{
+			Name: "asg_api",
+			Instances: []Instance{
+				{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-5", HealthStatus: "Healthy", LifecycleState: "InService"},
+			},
+			InstanceDetails: InstanceDetails{
+				{ID: "i-1", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-48 * time.Hour)},
+				{ID: "i-2", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-time.Hour)},
+				{ID: "i-3", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-time.Hour)},
+				{ID: "i-4", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-30 * time.Hour)},
+				{ID: "i-5", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-2 * time.Hour)},
+			},
+			MaximumAge: test.maximumAge,
+		}
+
+		decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), test.minimumInstanceCount, 0)
+		targets := DecisionIDs(decisions)
+
+		if err != nil {
+			t.Errorf("For test \"%s\", unexpected error %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("For test \"%s\", expected targets %v, but got %v", test.name, test.expected, targets)
+		}
+	}
+}
+
+func TestWeightedCapacityIsKept(t *testing.T) {
+	tests := []struct {
+		name                string
+		useWeightedCapacity bool
+		minimum             int
+		percentage          float64
+		expected            []string
+		expectedError       bool
+	}{
+		{
+			name:     "without weights, the number of instances is kept",
+			minimum:  4,
+			expected: []string{"i-1"},
+		},
+		{
+			name:                "the large instance provides half of the capacity",
+			useWeightedCapacity: true,
+			minimum:             4,
+			expected:            []string{"i-1"},
+		},
+		{
+			name:                "instances which would take the capacity below the minimum are skipped",
+			useWeightedCapacity: true,
+			minimum:             6,
+			expected:            []string{"i-2", "i-3"},
+		},
+		{
+			name:                "the percentage is of the weighted capacity",
+			useWeightedCapacity: true,
+			minimum:             1,
+			percentage:          75,
+			expected:            []string{"i-2", "i-3"},
+		},
+		{
+			name:                "a group at its minimum capacity isn't touched",
+			useWeightedCapacity: true,
+			minimum:             8,
+			expectedError:       true,
+		},
+	}
+
+	for _, test := range tests {
+		group := AutoScalingGroup{
+			Name: "asg_api",
+			Instances: []Instance{
+				{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService", Weight: 4},
+				{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService", Weight: 1},
+				{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService", Weight: 1},
+				{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService", Weight: 1},
+				{ID: "i-5", HealthStatus: "Healthy", LifecycleState: "InService", Weight: 1},
+			},
+			InstanceDetails: InstanceDetails{
+				{ID: "i-1", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-2", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-3", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-4", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-5", VersionNumber: semver.MustParse("1.0.0")},
+			},
+			UseWeightedCapacity: test.useWeightedCapacity,
+		}
+
+		decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), test.minimum, test.percentage)
+		targets := DecisionIDs(decisions)
+
+		if _, isSafetyError := err.(SafetyError); test.expectedError != isSafetyError {
+			t.Errorf("For test \"%s\", expected a safety error %v, but got %v", test.name, test.expectedError, err)
+			continue
+		}
+		if test.expectedError {
+			continue
+		}
+		if !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("For test \"%s\", expected targets %v, but got %v", test.name, test.expected, targets)
+		}
+	}
+}
+
+func TestInstanceWeightsAreReadFromTheGroup(t *testing.T) {
+	group := NewAutoScalingGroup("asg_api", []*autoscaling.Instance{
+		{InstanceId: aws.String("i-1"), WeightedCapacity: aws.String("4")},
+		{InstanceId: aws.String("i-2")},
+	}, nil)
+
+	if group.Instances[0].Weight != 4 || group.Instances[1].Weight != 1 {
+		t.Errorf("Expected the weights 4 and 1, but got %v and %v", group.Instances[0].Weight, group.Instances[1].Weight)
+	}
+}
+
+func TestTheMinSizeOfEachGroupIsKept(t *testing.T) {
+	tests := []struct {
+		name          string
+		minSize       int
+		useMinSize    bool
+		expected      int
+		expectedError bool
+	}{
+		{
+			name:       "a group with a MinSize of 1 keeps 1 instance",
+			minSize:    1,
+			useMinSize: true,
+			expected:   3,
+		},
+		{
+			name:       "a group with a MinSize of 3 keeps 3 instances",
+			minSize:    3,
+			useMinSize: true,
+			expected:   1,
+		},
+		{
+			name:          "a group which is at its MinSize isn't touched",
+			minSize:       4,
+			useMinSize:    true,
+			expectedError: true,
+		},
+		{
+			name:     "the MinSize is ignored unless UseMinSize is set",
+			minSize:  3,
+			expected: 2,
+		},
+	}
+
+	for _, test := range tests {
+		group := AutoScalingGroup{
+			Name: "asg_api",
+			Instances: []Instance{
+				{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+			},
+			InstanceDetails: InstanceDetails{
+				{ID: "i-1", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-2", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-3", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-4", VersionNumber: semver.MustParse("1.0.0")},
+			},
+			MinSize:    test.minSize,
+			UseMinSize: test.useMinSize,
+		}
+
+		decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 2, 0)
+		targets := DecisionIDs(decisions)
+
+		if _, isSafetyError := err.(SafetyError); test.expectedError != isSafetyError {
+			t.Errorf("For test \"%s\", expected a safety error %v, but got %v", test.name, test.expectedError, err)
+		}
+		if len(targets) != test.expected {
+			t.Errorf("For test \"%s\", expected %d targets, but got %v", test.name, test.expected, targets)
+		}
+	}
+}
+
+func TestTerminationReasons(t *testing.T) {
+	now := time.Now()
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-5", HealthStatus: "Healthy", LifecycleState: "InService"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-3 * time.Hour)},
+			{ID: "i-2", VersionNumber: semver.MustParse("3.0.0"), LaunchTime: now.Add(-2 * time.Hour)},
+			{ID: "i-3", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-48 * time.Hour)},
+			{ID: "i-4", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-time.Hour)},
+			{ID: "i-5", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-time.Hour)},
+		},
+		MaximumAge: 24 * time.Hour,
+	}
+
+	decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 1, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TerminationDecision{
+		{ID: "i-3", Reason: ReasonAgeExceeded, Version: semver.MustParse("2.0.0")},
+		{ID: "i-1", Reason: ReasonBelowCanonical, Version: semver.MustParse("1.0.0")},
+		{ID: "i-2", Reason: ReasonAboveCanonical, Version: semver.MustParse("3.0.0")},
+		{ID: "i-4", Reason: ReasonTrimmed, Version: semver.MustParse("2.0.0")},
+	}
+	if !reflect.DeepEqual(decisions, expected) {
+		t.Errorf("Expected decisions %+v, but got %+v", expected, decisions)
+	}
+}
+
+func TestTheDirectionLimitsTheTargets(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		direction Direction
+		expected  []TerminationDecision
+		excluded  []string
+	}{
+		{
+			direction: "",
+			expected: []TerminationDecision{
+				{ID: "i-1", Reason: ReasonBelowCanonical, Version: semver.MustParse("1.0.0")},
+				{ID: "i-2", Reason: ReasonAboveCanonical, Version: semver.MustParse("3.0.0")},
+			},
+		},
+		{
+			direction: DirectionBoth,
+			expected: []TerminationDecision{
+				{ID: "i-1", Reason: ReasonBelowCanonical, Version: semver.MustParse("1.0.0")},
+				{ID: "i-2", Reason: ReasonAboveCanonical, Version: semver.MustParse("3.0.0")},
+			},
+		},
+		{
+			direction: DirectionBelow,
+			expected: []TerminationDecision{
+				{ID: "i-1", Reason: ReasonBelowCanonical, Version: semver.MustParse("1.0.0")},
+			},
+			excluded: []string{"i-2"},
+		},
+		{
+			direction: DirectionAbove,
+			expected: []TerminationDecision{
+				{ID: "i-2", Reason: ReasonAboveCanonical, Version: semver.MustParse("3.0.0")},
+			},
+			excluded: []string{"i-1"},
+		},
+	}
+
+	for _, test := range tests {
+		// The instances below and above canonical are last, so that they'd be trimmed if they weren't excluded.
+		group := AutoScalingGroup{
+			Name: "asg_api",
+			Instances: []Instance{
+				{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			},
+			InstanceDetails: InstanceDetails{
+				{ID: "i-3", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-4 * time.Hour)},
+				{ID: "i-4", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: now.Add(-4 * time.Hour)},
+				{ID: "i-1", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: now.Add(-3 * time.Hour)},
+				{ID: "i-2", VersionNumber: semver.MustParse("3.0.0"), LaunchTime: now.Add(-2 * time.Hour)},
+			},
+			Direction: test.direction,
+		}
+
+		decisions, kept, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 2, 0)
+
+		if err != nil {
+			t.Fatalf("%q: %v", test.direction, err)
+		}
+
+		if !reflect.DeepEqual(decisions, test.expected) {
+			t.Errorf("%q: expected decisions %+v, but got %+v", test.direction, test.expected, decisions)
+		}
+
+		var excluded []string
+		for _, k := range kept {
+			if k.Reason == KeepExcludedDirection {
+				excluded = append(excluded, k.ID)
+			}
+		}
+		if !reflect.DeepEqual(excluded, test.excluded) {
+			t.Errorf("%q: expected %v to be kept because of the direction, but got %v", test.direction, test.excluded, excluded)
+		}
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	for _, s := range []string{"both", "below", "above"} {
+		if d, err := ParseDirection(s); err != nil || string(d) != s {
+			t.Errorf("Expected %q to be parsed, but got %q, %v", s, d, err)
+		}
+	}
+
+	if _, err := ParseDirection("sideways"); err == nil {
+		t.Error("Expected an unknown direction to be rejected")
+	}
+}
+
+func TestTerminationReasonsOfOtherRules(t *testing.T) {
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0")},
+			{ID: "i-2", VersionNumber: semver.MustParse("2.0.0")},
+		},
+	}
+
+	outdated, _, err := group.GetOutdatedInstances(semver.MustParse("2.0.0"), 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outdated) != 1 || outdated[0].Reason != ReasonBelowCanonical {
+		t.Errorf("Expected the outdated instance to be below the minimum, but got %+v", outdated)
+	}
+
+	outside, _, err := group.GetInstancesOutsideRange(semver.MustParseRange(">=2.0.0"), 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outside) != 1 || outside[0].Reason != ReasonOutsideRange {
+		t.Errorf("Expected the instance to be outside the range, but got %+v", outside)
+	}
+}
+
+func TestGetMismatchedInstancesUsesTheVersionScheme(t *testing.T) {
+	tests := []struct {
+		scheme    VersionScheme
+		canonical string
+		versions  []string
+		minimum   int
+		expected  []TerminationDecision
+	}{
+		{
+			scheme:    VersionSchemeInteger,
+			canonical: "10",
+			versions:  []string{"10", "9", "11"},
+			minimum:   1,
+			expected: []TerminationDecision{
+				{ID: "i-2", Reason: ReasonBelowCanonical},
+				{ID: "i-3", Reason: ReasonAboveCanonical},
+			},
+		},
+		{
+			scheme:    VersionSchemeLexicographic,
+			canonical: "2024.03.15",
+			versions:  []string{"2024.03.15", "2024.03.15", "2023.12.01"},
+			minimum:   2,
+			expected: []TerminationDecision{
+				{ID: "i-3", Reason: ReasonBelowCanonical},
+			},
+		},
+		{
+			scheme:    VersionSchemeInteger,
+			canonical: "4821",
+			versions:  []string{"4821", "4821", "4821"},
+			minimum:   1,
+			expected:  []TerminationDecision{},
+		},
+	}
+
+	for _, test := range tests {
+		group := AutoScalingGroup{Name: "asg_api"}
+		launched := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, v := range test.versions {
+			id := "i-" + string(rune('1'+i))
+			group.Instances = append(group.Instances, Instance{ID: id, HealthStatus: "Healthy", LifecycleState: "InService"})
+			group.InstanceDetails = append(group.InstanceDetails, InstanceDetail{ID: id, RawVersion: v, Scheme: test.scheme, LaunchTime: launched.Add(time.Duration(i) * time.Minute)})
+		}
+
+		actual, _, err := group.GetMismatchedInstances(test.canonical, test.scheme.Comparator(), test.minimum, 0)
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.scheme, err)
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s %s: expected %v, but got %v", test.scheme, test.canonical, test.expected, actual)
+		}
+	}
+}
+
+func TestInstancesWithoutDetails(t *testing.T) {
+	tests := []struct {
+		name                string
+		allowPartialDetails bool
+		expected            []string
+		expectedError       bool
+	}{
+		{
+			name:          "by default, the group is left alone",
+			expectedError: true,
+		},
+		{
+			name:                "when partial details are allowed, only instances with details are targeted",
+			allowPartialDetails: true,
+			expected:            []string{"i-1", "i-2"},
+		},
+	}
+
+	for _, test := range tests {
+		group := AutoScalingGroup{
+			Name: "asg_api",
+			Instances: []Instance{
+				{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService"},
+				{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+			},
+			InstanceDetails: InstanceDetails{
+				{ID: "i-1", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-2", VersionNumber: semver.MustParse("1.0.0")},
+				{ID: "i-3", VersionNumber: semver.MustParse("1.0.0")},
+			},
+			AllowPartialDetails: test.allowPartialDetails,
+		}
+
+		decisions, _, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 1, 0)
+		targets := DecisionIDs(decisions)
+
+		if _, isSafetyError := err.(SafetyError); test.expectedError != isSafetyError {
+			t.Errorf("For test \"%s\", expected a safety error %v, but got %v", test.name, test.expectedError, err)
+		}
+		if !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("For test \"%s\", expected targets %v, but got %v", test.name, test.expected, targets)
+		}
+	}
+}
+
+func TestKeptReasonsAtTheMinimumFloor(t *testing.T) {
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-3", HealthStatus: "Unhealthy", LifecycleState: "InService"},
+			{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "Terminating"},
+			{ID: "i-5", HealthStatus: "Healthy", LifecycleState: "Pending"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0")},
+			{ID: "i-2", VersionNumber: semver.MustParse("2.0.0")},
+		},
+	}
+
+	decisions, kept, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 2, 0)
+
+	if _, isSafetyError := err.(SafetyError); !isSafetyError {
+		t.Fatalf("Expected a safety error for a group at its minimum, but got %v", err)
+	}
+
+	if len(decisions) != 0 {
+		t.Errorf("Expected no instances to be targeted, but got %v", decisions)
+	}
+
+	expected := []KeepDecision{
+		{ID: "i-1", Reason: KeepMinimumCount, Version: "1.0.0"},
+		{ID: "i-2", Reason: KeepVersionMatches, Version: "2.0.0"},
+		{ID: "i-3", Reason: KeepUnhealthy},
+		{ID: "i-4", Reason: KeepTerminating},
+		{ID: "i-5", Reason: KeepTransitioning},
+	}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, kept)
+	}
+}
+
+func TestKeptReasonsAboveTheMinimumFloor(t *testing.T) {
+	group := AutoScalingGroup{
+		Name: "asg_api",
+		Instances: []Instance{
+			{ID: "i-1", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-2", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-3", HealthStatus: "Healthy", LifecycleState: "InService", ProtectedFromScaleIn: true},
+			{ID: "i-4", HealthStatus: "Healthy", LifecycleState: "InService"},
+			{ID: "i-5", HealthStatus: "Healthy", LifecycleState: "InService"},
+		},
+		InstanceDetails: InstanceDetails{
+			{ID: "i-1", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: time.Now().Add(-2 * time.Hour)},
+			{ID: "i-2", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: time.Now().Add(-time.Hour)},
+			{ID: "i-3", VersionNumber: semver.MustParse("1.0.0"), LaunchTime: time.Now().Add(-time.Hour)},
+			{ID: "i-4", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: time.Now().Add(-time.Hour)},
+			{ID: "i-5", VersionNumber: semver.MustParse("2.0.0"), LaunchTime: time.Now().Add(-time.Hour)},
+		},
+	}
+
+	decisions, kept, err := group.GetTargetInstances(semver.MustParse("2.0.0"), 4, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if targets := DecisionIDs(decisions); !reflect.DeepEqual(targets, []string{"i-1"}) {
+		t.Errorf("Expected only the oldest mismatched instance to be targeted, but got %v", targets)
+	}
+
+	expected := []KeepDecision{
+		{ID: "i-2", Reason: KeepMinimumCount, Version: "1.0.0"},
+		{ID: "i-3", Reason: KeepProtected, Version: "1.0.0"},
+		{ID: "i-4", Reason: KeepVersionMatches, Version: "2.0.0"},
+		{ID: "i-5", Reason: KeepVersionMatches, Version: "2.0.0"},
+	}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, kept)
+	}
+}
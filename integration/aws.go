@@ -2,78 +2,289 @@ package integration
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/blang/semver"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 // CloudProvider provides all of the methods required to integrate with AWS.
 type CloudProvider interface {
 	// DescribeAutoScalingGroups provides information about the available auto-scaling groups.
-	DescribeAutoScalingGroups(names []string, scheme string, port int, path string) ([]AutoScalingGroup, error)
+	DescribeAutoScalingGroups(ctx context.Context, names []string, scheme string, port int, path string) ([]AutoScalingGroup, error)
 	// GetDetail returns the launch time and version number returned by accessing the EC2 API and
 	// hitting the provided endpoint in the form {scheme}://{ec2.private_ip}:{port}{endpoint}
 	// instanceID refers to the ID of the AWS EC2 instance
 	// scheme is the protocol - http or https
 	// port is the TCP port, e.g. 80 or 443
-	// URL is the URL e.g. /version
-	GetDetail(instanceID string, scheme string, port int, endpoint string) (*InstanceDetail, error)
+	// URL is the URL e.g. /version, or a comma-separated list of URLs which are tried in order, e.g. /version,/status
+	GetDetail(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*InstanceDetail, error)
 	// TerminateInstances terminates the given instances.
-	TerminateInstances(instanceIDs []string) error
+	TerminateInstances(ctx context.Context, instanceIDs []string) error
+	// TerminateGroupInstances terminates the given instances through the auto-scaling API, so the
+	// group's desired capacity is decremented when shouldDecrementDesiredCapacity is set, instead of the group
+	// launching replacements.
+	TerminateGroupInstances(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error
+	// CompleteLifecycle completes the termination lifecycle hooks of the group for the instance.
+	CompleteLifecycle(ctx context.Context, groupName string, instanceID string) error
+	// DrainInstance stops the instance of the group from receiving new connections from its load balancers.
+	DrainInstance(ctx context.Context, groupName string, instanceID string) error
+	// EnterStandby moves the instances of the group into standby without decrementing its desired capacity, so
+	// the group launches their replacements.
+	EnterStandby(ctx context.Context, groupName string, instanceIDs []string) error
+	// SuspendProcesses stops the group running the auto-scaling processes, e.g. ProcessLaunch.
+	SuspendProcesses(ctx context.Context, groupName string, processes []string) error
+	// ResumeProcesses lets the group run the auto-scaling processes again.
+	ResumeProcesses(ctx context.Context, groupName string, processes []string) error
 
-	GetInstanceDetails(instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (InstanceDetails, error)
+	GetInstanceDetails(ctx context.Context, instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (InstanceDetails, error)
+	// Notify publishes a summary of the instances terminated from each group to the SNS topic.
+	Notify(ctx context.Context, topicARN string, terminated map[string][]string) error
+	// PutMetrics publishes the metrics of each group to CloudWatch in the namespace.
+	PutMetrics(ctx context.Context, namespace string, metrics []GroupMetrics) error
 }
 
 // AWSProvider provides data from AWS.
 type AWSProvider struct {
-	session *session.Session
+	session     *session.Session
+	autoScaling autoscalingiface.AutoScalingAPI
+	ec2         ec2iface.EC2API
+	elbv2       elbv2iface.ELBV2API
+	ssm         ssmiface.SSMAPI
+	sns         snsiface.SNSAPI
+	cloudWatch  cloudwatchiface.CloudWatchAPI
+	// VersionSource determines where the version of each instance is read from.
+	VersionSource VersionSource
+	// VersionTagKey is the key of the EC2 tag, e.g. app:version, which the version of each instance is read
+	// from with the tag VersionSource.
+	VersionTagKey string
+	// ProbeRetries is the number of times a failed version probe is retried.
+	ProbeRetries int
+	// ProbeBackoff is the delay before the first retry of a version probe, doubling on each retry.
+	ProbeBackoff time.Duration
+	// VersionCacheTTL, when greater than zero, is how long the version reported by an instance is reused for,
+	// instead of probing the instance again. Instances are probed again when their launch time changes.
+	VersionCacheTTL time.Duration
+	versions        versionCache
+	probeClients    probeClients
+	// ThrottleRetries is the number of times an AWS API call which was throttled is retried.
+	ThrottleRetries int
+	// ThrottleBackoff is the delay before the first retry of a throttled AWS API call, doubling on each retry.
+	ThrottleBackoff time.Duration
+	// ProbeTransport determines how version probes reach the instance.
+	ProbeTransport ProbeTransport
+	// SSH configures the tunnel used when ProbeTransport is ProbeTransportSSH.
+	SSH SSHConfig
+	// Concurrency is the maximum number of instances in a group which are probed at the same time.
+	Concurrency int
+	// TLSConfig configures probes of HTTPS version endpoints, nil uses the default configuration.
+	TLSConfig *tls.Config
+	// ProbeHeaders are sent with each request to the version endpoint, e.g. an Authorization header.
+	ProbeHeaders http.Header
+	// MaxResponseBytes, when greater than zero, is the largest version endpoint response which will be read.
+	MaxResponseBytes int64
+	// VersionHeader, when set, is the response header which the version is read from instead of the body.
+	VersionHeader string
+	// VersionJSONPath, when set, is the path to the version within a JSON response, e.g. ".app.version".
+	VersionJSONPath string
+	// VersionRegex, when set, extracts the version from the response using its capture group, e.g.
+	// `Version: (\S+)`, after VersionJSONPath is applied.
+	VersionRegex *regexp.Regexp
+	// GroupNamePattern, when set, limits the auto-scaling groups to those whose names match.
+	GroupNamePattern *regexp.Regexp
+	// SelectorTag, when set, limits the auto-scaling groups to those which have the tag.
+	SelectorTag *Tag
+	// StandbyCountsAsHealthy is set when healthy instances in standby count as healthy instances.
+	StandbyCountsAsHealthy bool
+	// UseMinSize is set when the MinSize of each group is the number of healthy instances to leave in it.
+	UseMinSize bool
+	// UseWeightedCapacity is set when the minimum is the capacity to leave in each group, summing the weights of
+	// its instances, see AutoScalingGroup.UseWeightedCapacity.
+	UseWeightedCapacity bool
+	// AllowPartialDetails is set when groups are acted on even if the details of some healthy instances couldn't
+	// be read, see AutoScalingGroup.AllowPartialDetails.
+	AllowPartialDetails bool
+	// MinInstanceAge, when greater than zero, spares instances launched more recently than the age from termination.
+	MinInstanceAge time.Duration
+	// MaxInstanceAge, when greater than zero, makes instances launched longer ago than the age candidates for termination.
+	MaxInstanceAge time.Duration
+	// VersionScheme determines how versions are compared, the versions of instances are only parsed as
+	// semantic versions with the semver scheme.
+	VersionScheme VersionScheme
+	// NormalizeVersions removes build metadata and git describe suffixes from the versions of instances.
+	NormalizeVersions bool
+	// UsePublicIP is set when version probes connect to the public IP address of instances instead of the private IP address.
+	UsePublicIP bool
+	// IPFamily determines whether version probes connect to the IPv4 or IPv6 address of instances. The IPv4
+	// address is used when it's empty, unless the instance only has an IPv6 address.
+	IPFamily IPFamily
+	// PortTag, when set, is the key of an EC2 tag, e.g. terminator:port, whose value is the port to probe each
+	// instance on, e.g. when service generations listen on different management ports. Instances without the tag
+	// are probed on the port passed to GetDetail.
+	PortTag string
+	// HealthURL, when set, is the path of a health endpoint, e.g. /healthz, which is probed before the version.
+	// Instances which fail the health check are treated as unhealthy, and their version isn't probed.
+	HealthURL string
+	// VersionOverrides, when set, maps the IDs of instances to the versions they're treated as running, instead
+	// of probing them, e.g. to rehearse a dry run. Instances without an override have no details.
+	VersionOverrides map[string]string
+	// DrainMode determines how DrainInstance stops instances receiving new connections.
+	DrainMode DrainMode
+	// DrainTimeout, when greater than zero, is the longest DrainInstance waits for an instance to finish
+	// draining from its target groups with the targetGroups drain mode.
+	DrainTimeout time.Duration
+}
+
+// AssumeRole configures the IAM role which the provider assumes, e.g. to manage groups in another account.
+type AssumeRole struct {
+	// ARN is the role to assume, when empty, the credentials of the session are used.
+	ARN string
+	// ExternalID is passed to STS when assuming the role, if the role's trust policy requires it.
+	ExternalID string
 }
 
 // NewAWSProvider creates an AWSProvider.
 // region, the default AWS region e.g. "eu-west-1"
-func NewAWSProvider(region string) (*AWSProvider, error) {
-	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+// role, the IAM role to assume, if any
+// endpoint, when set, the URL of the AWS APIs, e.g. "http://localhost:4566" for LocalStack
+// profile, when set, the named profile of the shared credentials and config files to use
+func NewAWSProvider(region string, role AssumeRole, endpoint string, profile string) (*AWSProvider, error) {
+	sess, err := session.NewSessionWithOptions(sessionOptions(region, endpoint, profile))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a session, %-v", err)
 	}
 
-	return &AWSProvider{session: sess}, nil
+	if role.ARN != "" {
+		sess = sess.Copy(&aws.Config{Credentials: newAssumeRoleCredentials(sts.New(sess), role)})
+	}
+
+	return &AWSProvider{
+		session:         sess,
+		autoScaling:     autoscaling.New(sess),
+		ec2:             ec2.New(sess),
+		elbv2:           elbv2.New(sess),
+		ssm:             ssm.New(sess),
+		sns:             sns.New(sess),
+		cloudWatch:      cloudwatch.New(sess),
+		VersionSource:   VersionSourceHTTP,
+		ProbeTransport:  ProbeTransportDirect,
+		DrainMode:       DrainModeStandby,
+		Concurrency:     1,
+		ThrottleRetries: defaultThrottleRetries,
+		ThrottleBackoff: defaultThrottleBackoff,
+	}, nil
+}
+
+// sessionOptions configures the session of the provider. When a profile is used, the shared config file is
+// loaded alongside the credentials file, so that profiles which assume roles also work.
+func sessionOptions(region string, endpoint string, profile string) session.Options {
+	opts := session.Options{
+		Config: aws.Config{Region: aws.String(region)},
+	}
+
+	if endpoint != "" {
+		opts.Config.Endpoint = aws.String(endpoint)
+	}
+
+	if profile != "" {
+		opts.Profile = profile
+		opts.SharedConfigState = session.SharedConfigEnable
+	}
+
+	return opts
 }
 
 // DescribeAutoScalingGroups provides information about the available auto-scaling groups.
-func (p *AWSProvider) DescribeAutoScalingGroups(names []string, scheme string, port int, path string) ([]AutoScalingGroup, error) {
-	fmt.Println("Retrieving data on autoscaling groups:", names)
+func (p *AWSProvider) DescribeAutoScalingGroups(ctx context.Context, names []string, scheme string, port int, path string) ([]AutoScalingGroup, error) {
+	slog.Debug("retrieving data on autoscaling groups", "groups", names)
 	start := time.Now()
-	svc := autoscaling.New(p.session)
-
-	awsGroups, err := svc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
-		AutoScalingGroupNames: convert(names),
+	requested := map[string]bool{}
+	for _, name := range names {
+		requested[name] = true
+	}
+	var awsGroups []*autoscaling.Group
+	var found map[string]bool
+	err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+		// A throttled page restarts the pagination, so the groups of earlier attempts are discarded.
+		awsGroups = nil
+		found = map[string]bool{}
+		return p.autoScaling.DescribeAutoScalingGroupsPagesWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: convert(names),
+		}, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+			for _, g := range page.AutoScalingGroups {
+				name := aws.StringValue(g.AutoScalingGroupName)
+				if len(requested) > 0 && !requested[name] {
+					continue
+				}
+				found[name] = true
+				if p.isSelected(g) {
+					awsGroups = append(awsGroups, g)
+				}
+			}
+			return true
+		})
 	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the description of all autoscaling groups, %-v", err)
 	}
 
-	groups := make([]AutoScalingGroup, len(awsGroups.AutoScalingGroups))
-	errorCount := 0
+	for _, name := range names {
+		if !found[name] {
+			slog.Warn("no auto-scaling group has the name, skipping it", "group", name)
+		}
+	}
 
-	for i, g := range awsGroups.AutoScalingGroups {
+	groups := make([]AutoScalingGroup, 0, len(awsGroups))
+
+	for _, g := range awsGroups {
 		groupName := aws.StringValue(g.AutoScalingGroupName)
-		fmt.Printf("%s => Getting instance details for this autoscaling group.\n", groupName)
+		slog.Debug("getting instance details for this autoscaling group", "group", groupName)
+
+		instances := g.Instances
+		var failedHealthCheck map[string]bool
+		if p.HealthURL != "" {
+			failedHealthCheck = checkHealthConcurrently(g.Instances, groupName, p.Concurrency, func(instanceID string) error {
+				return p.GetHealth(ctx, instanceID, scheme, port, p.HealthURL)
+			})
+			instances = withoutInstances(g.Instances, failedHealthCheck)
+		}
 
-		instanceDetails, err := p.GetInstanceDetails(g.Instances, groupName, scheme, port, path)
+		instanceDetails, err := p.GetInstanceDetails(ctx, instances, groupName, scheme, port, path)
 		if err != nil {
-			fmt.Printf("%s => Failed to get instance details, skipping this group\n", groupName)
-			errorCount++
+			slog.Warn("failed to get instance details, skipping this group", "group", groupName, "error", err)
 			continue
 		}
 
@@ -81,53 +292,463 @@ func (p *AWSProvider) DescribeAutoScalingGroups(names []string, scheme string, p
 			aws.StringValue(g.AutoScalingGroupName),
 			g.Instances,
 			instanceDetails)
+		for i := range asg.Instances {
+			asg.Instances[i].FailedHealthCheck = failedHealthCheck[asg.Instances[i].ID]
+		}
+		asg.DesiredCapacity = int(aws.Int64Value(g.DesiredCapacity))
+		asg.MinSize = int(aws.Int64Value(g.MinSize))
+		asg.UseMinSize = p.UseMinSize
+		asg.AllowPartialDetails = p.AllowPartialDetails
+		asg.StandbyCountsAsHealthy = p.StandbyCountsAsHealthy
+		asg.MinimumAge = p.MinInstanceAge
+		asg.MaximumAge = p.MaxInstanceAge
+		asg.UseWeightedCapacity = p.UseWeightedCapacity
 
-		fmt.Printf("%s => Retrieved all instance details.", asg.Name)
-		groups[i] = asg
+		slog.Debug("retrieved all instance details", "group", asg.Name)
+		groups = append(groups, asg)
 	}
 
-	fmt.Println("time: *AWSProvider.DescribeAutoScalingGroups() ", time.Since(start))
+	slog.Debug("time: *AWSProvider.DescribeAutoScalingGroups()", "duration", time.Since(start))
 
-	if len(groups) == errorCount {
+	if len(groups) == 0 {
 		return nil, fmt.Errorf("No valid groups found.")
 	}
 
 	return groups, nil
 }
 
-func (p *AWSProvider) GetInstanceDetails(instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (InstanceDetails, error) {
+// isSelected returns true if the group matches the GroupNamePattern and SelectorTag, when they're set.
+func (p *AWSProvider) isSelected(g *autoscaling.Group) bool {
+	if p.GroupNamePattern != nil && !p.GroupNamePattern.MatchString(aws.StringValue(g.AutoScalingGroupName)) {
+		return false
+	}
+
+	return p.SelectorTag == nil || p.SelectorTag.isOn(g)
+}
+
+func (p *AWSProvider) GetInstanceDetails(ctx context.Context, instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (InstanceDetails, error) {
 	start := time.Now()
-	details := InstanceDetails{}
 
+	getDetail := func(instanceID string) (*InstanceDetail, error) {
+		if len(p.VersionOverrides) > 0 {
+			return p.getOverriddenDetail(ctx, instanceID)
+		}
+		if p.VersionSource == VersionSourceSSMInventory {
+			return p.GetInventoryDetail(ctx, instanceID)
+		}
+		return p.GetDetail(ctx, instanceID, scheme, port, path)
+	}
+
+	details := getDetailsConcurrently(instances, groupName, p.Concurrency, getDetail)
+
+	slog.Debug("time: *AWSProvider.GetInstanceDetails()", "group", groupName, "duration", time.Since(start))
+
+	if len(details) <= 0 {
+		return nil, fmt.Errorf("Couldn't get any instance details")
+	}
+
+	return details, nil
+}
+
+// checkHealthConcurrently checks the health of the instances using at most concurrency simultaneous calls to
+// check, and returns the IDs of the instances which failed the check.
+func checkHealthConcurrently(instances []*autoscaling.Instance, groupName string, concurrency int, check func(instanceID string) error) map[string]bool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var m sync.Mutex
+	failed := map[string]bool{}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
 	for _, instance := range instances {
 		instanceID := aws.StringValue(instance.InstanceId)
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := check(instanceID); err != nil {
+				slog.Warn("instance failed the health check", "group", groupName, "instance", instanceID, "error", err)
+				m.Lock()
+				failed[instanceID] = true
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
 
-		fmt.Printf("%s => %s => Getting instance details.\n", groupName, instanceID)
-		detail, err := p.GetDetail(instanceID, scheme, port, path)
+	return failed
+}
 
-		if err != nil {
-			fmt.Printf("%s => %s => %+v\n", groupName, instanceID, err)
-			continue
+// withoutInstances returns the instances whose IDs aren't excluded.
+func withoutInstances(instances []*autoscaling.Instance, excluded map[string]bool) []*autoscaling.Instance {
+	result := []*autoscaling.Instance{}
+	for _, instance := range instances {
+		if !excluded[aws.StringValue(instance.InstanceId)] {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// getDetailsConcurrently gets the details of the instances using at most concurrency simultaneous calls to
+// getDetail. Instances whose details can't be retrieved are skipped.
+func getDetailsConcurrently(instances []*autoscaling.Instance, groupName string, concurrency int, getDetail func(instanceID string) (*InstanceDetail, error)) InstanceDetails {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	instanceIDs := make(chan string)
+	results := make(chan *InstanceDetail)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for instanceID := range instanceIDs {
+				slog.Debug("getting instance details", "group", groupName, "instance", instanceID)
+				detail, err := getDetail(instanceID)
+
+				if err != nil {
+					slog.Warn("failed to get instance details", "group", groupName, "instance", instanceID, "error", err)
+					continue
+				}
+
+				slog.Debug("retrieved instance details", "group", groupName, "instance", instanceID, "version", detail.Version())
+				results <- detail
+			}
+		}()
+	}
+
+	go func() {
+		for _, instance := range instances {
+			instanceIDs <- aws.StringValue(instance.InstanceId)
 		}
+		close(instanceIDs)
+		wg.Wait()
+		close(results)
+	}()
 
-		fmt.Printf("%s => %s => Retrieved instances details. Version %s", groupName, instanceID, detail.VersionNumber)
+	details := InstanceDetails{}
+	for detail := range results {
 		details = append(details, *detail)
 	}
 
-	fmt.Println("time: *AWSProvider.GetInstanceDetails() ", time.Since(start))
+	return details
+}
 
-	if len(details) <= 0 {
-		return nil, fmt.Errorf("Couldn't get any instance details")
+// GetHealth probes the health endpoint of the instance in the form {scheme}://{ec2.private_ip}:{port}{endpoint},
+// and returns an error unless it responds with a 2xx status.
+func (p *AWSProvider) GetHealth(ctx context.Context, instanceID string, scheme string, port int, endpoint string) error {
+	instance, err := p.describeInstance(ctx, instanceID)
+
+	if err != nil {
+		return err
 	}
 
-	return details, nil
+	host, err := probeAddress(instance, p.UsePublicIP, p.IPFamily)
+
+	if err != nil {
+		return err
+	}
+
+	port, err = p.probePort(instance, port)
+
+	if err != nil {
+		return err
+	}
+
+	endpoint, err = renderPath(endpoint, instanceID, host)
+
+	if err != nil {
+		return err
+	}
+
+	complete, client, done, err := p.openProbe(host, scheme, port, endpoint)
+
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	pr := probe{
+		client:           client,
+		headers:          p.ProbeHeaders,
+		maxResponseBytes: p.MaxResponseBytes,
+	}
+
+	if _, err := getURLWithRetries(ctx, pr, complete, p.ProbeRetries, p.ProbeBackoff); err != nil {
+		return fmt.Errorf("Failed the health check at URL %s with error %-v", complete, err)
+	}
+
+	return nil
+}
+
+// openProbe returns the URL of the endpoint on the host, and the client to request it with. When the probe
+// transport is SSH, the URL is reached through a tunnel, which is closed by calling done.
+func (p *AWSProvider) openProbe(host string, scheme string, port int, endpoint string) (complete string, client *http.Client, done func(), err error) {
+	client = p.probeClients.get(p.TLSConfig)
+	done = func() {}
+
+	if p.ProbeTransport == ProbeTransportSSH {
+		tunnel, err := openSSHTunnel(p.SSH, host)
+
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("Failed to open an SSH tunnel to %s with error %-v", host, err)
+		}
+
+		host = "localhost"
+		client = newProbeClient(tunnel.Dial, p.TLSConfig)
+		done = func() { tunnel.Close() }
+	}
+
+	complete = probeURL(scheme, host, port, endpoint)
+
+	if _, err := url.Parse(complete); err != nil {
+		done()
+		return "", nil, nil, fmt.Errorf("Failed to parse URL %s - %-v", complete, err)
+	}
+
+	return complete, client, done, nil
+}
+
+// GetDetail returns information about the instance. The endpoint can be a comma-separated list of paths, e.g.
+// "/version,/status", which are tried in order until one of them returns a version which can be understood.
+func (p *AWSProvider) GetDetail(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*InstanceDetail, error) {
+	instance, err := p.describeInstance(ctx, instanceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.VersionSource == VersionSourceTag {
+		return p.getTagDetail(instance, instanceID)
+	}
+
+	port, err = p.probePort(instance, port)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return getDetailFromPaths(instanceID, endpoint, func(path string) (*InstanceDetail, error) {
+		return p.getDetailFromPath(ctx, instance, instanceID, scheme, port, path)
+	})
+}
+
+// getOverriddenDetail returns information about the instance, using the version in VersionOverrides instead of
+// probing the instance.
+func (p *AWSProvider) getOverriddenDetail(ctx context.Context, instanceID string) (*InstanceDetail, error) {
+	versionNumber, ok := p.VersionOverrides[instanceID]
+
+	if !ok {
+		return nil, fmt.Errorf("there's no version override for instance %s", instanceID)
+	}
+
+	instance, err := p.describeInstance(ctx, instanceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := newInstanceDetail(instanceID, versionNumber, aws.TimeValue(instance.LaunchTime), p.VersionScheme, p.NormalizeVersions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &detail, nil
+}
+
+// getDetailFromPaths gets the detail of the instance from each of the comma-separated paths of the endpoint
+// in turn, until one of them succeeds.
+func getDetailFromPaths(instanceID string, endpoint string, getDetail func(path string) (*InstanceDetail, error)) (*InstanceDetail, error) {
+	paths := strings.Split(endpoint, ",")
+	var failures []string
+
+	for _, path := range paths {
+		detail, err := getDetail(strings.TrimSpace(path))
+
+		if err == nil {
+			return detail, nil
+		}
+
+		if len(paths) == 1 {
+			return nil, err
+		}
+
+		slog.Debug("failed to get the version, trying the next path", "instance", instanceID, "path", path, "error", err)
+		failures = append(failures, err.Error())
+	}
+
+	return nil, fmt.Errorf("all %d version paths failed: %s", len(paths), strings.Join(failures, "; "))
+}
+
+// getDetailFromPath returns information about the instance, reading its version from the endpoint.
+func (p *AWSProvider) getDetailFromPath(ctx context.Context, instance *ec2.Instance, instanceID string, scheme string, port int, endpoint string) (*InstanceDetail, error) {
+	host, err := probeAddress(instance, p.UsePublicIP, p.IPFamily)
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err = renderPath(endpoint, instanceID, host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	launchTime := aws.TimeValue(instance.LaunchTime)
+	cacheKey := newVersionCacheKey(instanceID, launchTime, probeURL(scheme, host, port, endpoint))
+
+	if p.VersionCacheTTL > 0 {
+		if detail, ok := p.versions.get(cacheKey, time.Now()); ok {
+			slog.Debug("using the cached version", "instance", instanceID, "version", detail.Version())
+			return &detail, nil
+		}
+	}
+
+	complete, client, done, err := p.openProbe(host, scheme, port, endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	pr := probe{
+		client:           client,
+		headers:          p.ProbeHeaders,
+		versionHeader:    p.VersionHeader,
+		maxResponseBytes: p.MaxResponseBytes,
+	}
+
+	versionNumber, err := getURLWithRetries(ctx, pr, complete, p.ProbeRetries, p.ProbeBackoff)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
+	}
+
+	if p.VersionJSONPath != "" {
+		versionNumber, err = getJSONPath(versionNumber, p.VersionJSONPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
+		}
+	}
+
+	if p.VersionRegex != nil {
+		versionNumber, err = getVersionMatch(versionNumber, p.VersionRegex)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
+		}
+	}
+
+	detail, err := newInstanceDetail(instanceID, versionNumber, launchTime, p.VersionScheme, p.NormalizeVersions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.VersionCacheTTL > 0 {
+		p.versions.put(cacheKey, detail, time.Now(), p.VersionCacheTTL)
+	}
+
+	return &detail, nil
+}
+
+// newInstanceDetail reads the version returned by an instance using the version scheme.
+func newInstanceDetail(instanceID string, versionNumber string, launchTime time.Time, scheme VersionScheme, normalize bool) (detail InstanceDetail, err error) {
+	detail = InstanceDetail{
+		ID:         instanceID,
+		RawVersion: versionNumber,
+		LaunchTime: launchTime,
+		Scheme:     scheme,
+	}
+
+	if scheme.IsSemver() {
+		detail.VersionNumber, detail.Warnings, err = ParseVersion(versionNumber)
+		if err == nil && normalize {
+			detail.VersionNumber = NormalizeVersion(detail.VersionNumber)
+		}
+	} else {
+		err = scheme.Comparator().Validate(versionNumber)
+	}
+
+	if err != nil {
+		return InstanceDetail{}, fmt.Errorf("Failed to understand the version number %s with error %-v", versionNumber, err)
+	}
+
+	return detail, nil
+}
+
+// probeAddress returns the IP address of the instance which version probes connect to. IPv6 addresses are the
+// same inside and outside the VPC, so usePublicIP only applies to IPv4 addresses.
+func probeAddress(instance *ec2.Instance, usePublicIP bool, family IPFamily) (string, error) {
+	if family == IPFamilyIPv6 {
+		if ip := ipv6Address(instance); ip != "" {
+			return ip, nil
+		}
+		return "", fmt.Errorf("instance %s doesn't have an IPv6 address", aws.StringValue(instance.InstanceId))
+	}
+
+	ip := aws.StringValue(instance.PrivateIpAddress)
+	if usePublicIP {
+		ip = aws.StringValue(instance.PublicIpAddress)
+	}
+	if ip != "" {
+		return ip, nil
+	}
+
+	// IPv6-only instances don't have an IPv4 address.
+	if family != IPFamilyIPv4 {
+		if ip := ipv6Address(instance); ip != "" {
+			return ip, nil
+		}
+	}
+
+	if usePublicIP {
+		return "", fmt.Errorf("instance %s doesn't have a public IP address", aws.StringValue(instance.InstanceId))
+	}
+	return "", fmt.Errorf("instance %s doesn't have a private IP address", aws.StringValue(instance.InstanceId))
+}
+
+// probePort returns the port in the instance's PortTag, or the port when the instance doesn't have the tag.
+func (p *AWSProvider) probePort(instance *ec2.Instance, port int) (int, error) {
+	if p.PortTag == "" {
+		return port, nil
+	}
+
+	for _, tag := range instance.Tags {
+		if aws.StringValue(tag.Key) != p.PortTag {
+			continue
+		}
+
+		value := aws.StringValue(tag.Value)
+		tagged, err := strconv.Atoi(value)
+		if err != nil || tagged < 1 || tagged > 65535 {
+			return 0, fmt.Errorf("instance %s has an invalid %s tag %q, expected a port", aws.StringValue(instance.InstanceId), p.PortTag, value)
+		}
+		return tagged, nil
+	}
+
+	return port, nil
 }
 
-// GetDetail returns information about the instance.
-func (p *AWSProvider) GetDetail(instanceID string, scheme string, port int, endpoint string) (*InstanceDetail, error) {
-	svc := ec2.New(p.session)
-	instances, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
-		InstanceIds: convert([]string{instanceID}),
+// describeInstance returns the EC2 description of a single instance.
+func (p *AWSProvider) describeInstance(ctx context.Context, instanceID string) (*ec2.Instance, error) {
+	var instances *ec2.DescribeInstancesOutput
+	err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() (err error) {
+		instances, err = p.ec2.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: convert([]string{instanceID}),
+		})
+		return err
 	})
 
 	if err != nil {
@@ -136,83 +757,288 @@ func (p *AWSProvider) GetDetail(instanceID string, scheme string, port int, endp
 
 	for _, reservation := range instances.Reservations {
 		for _, instance := range reservation.Instances {
-			ip := aws.StringValue(instance.PrivateIpAddress)
+			return instance, nil
+		}
+	}
 
-			complete := fmt.Sprintf("%s://%s:%d%s", scheme, ip, port, endpoint)
-			u, err := url.Parse(complete)
+	return nil, fmt.Errorf("Could not find an instance with id %s", instanceID)
+}
 
-			if err != nil {
-				return nil, fmt.Errorf("Failed to parse URL %s - %-v", complete, err)
-			}
+// maxTerminateBatchSize is the maximum number of instances EC2 accepts in a single TerminateInstances call.
+const maxTerminateBatchSize = 1000
 
-			versionNumber, err := getURL(u.String())
+// TerminateInstances terminates the given instances, in batches of up to 1000 instances. If a batch
+// fails, the remaining batches are still attempted, and the returned error describes each failed batch.
+func (p *AWSProvider) TerminateInstances(ctx context.Context, instanceIDs []string) error {
+	batches := batch(instanceIDs, maxTerminateBatchSize)
+	var failures []string
 
-			if err != nil {
-				return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
-			}
+	for i, b := range batches {
+		params := &ec2.TerminateInstancesInput{
+			InstanceIds: convert(b),
+		}
 
-			// Trim quotes.
-			versionNumber = strings.Trim(versionNumber, "\"")
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+			_, err := p.ec2.TerminateInstancesWithContext(ctx, params)
+			return err
+		})
 
-			// Trim v from any version number returned from a URL.
-			if strings.HasPrefix(versionNumber, "v") {
-				versionNumber = versionNumber[1:]
-			}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("batch %d of %d (%s to %s) failed, %v", i+1, len(batches), b[0], b[len(b)-1], err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to terminate instances: %s", strings.Join(failures, "; "))
+	}
 
-			version, err := semver.Make(versionNumber)
+	return nil
+}
 
-			if err != nil {
-				return nil, fmt.Errorf("Failed to understand the version number %s with error %-v", versionNumber, err)
-			}
+// TerminateGroupInstances terminates the given instances one at a time, since the auto-scaling API
+// accepts a single instance per call. If an instance fails, the remaining instances are still attempted, and the
+// returned error describes each failure.
+func (p *AWSProvider) TerminateGroupInstances(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error {
+	var failures []string
+
+	for _, id := range instanceIDs {
+		params := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     aws.String(id),
+			ShouldDecrementDesiredCapacity: aws.Bool(shouldDecrementDesiredCapacity),
+		}
 
-			return &InstanceDetail{
-				ID:            instanceID,
-				VersionNumber: version,
-				LaunchTime:    aws.TimeValue(instance.LaunchTime),
-			}, nil
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+			_, err := p.autoScaling.TerminateInstanceInAutoScalingGroupWithContext(ctx, params)
+			return err
+		})
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s failed, %v", id, err))
 		}
 	}
 
-	return nil, fmt.Errorf("Could not find an instance with id %s", instanceID)
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to terminate instances: %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// newAssumeRoleCredentials returns credentials for the role, which are retrieved from STS using the client.
+func newAssumeRoleCredentials(client stscreds.AssumeRoler, role AssumeRole) *credentials.Credentials {
+	return stscreds.NewCredentialsWithClient(client, role.ARN, func(p *stscreds.AssumeRoleProvider) {
+		if role.ExternalID != "" {
+			p.ExternalID = aws.String(role.ExternalID)
+		}
+	})
+}
+
+// batch splits the values into batches of at most size values.
+func batch(values []string, size int) [][]string {
+	var batches [][]string
+
+	for len(values) > size {
+		batches = append(batches, values[:size])
+		values = values[size:]
+	}
+
+	if len(values) > 0 {
+		batches = append(batches, values)
+	}
+
+	return batches
+}
+
+// probeTimeout is the longest a single request to a version or health endpoint can take.
+const probeTimeout = 30 * time.Second
+
+// newProbeClient creates an HTTP client which opens its connections with dial, or when dial is nil,
+// connects directly. tlsConfig configures HTTPS connections, nil uses the default configuration. Connections
+// opened with dial aren't kept alive, since each one is only used for a single probe.
+func newProbeClient(dial func(network, address string) (net.Conn, error), tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Dial:              dial,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: dial != nil,
+		},
+		Timeout: probeTimeout,
+	}
+}
+
+// probeClients reuses a client for each TLS configuration to probe instances directly, so that each probe
+// doesn't leave the idle connections of a new transport open. The zero value has no clients, and it's safe for
+// concurrent use.
+type probeClients struct {
+	m       sync.Mutex
+	clients map[*tls.Config]*http.Client
 }
 
-// TerminateInstances terminates the given instances.
-func (p *AWSProvider) TerminateInstances(instanceIDs []string) error {
-	params := &ec2.TerminateInstancesInput{
-		InstanceIds: convert(instanceIDs),
+// get returns the client for the TLS configuration, creating it on first use.
+func (c *probeClients) get(tlsConfig *tls.Config) *http.Client {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.clients == nil {
+		c.clients = map[*tls.Config]*http.Client{}
+	}
+
+	client, ok := c.clients[tlsConfig]
+	if !ok {
+		client = newProbeClient(nil, tlsConfig)
+		c.clients[tlsConfig] = client
+	}
+
+	return client
+}
+
+// NewTLSConfig creates the TLS configuration used to probe HTTPS version endpoints. When insecureSkipVerify is
+// set, certificates aren't verified, allowing self-signed certificates. When caBundle is set, it is the path to
+// a PEM file of the certificate authorities to trust.
+func NewTLSConfig(insecureSkipVerify bool, caBundle string) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caBundle != "" {
+		pem, err := ioutil.ReadFile(caBundle)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle %s, %-v", caBundle, err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to find any certificates in the CA bundle %s", caBundle)
+		}
 	}
 
-	svc := ec2.New(p.session)
-	_, err := svc.TerminateInstances(params)
+	return config, nil
+}
 
-	return err
+// probe configures the request for the version of an instance.
+type probe struct {
+	client *http.Client
+	// headers are sent with the request.
+	headers http.Header
+	// versionHeader, when set, is the response header which the version is read from instead of the body.
+	versionHeader string
+	// maxResponseBytes, when greater than zero, is the largest response body which will be read.
+	maxResponseBytes int64
 }
 
-func getURL(url string) (string, error) {
-	request, err := http.NewRequest("GET", url, nil)
+// getURL requests the URL, and returns the body of the response, or when the probe has a versionHeader,
+// the value of that response header.
+func getURL(ctx context.Context, pr probe, url string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 
 	if err != nil {
 		return "", err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(request)
+	for name, values := range pr.headers {
+		request.Header[name] = values
+	}
+
+	resp, err := pr.client.Do(request)
 
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxStatusErrorBodyBytes))
+		return "", statusError{URL: url, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+	}
+
+	if pr.versionHeader != "" {
+		value := resp.Header.Get(pr.versionHeader)
+
+		if value == "" {
+			return "", fmt.Errorf("%s did not return the %s header", url, pr.versionHeader)
+		}
+
+		return value, nil
+	}
+
+	var body io.Reader = resp.Body
+	if pr.maxResponseBytes > 0 {
+		// Read one byte more than the limit to tell whether the limit was exceeded.
+		body = io.LimitReader(resp.Body, pr.maxResponseBytes+1)
+	}
+
 	buf := new(bytes.Buffer)
-	_, err = buf.ReadFrom(resp.Body)
+	_, err = buf.ReadFrom(body)
 
 	if err != nil {
 		return "", err
 	}
 
+	if pr.maxResponseBytes > 0 && int64(buf.Len()) > pr.maxResponseBytes {
+		return "", fmt.Errorf("%s returned a response larger than the limit of %d bytes", url, pr.maxResponseBytes)
+	}
+
 	return buf.String(), nil
 }
 
+// statusError is returned when a version endpoint responds with an unexpected HTTP status.
+type statusError struct {
+	URL        string
+	StatusCode int
+	// Body is the start of the response body, to help diagnose the failure.
+	Body string
+}
+
+// maxStatusErrorBodyBytes is the amount of the response body included in a statusError.
+const maxStatusErrorBodyBytes = 256
+
+func (e statusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s returned HTTP status %d", e.URL, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned HTTP status %d: %q", e.URL, e.StatusCode, e.Body)
+}
+
+// getURLWithRetries gets the URL, retrying network errors and server errors up to retries times.
+// The delay between attempts starts at backoff and doubles after each attempt. When all of the
+// attempts fail, the last error is returned.
+func getURLWithRetries(ctx context.Context, pr probe, url string, retries int, backoff time.Duration) (body string, err error) {
+	delay := backoff
+
+	for attempt := 0; ; attempt++ {
+		body, err = getURL(ctx, pr, url)
+
+		if err == nil || attempt >= retries || !isRetryable(err) {
+			return body, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return body, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isRetryable returns true for server errors, and network errors which can pass, such as timeouts, and
+// connections which were refused or reset while the instance starts up. Other errors, e.g. a certificate which
+// can't be verified, fail straight away, since retrying them won't help.
+func isRetryable(err error) bool {
+	if se, ok := err.(statusError); ok {
+		return se.StatusCode >= http.StatusInternalServerError
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
 func convert(s []string) []*string {
 	rv := make([]*string, len(s))
 
@@ -0,0 +1,1286 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/blang/semver"
+)
+
+func newFlakyServer(failures int, status int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprint(w, "1.2.3")
+	}))
+	return server, &requests
+}
+
+func TestGetURLWithRetriesRecoversFromServerErrors(t *testing.T) {
+	server, requests := newFlakyServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	body, err := getURLWithRetries(context.Background(), probe{client: &http.Client{}}, server.URL, 2, time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Expected the probe to eventually succeed, but got %v", err)
+	}
+
+	if body != "1.2.3" {
+		t.Errorf("Expected the version 1.2.3, but got %q", body)
+	}
+
+	if *requests != 3 {
+		t.Errorf("Expected 3 requests, but got %d", *requests)
+	}
+}
+
+func TestGetURLWithRetriesReturnsTheLastError(t *testing.T) {
+	server, requests := newFlakyServer(5, http.StatusBadGateway)
+	defer server.Close()
+
+	_, err := getURLWithRetries(context.Background(), probe{client: &http.Client{}}, server.URL, 2, time.Millisecond)
+
+	if se, ok := err.(statusError); !ok || se.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a 502 status error, but got %v", err)
+	}
+
+	if *requests != 3 {
+		t.Errorf("Expected 3 requests, but got %d", *requests)
+	}
+}
+
+func TestGetURLWithRetriesDoesNotRetryClientErrors(t *testing.T) {
+	server, requests := newFlakyServer(1, http.StatusNotFound)
+	defer server.Close()
+
+	getURLWithRetries(context.Background(), probe{client: &http.Client{}}, server.URL, 2, time.Millisecond)
+
+	if *requests != 1 {
+		t.Errorf("Expected a single request, but got %d", *requests)
+	}
+}
+
+func TestGetURLFailsOnUnsuccessfulStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{
+			name:   "service unavailable",
+			status: http.StatusServiceUnavailable,
+			body:   "<html><body>Service Unavailable</body></html>",
+		},
+		{
+			name:   "not found",
+			status: http.StatusNotFound,
+			body:   "<html><body>" + strings.Repeat("Not Found ", 100) + "</body></html>",
+		},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		_, err := getURL(context.Background(), probe{client: &http.Client{}}, server.URL)
+		server.Close()
+
+		se, ok := err.(statusError)
+
+		if !ok {
+			t.Errorf("For test \"%s\", expected a status error, but got %v", test.name, err)
+			continue
+		}
+
+		if se.StatusCode != test.status {
+			t.Errorf("For test \"%s\", expected status %d, but got %d", test.name, test.status, se.StatusCode)
+		}
+
+		if !strings.HasPrefix(test.body, se.Body) || se.Body == "" || len(se.Body) > maxStatusErrorBodyBytes {
+			t.Errorf("For test \"%s\", expected a snippet of the body of at most %d bytes, but got %q", test.name, maxStatusErrorBodyBytes, se.Body)
+		}
+	}
+}
+
+func TestGetURLWithRetriesRetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	start := time.Now()
+	_, err := getURLWithRetries(context.Background(), probe{client: &http.Client{}}, url, 2, 10*time.Millisecond)
+
+	if err == nil {
+		t.Fatal("Expected connection refused, but got no error")
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected the probe to back off for at least 30ms, but it took %v", elapsed)
+	}
+}
+
+func TestGetURLWithRetriesDoesNotRetryCertificateErrors(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1.2.3")
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	_, err := getURLWithRetries(context.Background(), probe{client: newProbeClient(nil, nil)}, server.URL, 2, 10*time.Second)
+
+	if err == nil {
+		t.Fatal("Expected the self-signed certificate to be rejected, but got no error")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the certificate error to fail straight away, but it took %v", elapsed)
+	}
+}
+
+func TestGetURLWithRetriesRetriesTimeouts(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request takes longer than the client's timeout.
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		fmt.Fprint(w, "1.2.3")
+	}))
+	defer server.Close()
+
+	body, err := getURLWithRetries(context.Background(), probe{client: &http.Client{Timeout: 10 * time.Millisecond}}, server.URL, 2, time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Expected the timed out probe to be retried, but got %v", err)
+	}
+
+	if body != "1.2.3" {
+		t.Errorf("Expected the version 1.2.3, but got %q", body)
+	}
+}
+
+func TestProbeClientUsesTheDialer(t *testing.T) {
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	var dialed []string
+	client := newProbeClient(func(network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		return net.Dial(network, server.Listener.Addr().String())
+	}, nil)
+
+	body, err := getURL(context.Background(), probe{client: client}, "http://localhost:8080/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body != "1.2.3" {
+		t.Errorf("Expected the version 1.2.3 to be read through the dialer, but got %q", body)
+	}
+
+	if len(dialed) != 1 || dialed[0] != "localhost:8080" {
+		t.Errorf("Expected localhost:8080 to be dialed, but got %v", dialed)
+	}
+}
+
+func TestProbeClientsAreReusedForEachTLSConfig(t *testing.T) {
+	var clients probeClients
+	insecure, err := NewTLSConfig(true, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if clients.get(nil) != clients.get(nil) {
+		t.Error("Expected the client of the default TLS configuration to be reused")
+	}
+
+	if clients.get(insecure) != clients.get(insecure) {
+		t.Error("Expected the client of the TLS configuration to be reused")
+	}
+
+	if clients.get(nil) == clients.get(insecure) {
+		t.Error("Expected each TLS configuration to have its own client")
+	}
+
+	if timeout := clients.get(nil).Timeout; timeout != probeTimeout {
+		t.Errorf("Expected the client to time out after %v, but got %v", probeTimeout, timeout)
+	}
+}
+
+func TestGetURLReadsTheVersionHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App-Version", "v1.4.2")
+	}))
+	defer server.Close()
+
+	version, err := getURL(context.Background(), probe{client: &http.Client{}, versionHeader: "X-App-Version"}, server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != "v1.4.2" {
+		t.Errorf("Expected the version v1.4.2 from the header, but got %q", version)
+	}
+
+	if _, err := getURL(context.Background(), probe{client: &http.Client{}, versionHeader: "X-Missing-Version"}, server.URL); err == nil {
+		t.Error("Expected an error when the header is missing.")
+	}
+}
+
+func TestProbingSelfSignedCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1.2.3")
+	}))
+	defer server.Close()
+
+	if _, err := getURL(context.Background(), probe{client: newProbeClient(nil, nil)}, server.URL); err == nil {
+		t.Error("Expected the self-signed certificate to be rejected by default.")
+	}
+
+	insecure, err := NewTLSConfig(true, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getURL(context.Background(), probe{client: newProbeClient(nil, insecure)}, server.URL); err != nil {
+		t.Errorf("Expected the self-signed certificate to be accepted when skipping verification, but got %v", err)
+	}
+
+	bundle, err := ioutil.TempFile("", "ca-bundle")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bundle.Name())
+
+	pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	bundle.Close()
+
+	trusted, err := NewTLSConfig(false, bundle.Name())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getURL(context.Background(), probe{client: newProbeClient(nil, trusted)}, server.URL); err != nil {
+		t.Errorf("Expected the certificate to be trusted through the CA bundle, but got %v", err)
+	}
+}
+
+func TestGetURLSendsTheProbeHeaders(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		fmt.Fprint(w, "1.2.3")
+	}))
+	defer server.Close()
+
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer xyz")
+
+	if _, err := getURL(context.Background(), probe{client: &http.Client{}, headers: headers}, server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if authorization != "Bearer xyz" {
+		t.Errorf("Expected the Authorization header to reach the server, but got %q", authorization)
+	}
+}
+
+func TestGetURLLimitsTheResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := strings.Repeat("x", 1024)
+		for i := 0; i < 1024; i++ {
+			if _, err := fmt.Fprint(w, chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	if _, err := getURL(context.Background(), probe{client: &http.Client{}, maxResponseBytes: 64 * 1024}, server.URL); err == nil {
+		t.Error("Expected a response larger than the limit to fail")
+	}
+
+	body, err := getURL(context.Background(), probe{client: &http.Client{}, maxResponseBytes: 1024 * 1024}, server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body) != 1024*1024 {
+		t.Errorf("Expected a response at the limit to be read in full, but got %d bytes", len(body))
+	}
+}
+
+func TestGetDetailsConcurrentlyIsBounded(t *testing.T) {
+	var instances []*autoscaling.Instance
+	for i := 0; i < 20; i++ {
+		instances = append(instances, &autoscaling.Instance{InstanceId: aws.String(fmt.Sprintf("i-%d", i))})
+	}
+
+	var m sync.Mutex
+	running, maximum := 0, 0
+
+	details := getDetailsConcurrently(instances, "asg_web", 3, func(instanceID string) (*InstanceDetail, error) {
+		m.Lock()
+		running++
+		if running > maximum {
+			maximum = running
+		}
+		m.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		m.Lock()
+		running--
+		m.Unlock()
+
+		if instanceID == "i-7" {
+			return nil, errors.New("connection refused")
+		}
+		return &InstanceDetail{ID: instanceID}, nil
+	})
+
+	if maximum != 3 {
+		t.Errorf("Expected 3 instances to be probed at the same time, but got %d", maximum)
+	}
+
+	if len(details) != 19 {
+		t.Errorf("Expected the details of 19 instances, skipping the failure, but got %d", len(details))
+	}
+}
+
+func TestGetURLWithRetriesStopsWhenCancelled(t *testing.T) {
+	server, requests := newFlakyServer(5, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := getURLWithRetries(ctx, probe{client: &http.Client{}}, server.URL, 5, time.Hour); err == nil {
+		t.Error("Expected an error when the context is cancelled.")
+	}
+
+	if *requests != 0 {
+		t.Errorf("Expected no requests once the context is cancelled, but got %d", *requests)
+	}
+}
+
+type mockEC2 struct {
+	ec2iface.EC2API
+	terminateCalls [][]string
+	terminateErr   map[int]error
+	// launchTime of the instances, or the current time if it's zero.
+	launchTime time.Time
+	// tags of the instances.
+	tags []*ec2.Tag
+}
+
+func (m *mockEC2) TerminateInstancesWithContext(ctx aws.Context, input *ec2.TerminateInstancesInput, opts ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	m.terminateCalls = append(m.terminateCalls, aws.StringValueSlice(input.InstanceIds))
+	return &ec2.TerminateInstancesOutput{}, m.terminateErr[len(m.terminateCalls)]
+}
+
+func instanceIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("i-%04d", i)
+	}
+	return ids
+}
+
+func TestTerminateInstancesIsBatched(t *testing.T) {
+	tests := []struct {
+		instances     int
+		expectedCalls []int
+	}{
+		{instances: 3, expectedCalls: []int{3}},
+		{instances: 1000, expectedCalls: []int{1000}},
+		{instances: 2500, expectedCalls: []int{1000, 1000, 500}},
+	}
+
+	for _, test := range tests {
+		m := &mockEC2{}
+		p := &AWSProvider{ec2: m}
+
+		if err := p.TerminateInstances(context.Background(), instanceIDs(test.instances)); err != nil {
+			t.Fatal(err)
+		}
+
+		actual := make([]int, len(m.terminateCalls))
+		for i, c := range m.terminateCalls {
+			actual[i] = len(c)
+		}
+
+		if !reflect.DeepEqual(actual, test.expectedCalls) {
+			t.Errorf("Terminating %d instances, expected calls of %v, but got %v", test.instances, test.expectedCalls, actual)
+		}
+	}
+}
+
+func TestTerminateInstancesReportsTheFailedBatch(t *testing.T) {
+	m := &mockEC2{terminateErr: map[int]error{2: errors.New("UnauthorizedOperation")}}
+	p := &AWSProvider{ec2: m}
+
+	err := p.TerminateInstances(context.Background(), instanceIDs(2500))
+
+	if len(m.terminateCalls) != 3 {
+		t.Errorf("Expected every batch to be attempted, but got %d calls", len(m.terminateCalls))
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "batch 2 of 3 (i-1000 to i-1999)") {
+		t.Errorf("Expected the error to describe the failed batch, but got %v", err)
+	}
+}
+
+type mockAutoScaling struct {
+	autoscalingiface.AutoScalingAPI
+	pages []*autoscaling.DescribeAutoScalingGroupsOutput
+}
+
+func (m *mockAutoScaling) DescribeAutoScalingGroupsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool, opts ...request.Option) error {
+	for i, page := range m.pages {
+		if !fn(page, i == len(m.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockEC2) DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	launchTime := m.launchTime
+	if launchTime.IsZero() {
+		launchTime = time.Now()
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{
+				Instances: []*ec2.Instance{
+					{
+						InstanceId:       input.InstanceIds[0],
+						PrivateIpAddress: aws.String("127.0.0.1"),
+						LaunchTime:       aws.Time(launchTime),
+						Tags:             m.tags,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func newMockGroup(name string, instanceIDs ...string) *autoscaling.Group {
+	g := &autoscaling.Group{AutoScalingGroupName: aws.String(name)}
+	for _, id := range instanceIDs {
+		g.Instances = append(g.Instances, &autoscaling.Instance{
+			InstanceId:     aws.String(id),
+			HealthStatus:   aws.String("Healthy"),
+			LifecycleState: aws.String("InService"),
+		})
+	}
+	return g
+}
+
+// newMockAWSProvider creates a provider whose instances all serve their version from the server.
+func newMockAWSProvider(server *httptest.Server, groups ...[]*autoscaling.Group) (p *AWSProvider, port int) {
+	m := &mockAutoScaling{}
+	for _, page := range groups {
+		m.pages = append(m.pages, &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: page})
+	}
+
+	port = server.Listener.Addr().(*net.TCPAddr).Port
+
+	return &AWSProvider{
+		autoScaling:    m,
+		ec2:            &mockEC2{},
+		VersionSource:  VersionSourceHTTP,
+		ProbeTransport: ProbeTransportDirect,
+		Concurrency:    1,
+	}, port
+}
+
+func TestDescribeAutoScalingGroupsReadsEveryPage(t *testing.T) {
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server,
+		[]*autoscaling.Group{newMockGroup("asg_api", "i-1"), newMockGroup("asg_web", "i-2")},
+		[]*autoscaling.Group{newMockGroup("dev_asg_web", "i-3", "i-4")})
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.Name
+	}
+
+	expected := []string{"asg_api", "asg_web", "dev_asg_web"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the groups from both pages %v, but got %v", expected, names)
+	}
+
+	if len(groups[2].InstanceDetails) != 2 {
+		t.Errorf("Expected the details of both instances in dev_asg_web, but got %+v", groups[2].InstanceDetails)
+	}
+}
+
+func TestDescribeAutoScalingGroupsReadsTheInstanceTypeAndLaunchTemplate(t *testing.T) {
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	g := newMockGroup("asg_api", "i-1", "i-2", "i-3")
+	g.Instances[0].InstanceType = aws.String("m5.large")
+	g.Instances[0].LaunchTemplate = &autoscaling.LaunchTemplateSpecification{LaunchTemplateName: aws.String("api"), Version: aws.String("3")}
+	g.Instances[1].InstanceType = aws.String("c5.xlarge")
+	g.Instances[1].LaunchTemplate = &autoscaling.LaunchTemplateSpecification{LaunchTemplateId: aws.String("lt-0123"), Version: aws.String("$Latest")}
+	g.Instances[2].LaunchConfigurationName = aws.String("api-v2")
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{g})
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][4]string{
+		{"m5.large", "api", "3", ""},
+		{"c5.xlarge", "lt-0123", "$Latest", ""},
+		{"", "", "", "api-v2"},
+	}
+	for i, instance := range groups[0].Instances {
+		actual := [4]string{instance.InstanceType, instance.LaunchTemplateName, instance.LaunchTemplateVersion, instance.LaunchConfigurationName}
+		if actual != expected[i] {
+			t.Errorf("Expected the type and launch template of %s to be %v, but got %v", instance.ID, expected[i], actual)
+		}
+	}
+}
+
+func TestDescribeAutoScalingGroupsSkipsGroupsWhichFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/i-2/version" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "1.2.3")
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server,
+		[]*autoscaling.Group{newMockGroup("asg_api", "i-1"), newMockGroup("asg_web", "i-2"), newMockGroup("asg_worker", "i-3")})
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/{{.InstanceID}}/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.Name
+	}
+
+	expected := []string{"asg_api", "asg_worker"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected only the groups whose instances could be probed %v, but got %q", expected, names)
+	}
+}
+
+func TestDescribeAutoScalingGroupsFailsWhenEveryGroupFailed(t *testing.T) {
+	server, _ := newFlakyServer(1000, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{newMockGroup("asg_api", "i-1"), newMockGroup("asg_web", "i-2")})
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err == nil {
+		t.Errorf("Expected an error, but got %+v", groups)
+	}
+}
+
+func TestDescribeAutoScalingGroupsOnlyReturnsTheRequestedGroups(t *testing.T) {
+	defer func(l *slog.Logger) { slog.SetDefault(l) }(slog.Default())
+	buf := new(bytes.Buffer)
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, nil)))
+
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{newMockGroup("asg_api", "i-1"), newMockGroup("asg_web", "i-2")})
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), []string{"asg_api", "asg_missing"}, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 || groups[0].Name != "asg_api" {
+		t.Errorf("Expected only asg_api, but got %+v", groups)
+	}
+
+	if !strings.Contains(buf.String(), "group=asg_missing") {
+		t.Errorf("Expected a warning about the group which doesn't exist, but got %s", buf.String())
+	}
+}
+
+func TestDescribeAutoScalingGroupsFiltersByPattern(t *testing.T) {
+	server, requests := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server,
+		[]*autoscaling.Group{newMockGroup("web-prod-v1", "i-1"), newMockGroup("web-dev-v1", "i-2")},
+		[]*autoscaling.Group{newMockGroup("web-prod-v2", "i-3"), newMockGroup("old-web-prod-v1", "i-4")})
+	p.GroupNamePattern = regexp.MustCompile("^(?:web-prod-.*)$")
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.Name
+	}
+
+	expected := []string{"web-prod-v1", "web-prod-v2"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the matching groups %v, but got %v", expected, names)
+	}
+
+	if *requests != 2 {
+		t.Errorf("Expected only the instances of matching groups to be probed, but got %d requests", *requests)
+	}
+}
+
+type mockGroupTermination struct {
+	autoscalingiface.AutoScalingAPI
+	inputs []*autoscaling.TerminateInstanceInAutoScalingGroupInput
+	fail   map[string]bool
+}
+
+func (m *mockGroupTermination) TerminateInstanceInAutoScalingGroupWithContext(ctx aws.Context, input *autoscaling.TerminateInstanceInAutoScalingGroupInput, opts ...request.Option) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	m.inputs = append(m.inputs, input)
+	if m.fail[aws.StringValue(input.InstanceId)] {
+		return nil, errors.New("instance not found")
+	}
+	return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{}, nil
+}
+
+func TestTerminateGroupInstances(t *testing.T) {
+	for _, decrement := range []bool{true, false} {
+		m := &mockGroupTermination{fail: map[string]bool{"i-2": true}}
+		p := &AWSProvider{autoScaling: m}
+
+		err := p.TerminateGroupInstances(context.Background(), []string{"i-1", "i-2", "i-3"}, decrement)
+
+		if len(m.inputs) != 3 {
+			t.Fatalf("Expected every instance to be attempted, but got %d calls", len(m.inputs))
+		}
+
+		for _, input := range m.inputs {
+			if aws.BoolValue(input.ShouldDecrementDesiredCapacity) != decrement {
+				t.Errorf("Expected ShouldDecrementDesiredCapacity to be %v for %s", decrement, aws.StringValue(input.InstanceId))
+			}
+		}
+
+		if err == nil || !strings.Contains(err.Error(), "i-2 failed") || strings.Contains(err.Error(), "i-1") {
+			t.Errorf("Expected the error to describe the failed instance, but got %v", err)
+		}
+	}
+}
+
+func TestGetDetailNormalizesVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v1.2.3-4-gdeadbee"))
+	}))
+	defer server.Close()
+
+	for _, normalize := range []bool{false, true} {
+		p, port := newMockAWSProvider(server)
+		p.NormalizeVersions = normalize
+
+		detail, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "1.2.3-4-gdeadbee"
+		if normalize {
+			expected = "1.2.3"
+		}
+
+		if actual := detail.VersionNumber.String(); actual != expected {
+			t.Errorf("With NormalizeVersions %v, expected %s, but got %s", normalize, expected, actual)
+		}
+	}
+}
+
+func TestGetDetailRendersTemplatedPaths(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write([]byte("1.2.3"))
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+
+	if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/instances/{{.InstanceID}}/version"); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "/instances/i-1/version"; requested != expected {
+		t.Errorf("Expected %s to be requested, but got %s", expected, requested)
+	}
+}
+
+func TestProbeAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		instance    *ec2.Instance
+		usePublicIP bool
+		family      IPFamily
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "the private IP is used by default",
+			instance: &ec2.Instance{PrivateIpAddress: aws.String("10.0.0.1"), PublicIpAddress: aws.String("203.0.113.1")},
+			expected: "10.0.0.1",
+		},
+		{
+			name:        "the public IP is used when set",
+			instance:    &ec2.Instance{PrivateIpAddress: aws.String("10.0.0.1"), PublicIpAddress: aws.String("203.0.113.1")},
+			usePublicIP: true,
+			expected:    "203.0.113.1",
+		},
+		{
+			name:        "instances without a public IP can't be probed",
+			instance:    &ec2.Instance{InstanceId: aws.String("i-1"), PrivateIpAddress: aws.String("10.0.0.1")},
+			usePublicIP: true,
+			expectError: true,
+		},
+		{
+			name:     "IPv6-only instances are probed on their IPv6 address",
+			instance: &ec2.Instance{Ipv6Address: aws.String("2001:db8::1")},
+			expected: "2001:db8::1",
+		},
+		{
+			name: "the IPv6 address of the network interface is used when the instance doesn't have one",
+			instance: &ec2.Instance{NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+				{Ipv6Addresses: []*ec2.InstanceIpv6Address{{Ipv6Address: aws.String("2001:db8::2")}}},
+			}},
+			family:   IPFamilyAuto,
+			expected: "2001:db8::2",
+		},
+		{
+			name:     "the IPv6 address is used when the family is IPv6",
+			instance: &ec2.Instance{PrivateIpAddress: aws.String("10.0.0.1"), Ipv6Address: aws.String("2001:db8::1")},
+			family:   IPFamilyIPv6,
+			expected: "2001:db8::1",
+		},
+		{
+			name:        "instances without an IPv6 address can't be probed when the family is IPv6",
+			instance:    &ec2.Instance{InstanceId: aws.String("i-1"), PrivateIpAddress: aws.String("10.0.0.1")},
+			family:      IPFamilyIPv6,
+			expectError: true,
+		},
+		{
+			name:        "IPv6-only instances can't be probed when the family is IPv4",
+			instance:    &ec2.Instance{InstanceId: aws.String("i-1"), Ipv6Address: aws.String("2001:db8::1")},
+			family:      IPFamilyIPv4,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := probeAddress(test.instance, test.usePublicIP, test.family)
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("For test \"%s\", expected an error, but got %s", test.name, actual)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("For test \"%s\", unexpected error %v", test.name, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("For test \"%s\", expected %s, but got %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestProbeURL(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "10.0.0.1", expected: "http://10.0.0.1:8080/version"},
+		{host: "2001:db8::1", expected: "http://[2001:db8::1]:8080/version"},
+	}
+
+	for _, test := range tests {
+		if actual := probeURL("http", test.host, 8080, "/version"); actual != test.expected {
+			t.Errorf("Expected the URL of %s to be %s, but got %s", test.host, test.expected, actual)
+		}
+	}
+}
+
+func TestGetDetailProbesTheIPv6Address(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 isn't available, %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.2.3"))
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.ec2 = &mockEC2IPv6{}
+	p.IPFamily = IPFamilyAuto
+
+	detail, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version")
+
+	if err != nil {
+		t.Fatalf("Expected the IPv6 address to be probed, but got %v", err)
+	}
+
+	if detail.VersionNumber.String() != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, but got %s", detail.VersionNumber)
+	}
+}
+
+// mockEC2IPv6 describes IPv6-only instances.
+type mockEC2IPv6 struct {
+	ec2iface.EC2API
+}
+
+func (m *mockEC2IPv6) DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{Instances: []*ec2.Instance{{InstanceId: input.InstanceIds[0], Ipv6Address: aws.String("::1"), LaunchTime: aws.Time(time.Now())}}},
+		},
+	}, nil
+}
+
+func TestGetDetailFailsWithoutAPublicIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the instance not to be probed")
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.UsePublicIP = true
+
+	if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version"); err == nil || !strings.Contains(err.Error(), "public IP") {
+		t.Errorf("Expected an error describing the missing public IP, but got %v", err)
+	}
+}
+
+// mockEC2Addresses describes instances with both a private and a public IP address, where only the public
+// IP address can be reached.
+type mockEC2Addresses struct {
+	ec2iface.EC2API
+}
+
+func (m *mockEC2Addresses) DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{
+				Instances: []*ec2.Instance{
+					{
+						InstanceId:       input.InstanceIds[0],
+						PrivateIpAddress: aws.String("invalid.private.address"),
+						PublicIpAddress:  aws.String("127.0.0.1"),
+						LaunchTime:       aws.Time(time.Now()),
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func TestGetDetailProbesThePublicIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.2.3"))
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.ec2 = &mockEC2Addresses{}
+	p.UsePublicIP = true
+
+	detail, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version")
+
+	if err != nil {
+		t.Fatalf("Expected the public IP to be probed, but got %v", err)
+	}
+
+	if detail.VersionNumber.String() != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, but got %s", detail.VersionNumber)
+	}
+}
+
+func TestGetDetailProbesThePortInTheTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.2.3"))
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.ec2 = &mockEC2{tags: []*ec2.Tag{
+		{Key: aws.String("Name"), Value: aws.String("api")},
+		{Key: aws.String("terminator:port"), Value: aws.String(strconv.Itoa(port))},
+	}}
+	p.PortTag = "terminator:port"
+
+	// Nothing listens on port 1, so the version can only be read from the port in the tag.
+	detail, err := p.GetDetail(context.Background(), "i-1", "http", 1, "/version")
+
+	if err != nil {
+		t.Fatalf("Expected the port in the tag to be probed, but got %v", err)
+	}
+
+	if detail.VersionNumber.String() != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, but got %s", detail.VersionNumber)
+	}
+}
+
+func TestGetDetailFallsBackToThePortWithoutATag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.2.3"))
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.PortTag = "terminator:port"
+
+	if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version"); err != nil {
+		t.Errorf("Expected the port to be probed, but got %v", err)
+	}
+
+	p.ec2 = &mockEC2{tags: []*ec2.Tag{{Key: aws.String("terminator:port"), Value: aws.String("management")}}}
+
+	if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version"); err == nil || !strings.Contains(err.Error(), "invalid terminator:port tag") {
+		t.Errorf("Expected an error describing the invalid tag, but got %v", err)
+	}
+}
+
+func TestGetDetailUsesTheVersionCache(t *testing.T) {
+	server, requests := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	m := &mockEC2{launchTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p.ec2 = m
+	p.VersionCacheTTL = time.Hour
+
+	for i := 0; i < 3; i++ {
+		detail, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if detail.VersionNumber.String() != "1.2.3" {
+			t.Errorf("Expected the version 1.2.3, but got %v", detail.VersionNumber)
+		}
+	}
+
+	if *requests != 1 {
+		t.Errorf("Expected the instance to be probed once, but it was probed %d times", *requests)
+	}
+
+	m.launchTime = m.launchTime.Add(time.Minute)
+	if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *requests != 2 {
+		t.Errorf("Expected a new launch time to probe the instance again, but it was probed %d times", *requests)
+	}
+}
+
+func TestGetDetailDoesNotCacheWithoutATTL(t *testing.T) {
+	server, requests := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.ec2 = &mockEC2{launchTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if *requests != 2 {
+		t.Errorf("Expected the instance to be probed on each call, but it was probed %d times", *requests)
+	}
+}
+
+func TestVersionCacheEntriesExpire(t *testing.T) {
+	var c versionCache
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := newVersionCacheKey("i-1", now, "http://127.0.0.1:80/version")
+
+	c.put(key, InstanceDetail{ID: "i-1"}, now, time.Minute)
+
+	if _, ok := c.get(key, now.Add(59*time.Second)); !ok {
+		t.Errorf("Expected the entry to be cached before the TTL passed")
+	}
+	if _, ok := c.get(key, now.Add(time.Minute)); ok {
+		t.Errorf("Expected the entry to expire after the TTL")
+	}
+}
+
+func TestVersionCacheRemovesExpiredEntries(t *testing.T) {
+	var c versionCache
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The instances are terminated, so their entries are never looked up again.
+	c.put(newVersionCacheKey("i-1", now, "http://127.0.0.1:80/version"), InstanceDetail{ID: "i-1"}, now, time.Minute)
+	c.put(newVersionCacheKey("i-2", now, "http://127.0.0.1:80/version"), InstanceDetail{ID: "i-2"}, now, time.Minute)
+
+	later := now.Add(time.Hour)
+	c.put(newVersionCacheKey("i-3", later, "http://127.0.0.1:80/version"), InstanceDetail{ID: "i-3"}, later, time.Minute)
+
+	if len(c.entries) != 1 {
+		t.Errorf("Expected the expired entries to be removed, but got %v", c.entries)
+	}
+}
+
+func TestGetDetailReadsVersionsWithTheScheme(t *testing.T) {
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+
+	p.VersionScheme = VersionSchemeLexicographic
+	detail, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if detail.Version() != "1.2.3" || detail.Scheme != VersionSchemeLexicographic {
+		t.Errorf("Expected the lexicographic version 1.2.3, but got %q with the scheme %q", detail.Version(), detail.Scheme)
+	}
+
+	p.VersionScheme = VersionSchemeInteger
+	if _, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version"); err == nil {
+		t.Errorf("Expected 1.2.3 to be rejected by the integer scheme")
+	}
+}
+
+func TestHealthIsCheckedBeforeTheVersion(t *testing.T) {
+	var m sync.Mutex
+	requested := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		requested[r.URL.Path] = true
+		m.Unlock()
+		switch r.URL.Path {
+		case "/i-2/version", "/i-3/healthz":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "1.2.3")
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{newMockGroup("asg_api", "i-1", "i-2", "i-3")})
+	p.HealthURL = "/{{.InstanceID}}/healthz"
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/{{.InstanceID}}/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := groups[0]
+	failed := map[string]bool{}
+	for _, instance := range g.Instances {
+		failed[instance.ID] = instance.FailedHealthCheck
+	}
+
+	if failed["i-1"] || failed["i-2"] || !failed["i-3"] {
+		t.Errorf("Expected only i-3 to fail the health check, but got %v", failed)
+	}
+
+	if g.HealthyCount() != 2 {
+		t.Errorf("Expected the instance which failed the health check to be unhealthy, but %d instances are healthy", g.HealthyCount())
+	}
+
+	ids := []string{}
+	for _, d := range g.InstanceDetails {
+		ids = append(ids, d.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"i-1"}) {
+		t.Errorf("Expected only i-1 to have a version, because i-2's version failed and i-3 is unhealthy, but got %v", ids)
+	}
+
+	if requested["/i-3/version"] {
+		t.Errorf("Expected the version of the instance which failed the health check not to be probed")
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	server, _ := newFlakyServer(1, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+
+	if err := p.GetHealth(context.Background(), "i-1", "http", port, "/healthz"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the health check to fail with the status, but got %v", err)
+	}
+
+	if err := p.GetHealth(context.Background(), "i-1", "http", port, "/healthz"); err != nil {
+		t.Errorf("Expected the health check to pass, but got %v", err)
+	}
+}
+
+func TestGetDetailTriesEachPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			fmt.Fprint(w, "1.2.3")
+		case "/unparseable":
+			fmt.Fprint(w, "not a version")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+
+	tests := []struct {
+		path          string
+		expectedError string
+	}{
+		{path: "/version,/status"},
+		{path: "/unparseable, /status"},
+		{path: "/version,/missing", expectedError: "all 2 version paths failed"},
+		{path: "/version", expectedError: "returned HTTP status 404"},
+	}
+
+	for _, test := range tests {
+		detail, err := p.GetDetail(context.Background(), "i-1", "http", port, test.path)
+
+		if test.expectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Errorf("%s: expected an error containing %q, but got %v", test.path, test.expectedError, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.path, err)
+			continue
+		}
+
+		if detail.Version() != "1.2.3" {
+			t.Errorf("%s: expected the version 1.2.3 from /status, but got %s", test.path, detail.Version())
+		}
+	}
+}
+
+func TestVersionOverridesDriveTheSelection(t *testing.T) {
+	server, requests := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{newMockGroup("asg_api", "i-1", "i-2", "i-3", "i-4")})
+	p.VersionOverrides = map[string]string{"i-1": "1.2.0", "i-2": "1.1.0", "i-3": "1.2.0", "i-4": "1.2.0"}
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions, _, err := groups[0].GetTargetInstances(semver.MustParse("1.2.0"), 3, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var targets []string
+	for _, d := range decisions {
+		targets = append(targets, d.ID)
+	}
+
+	if expected := []string{"i-2"}; !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected the instance overridden to an old version %v to be targeted, but got %v", expected, targets)
+	}
+
+	if *requests != 0 {
+		t.Errorf("Expected no instances to be probed, but got %d requests", *requests)
+	}
+}
+
+func TestInstancesWithoutAVersionOverrideHaveNoDetails(t *testing.T) {
+	server, requests := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.VersionOverrides = map[string]string{"i-1": "1.2.0"}
+
+	details, err := p.GetInstanceDetails(context.Background(), newMockGroup("asg_api", "i-1", "i-2").Instances, "asg_api", "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(details) != 1 || details[0].ID != "i-1" || details[0].Version() != "1.2.0" {
+		t.Errorf("Expected only the details of i-1, but got %+v", details)
+	}
+
+	if *requests != 0 {
+		t.Errorf("Expected no instances to be probed, but got %d requests", *requests)
+	}
+}
@@ -0,0 +1,28 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxCanonicalVersionBytes is the largest response which is read as a canonical version.
+const maxCanonicalVersionBytes = 1024
+
+// GetCanonicalVersion fetches the canonical version from the URL, e.g. an object published by a deployment
+// pipeline. The body of the response is the version, surrounding whitespace is ignored.
+func GetCanonicalVersion(ctx context.Context, client *http.Client, url string) (string, error) {
+	body, err := getURL(ctx, probe{client: client, maxResponseBytes: maxCanonicalVersionBytes}, url)
+
+	if err != nil {
+		return "", err
+	}
+
+	version := strings.TrimSpace(body)
+	if version == "" {
+		return "", fmt.Errorf("%s returned an empty version", url)
+	}
+
+	return version, nil
+}
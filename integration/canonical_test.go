@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetCanonicalVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		expected        string
+		expectedToError bool
+	}{
+		{
+			name:     "The version is read from the body.",
+			status:   http.StatusOK,
+			body:     "1.2.0\n",
+			expected: "1.2.0",
+		},
+		{
+			name:            "An empty body is an error.",
+			status:          http.StatusOK,
+			body:            " \n",
+			expectedToError: true,
+		},
+		{
+			name:            "An unexpected status is an error.",
+			status:          http.StatusForbidden,
+			body:            "AccessDenied",
+			expectedToError: true,
+		},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		actual, err := GetCanonicalVersion(context.Background(), server.Client(), server.URL)
+		server.Close()
+
+		if test.expectedToError && err == nil {
+			t.Errorf("%s: expected an error, but got %q", test.name, actual)
+		}
+		if !test.expectedToError && (err != nil || actual != test.expected) {
+			t.Errorf("%s: expected %q, but got %q, %v", test.name, test.expected, actual, err)
+		}
+	}
+}
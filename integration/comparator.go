@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionScheme determines how the versions reported by instances are compared.
+type VersionScheme string
+
+const (
+	// VersionSchemeSemver compares semantic versions, e.g. 1.2.3.
+	VersionSchemeSemver VersionScheme = "semver"
+	// VersionSchemeInteger compares build numbers, e.g. 4821.
+	VersionSchemeInteger VersionScheme = "integer"
+	// VersionSchemeLexicographic compares the versions as strings, e.g. dates like 2024.03.15.
+	VersionSchemeLexicographic VersionScheme = "lexicographic"
+)
+
+// ParseVersionScheme validates the name of a version scheme.
+func ParseVersionScheme(s string) (VersionScheme, error) {
+	switch VersionScheme(s) {
+	case VersionSchemeSemver, VersionSchemeInteger, VersionSchemeLexicographic:
+		return VersionScheme(s), nil
+	}
+	return "", fmt.Errorf("unknown version scheme %q, expected %q, %q or %q", s, VersionSchemeSemver, VersionSchemeInteger, VersionSchemeLexicographic)
+}
+
+// IsSemver returns true for the semver scheme, which is also the zero value.
+func (s VersionScheme) IsSemver() bool {
+	return s == "" || s == VersionSchemeSemver
+}
+
+// Comparator returns the comparator for the scheme. The zero value is the semver scheme.
+func (s VersionScheme) Comparator() VersionComparator {
+	switch s {
+	case VersionSchemeInteger:
+		return IntegerComparator{}
+	case VersionSchemeLexicographic:
+		return LexicographicComparator{}
+	}
+	return SemverComparator{}
+}
+
+// VersionComparator compares the versions reported by instances.
+type VersionComparator interface {
+	// Validate returns an error if the version can't be compared.
+	Validate(version string) error
+	// Compare returns a negative number when a is lower than b, zero when they're the same version, and a
+	// positive number when a is higher.
+	Compare(a, b string) (int, error)
+}
+
+// SemverComparator compares semantic versions, coercing versions such as "1.2" in the same way as ParseVersion.
+type SemverComparator struct{}
+
+// Validate returns an error if the version isn't a semantic version.
+func (SemverComparator) Validate(version string) error {
+	_, _, err := ParseVersion(version)
+	return err
+}
+
+// Compare compares the semantic versions.
+func (SemverComparator) Compare(a, b string) (int, error) {
+	va, _, err := ParseVersion(a)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q, %v", a, err)
+	}
+	vb, _, err := ParseVersion(b)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q, %v", b, err)
+	}
+	return va.Compare(vb), nil
+}
+
+// IntegerComparator compares versions which are whole numbers, e.g. build numbers, so that 10 is higher than 9.
+type IntegerComparator struct{}
+
+// Validate returns an error if the version isn't a whole number.
+func (c IntegerComparator) Validate(version string) error {
+	_, err := c.parse(version)
+	return err
+}
+
+// Compare compares the numbers.
+func (c IntegerComparator) Compare(a, b string) (int, error) {
+	na, err := c.parse(a)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q, %v", a, err)
+	}
+	nb, err := c.parse(b)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q, %v", b, err)
+	}
+	switch {
+	case na < nb:
+		return -1, nil
+	case na > nb:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (IntegerComparator) parse(version string) (int64, error) {
+	n, err := strconv.ParseInt(TrimVersion(version), 10, 64)
+	if err != nil {
+		return 0, errors.New("expected a whole number")
+	}
+	return n, nil
+}
+
+// LexicographicComparator compares versions as strings, which orders dates written with the largest unit
+// first and zero padding, e.g. 2024.03.15, by date.
+type LexicographicComparator struct{}
+
+// Validate returns an error if the version is empty.
+func (LexicographicComparator) Validate(version string) error {
+	if TrimVersion(version) == "" {
+		return errors.New("the version is empty")
+	}
+	return nil
+}
+
+// Compare compares the strings.
+func (LexicographicComparator) Compare(a, b string) (int, error) {
+	return strings.Compare(TrimVersion(a), TrimVersion(b)), nil
+}
+
+// TrimVersion removes the whitespace and quotes surrounding a version returned by an instance.
+func TrimVersion(raw string) string {
+	return strings.Trim(strings.TrimSpace(raw), "\"")
+}
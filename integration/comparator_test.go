@@ -0,0 +1,62 @@
+package integration
+
+import "testing"
+
+func TestVersionComparators(t *testing.T) {
+	tests := []struct {
+		scheme   VersionScheme
+		a, b     string
+		expected int
+	}{
+		{scheme: VersionSchemeSemver, a: "1.2.3", b: "1.2.3", expected: 0},
+		{scheme: VersionSchemeSemver, a: "v1.2", b: "1.2.0", expected: 0},
+		{scheme: VersionSchemeSemver, a: "1.10.0", b: "1.9.0", expected: 1},
+		{scheme: VersionSchemeInteger, a: "4821", b: "4821", expected: 0},
+		{scheme: VersionSchemeInteger, a: " \"4821\"\n", b: "4821", expected: 0},
+		{scheme: VersionSchemeInteger, a: "9", b: "10", expected: -1},
+		{scheme: VersionSchemeInteger, a: "4822", b: "4821", expected: 1},
+		{scheme: VersionSchemeLexicographic, a: "2024.03.15", b: "2024.03.15", expected: 0},
+		{scheme: VersionSchemeLexicographic, a: "2024.03.15", b: "2024.11.01", expected: -1},
+		{scheme: VersionSchemeLexicographic, a: "2025.01.01", b: "2024.12.31", expected: 1},
+	}
+
+	for _, test := range tests {
+		actual, err := test.scheme.Comparator().Compare(test.a, test.b)
+
+		if err != nil {
+			t.Errorf("%s: unexpected error comparing %q with %q: %v", test.scheme, test.a, test.b, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("%s: expected comparing %q with %q to return %d, but got %d", test.scheme, test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func TestVersionComparatorsRejectInvalidVersions(t *testing.T) {
+	tests := []struct {
+		scheme  VersionScheme
+		version string
+	}{
+		{scheme: VersionSchemeSemver, version: "latest"},
+		{scheme: VersionSchemeInteger, version: "1.2.3"},
+		{scheme: VersionSchemeInteger, version: "build-4821"},
+		{scheme: VersionSchemeLexicographic, version: " "},
+	}
+
+	for _, test := range tests {
+		if err := test.scheme.Comparator().Validate(test.version); err == nil {
+			t.Errorf("%s: expected %q to be invalid", test.scheme, test.version)
+		}
+	}
+}
+
+func TestParseVersionScheme(t *testing.T) {
+	if _, err := ParseVersionScheme("integer"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParseVersionScheme("calver"); err == nil {
+		t.Errorf("expected an unknown scheme to be rejected")
+	}
+}
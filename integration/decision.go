@@ -0,0 +1,61 @@
+package integration
+
+import "github.com/blang/semver"
+
+// TerminationReason explains why an instance was selected for termination.
+type TerminationReason string
+
+// The reasons an instance is selected for termination.
+const (
+	ReasonBelowCanonical TerminationReason = "version below canonical"
+	ReasonAboveCanonical TerminationReason = "version above canonical"
+	ReasonOutsideRange   TerminationReason = "version outside range"
+	ReasonAgeExceeded    TerminationReason = "age exceeded"
+	ReasonTrimmed        TerminationReason = "trimmed to fit capacity"
+)
+
+// TerminationDecision is an instance selected for termination, and the reason it was selected.
+type TerminationDecision struct {
+	ID      string
+	Reason  TerminationReason
+	Version semver.Version
+}
+
+// KeepReason explains why an instance wasn't selected for termination.
+type KeepReason string
+
+// The reasons an instance is kept.
+const (
+	KeepVersionMatches    KeepReason = "version matches"
+	KeepMinimumCount      KeepReason = "part of the minimum count"
+	KeepGroupUnsafe       KeepReason = "the group isn't safe to change"
+	KeepUnhealthy         KeepReason = "unhealthy"
+	KeepTerminating       KeepReason = "already terminating"
+	KeepTransitioning     KeepReason = "pending or in standby"
+	KeepUnknownVersion    KeepReason = "version unknown"
+	KeepProtected         KeepReason = "protected from scale in"
+	KeepTooYoung          KeepReason = "launched too recently"
+	KeepMaxTerminations   KeepReason = "maximum terminations reached"
+	KeepExcludedDirection KeepReason = "version excluded by the direction"
+)
+
+// KeepDecision is an instance which wasn't selected for termination, and the reason it was kept.
+type KeepDecision struct {
+	ID     string
+	Reason KeepReason
+	// Version is the version of the instance, or empty when it's unknown.
+	Version string
+}
+
+// DecisionIDs returns the IDs of the instances, in the same order as the decisions.
+func DecisionIDs(decisions []TerminationDecision) []string {
+	if decisions == nil {
+		return nil
+	}
+
+	ids := make([]string, len(decisions))
+	for i, d := range decisions {
+		ids[i] = d.ID
+	}
+	return ids
+}
@@ -0,0 +1,38 @@
+package integration
+
+import "fmt"
+
+// Direction determines which side of the canonical version an instance's version must be on for it to be
+// terminated.
+type Direction string
+
+const (
+	// DirectionBoth terminates instances whose version is either below or above the canonical version.
+	DirectionBoth Direction = "both"
+	// DirectionBelow only terminates instances whose version is below the canonical version, e.g. so that a
+	// canary running a newer version survives a progressive rollout.
+	DirectionBelow Direction = "below"
+	// DirectionAbove only terminates instances whose version is above the canonical version, e.g. to roll back.
+	DirectionAbove Direction = "above"
+)
+
+// ParseDirection validates the name of a direction.
+func ParseDirection(s string) (Direction, error) {
+	switch Direction(s) {
+	case DirectionBoth, DirectionBelow, DirectionAbove:
+		return Direction(s), nil
+	}
+	return "", fmt.Errorf("unknown direction %q, expected %q, %q or %q", s, DirectionBoth, DirectionBelow, DirectionAbove)
+}
+
+// Allows returns true when an instance selected for the reason can be terminated. The empty direction allows
+// both directions.
+func (d Direction) Allows(reason TerminationReason) bool {
+	switch d {
+	case DirectionBelow:
+		return reason != ReasonAboveCanonical
+	case DirectionAbove:
+		return reason != ReasonBelowCanonical
+	}
+	return true
+}
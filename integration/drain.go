@@ -0,0 +1,173 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// DrainMode determines how instances are stopped from receiving new connections before they're terminated.
+type DrainMode string
+
+const (
+	// DrainModeStandby moves instances into standby, and then waits for the drain timeout.
+	DrainModeStandby DrainMode = "standby"
+	// DrainModeTargetGroups deregisters instances from the target groups of their group, and waits until the
+	// target groups have finished draining them, or the drain timeout elapses.
+	DrainModeTargetGroups DrainMode = "targetGroups"
+)
+
+// ParseDrainMode validates the name of a drain mode.
+func ParseDrainMode(s string) (DrainMode, error) {
+	switch DrainMode(s) {
+	case DrainModeStandby, DrainModeTargetGroups:
+		return DrainMode(s), nil
+	}
+	return "", fmt.Errorf("unknown drain mode %q, expected %q or %q", s, DrainModeStandby, DrainModeTargetGroups)
+}
+
+// targetHealthPollInterval is the delay between checks of whether an instance has finished draining from its
+// target groups, tests replace it to avoid waiting.
+var targetHealthPollInterval = 5 * time.Second
+
+// DrainInstance stops the instance of the group from receiving new connections. With the standby drain mode,
+// the instance is moved into standby, which deregisters it from the group's load balancers and target groups.
+// With the targetGroups drain mode, it's deregistered from the group's target groups, and DrainInstance
+// returns once they've finished draining it. In both modes, the desired capacity of the group isn't
+// decremented, so the group launches a replacement.
+func (p *AWSProvider) DrainInstance(ctx context.Context, groupName string, instanceID string) error {
+	if p.DrainMode == DrainModeTargetGroups {
+		return p.deregisterInstance(ctx, groupName, instanceID)
+	}
+
+	return p.EnterStandby(ctx, groupName, []string{instanceID})
+}
+
+// EnterStandby moves the instances of the group into standby, which deregisters them from the group's load
+// balancers and target groups. The desired capacity of the group isn't decremented, so the group launches
+// their replacements while they're still running.
+func (p *AWSProvider) EnterStandby(ctx context.Context, groupName string, instanceIDs []string) error {
+	slog.Info("moving instances into standby", "group", groupName, "instances", instanceIDs)
+
+	params := &autoscaling.EnterStandbyInput{
+		AutoScalingGroupName:           aws.String(groupName),
+		InstanceIds:                    aws.StringSlice(instanceIDs),
+		ShouldDecrementDesiredCapacity: aws.Bool(false),
+	}
+
+	if _, err := p.autoScaling.EnterStandbyWithContext(ctx, params); err != nil {
+		return fmt.Errorf("failed to move instances %v of group %s into standby, %v", instanceIDs, groupName, err)
+	}
+
+	return nil
+}
+
+// deregisterInstance deregisters the instance from the target groups of the group, and waits until each of them
+// reports the instance as unused, or the DrainTimeout elapses. When the timeout elapses, the instance is
+// terminated anyway, since it's no longer receiving new connections.
+func (p *AWSProvider) deregisterInstance(ctx context.Context, groupName string, instanceID string) error {
+	targetGroupARNs, err := p.targetGroups(ctx, groupName)
+
+	if err != nil {
+		return fmt.Errorf("failed to get the target groups of group %s, %v", groupName, err)
+	}
+
+	target := []*elbv2.TargetDescription{{Id: aws.String(instanceID)}}
+
+	for _, arn := range targetGroupARNs {
+		slog.Info("deregistering instance from target group", "group", groupName, "instance", instanceID, "targetGroup", arn)
+
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+			_, err := p.elbv2.DeregisterTargetsWithContext(ctx, &elbv2.DeregisterTargetsInput{
+				TargetGroupArn: aws.String(arn),
+				Targets:        target,
+			})
+			return err
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to deregister instance %s from target group %s, %v", instanceID, arn, err)
+		}
+	}
+
+	waitCtx := ctx
+	if p.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, p.DrainTimeout)
+		defer cancel()
+	}
+
+	for _, arn := range targetGroupARNs {
+		if err := p.waitUntilUnused(waitCtx, arn, instanceID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Warn("the instance didn't finish draining before the drain timeout, terminating it anyway", "group", groupName, "instance", instanceID, "targetGroup", arn, "error", err)
+			return nil
+		}
+	}
+
+	slog.Info("instance finished draining", "group", groupName, "instance", instanceID)
+
+	return nil
+}
+
+// targetGroups returns the ARNs of the target groups attached to the group.
+func (p *AWSProvider) targetGroups(ctx context.Context, groupName string) ([]string, error) {
+	var arns []string
+	err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+		arns = nil
+		return p.autoScaling.DescribeLoadBalancerTargetGroupsPagesWithContext(ctx, &autoscaling.DescribeLoadBalancerTargetGroupsInput{
+			AutoScalingGroupName: aws.String(groupName),
+		}, func(page *autoscaling.DescribeLoadBalancerTargetGroupsOutput, lastPage bool) bool {
+			for _, tg := range page.LoadBalancerTargetGroups {
+				arns = append(arns, aws.StringValue(tg.LoadBalancerTargetGroupARN))
+			}
+			return true
+		})
+	})
+	return arns, err
+}
+
+// waitUntilUnused polls the health of the instance in the target group until it's no longer registered, or
+// the context is done.
+func (p *AWSProvider) waitUntilUnused(ctx context.Context, targetGroupARN string, instanceID string) error {
+	for {
+		var output *elbv2.DescribeTargetHealthOutput
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() (err error) {
+			output, err = p.elbv2.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+				TargetGroupArn: aws.String(targetGroupARN),
+				Targets:        []*elbv2.TargetDescription{{Id: aws.String(instanceID)}},
+			})
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		state := elbv2.TargetHealthStateEnumUnused
+		for _, d := range output.TargetHealthDescriptions {
+			if d.TargetHealth != nil {
+				state = aws.StringValue(d.TargetHealth.State)
+			}
+		}
+
+		if state == elbv2.TargetHealthStateEnumUnused {
+			return nil
+		}
+
+		slog.Debug("waiting for the instance to drain", "instance", instanceID, "targetGroup", targetGroupARN, "state", state)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the instance was still %s, %v", state, ctx.Err())
+		case <-time.After(targetHealthPollInterval):
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+)
+
+type mockStandby struct {
+	autoscalingiface.AutoScalingAPI
+	inputs []*autoscaling.EnterStandbyInput
+	err    error
+}
+
+func (m *mockStandby) EnterStandbyWithContext(ctx aws.Context, input *autoscaling.EnterStandbyInput, opts ...request.Option) (*autoscaling.EnterStandbyOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &autoscaling.EnterStandbyOutput{}, m.err
+}
+
+func TestDrainInstance(t *testing.T) {
+	m := &mockStandby{}
+	p := &AWSProvider{autoScaling: m}
+
+	if err := p.DrainInstance(context.Background(), "asg_api", "i-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.inputs) != 1 {
+		t.Fatalf("Expected a single call to enter standby, but got %d", len(m.inputs))
+	}
+
+	input := m.inputs[0]
+	if aws.StringValue(input.AutoScalingGroupName) != "asg_api" {
+		t.Errorf("Expected the group asg_api, but got %s", aws.StringValue(input.AutoScalingGroupName))
+	}
+	if ids := aws.StringValueSlice(input.InstanceIds); len(ids) != 1 || ids[0] != "i-1" {
+		t.Errorf("Expected the instance i-1, but got %v", ids)
+	}
+	if aws.BoolValue(input.ShouldDecrementDesiredCapacity) {
+		t.Error("Expected the desired capacity not to be decremented, so that the instance is replaced")
+	}
+}
+
+func TestEnterStandbyMovesTheInstancesWithoutDecrementingTheGroup(t *testing.T) {
+	m := &mockStandby{}
+	p := &AWSProvider{autoScaling: m}
+
+	if err := p.EnterStandby(context.Background(), "asg_api", []string{"i-1", "i-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.inputs) != 1 {
+		t.Fatalf("Expected a single call to enter standby, but got %d", len(m.inputs))
+	}
+
+	input := m.inputs[0]
+	if ids := aws.StringValueSlice(input.InstanceIds); !reflect.DeepEqual(ids, []string{"i-1", "i-2"}) {
+		t.Errorf("Expected the instances i-1 and i-2, but got %v", ids)
+	}
+	if aws.BoolValue(input.ShouldDecrementDesiredCapacity) {
+		t.Error("Expected the desired capacity not to be decremented, so that the instances are replaced")
+	}
+}
+
+func TestDrainInstanceFailures(t *testing.T) {
+	p := &AWSProvider{autoScaling: &mockStandby{err: errors.New("instance is not in service")}}
+
+	err := p.DrainInstance(context.Background(), "asg_api", "i-1")
+
+	if err == nil || !strings.Contains(err.Error(), "instance is not in service") {
+		t.Errorf("Expected the error to be returned, but got %v", err)
+	}
+}
+
+type mockTargetGroups struct {
+	autoscalingiface.AutoScalingAPI
+	arns []string
+}
+
+func (m *mockTargetGroups) DescribeLoadBalancerTargetGroupsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeLoadBalancerTargetGroupsInput, fn func(*autoscaling.DescribeLoadBalancerTargetGroupsOutput, bool) bool, opts ...request.Option) error {
+	page := &autoscaling.DescribeLoadBalancerTargetGroupsOutput{}
+	for _, arn := range m.arns {
+		page.LoadBalancerTargetGroups = append(page.LoadBalancerTargetGroups, &autoscaling.LoadBalancerTargetGroupState{
+			LoadBalancerTargetGroupARN: aws.String(arn),
+		})
+	}
+	fn(page, true)
+	return nil
+}
+
+// mockELBV2 reports each of the states in turn for the deregistered targets, and then the last state.
+type mockELBV2 struct {
+	elbv2iface.ELBV2API
+	states       []string
+	checks       map[string]int
+	deregistered []string
+}
+
+func (m *mockELBV2) DeregisterTargetsWithContext(ctx aws.Context, input *elbv2.DeregisterTargetsInput, opts ...request.Option) (*elbv2.DeregisterTargetsOutput, error) {
+	m.deregistered = append(m.deregistered, aws.StringValue(input.TargetGroupArn)+" "+aws.StringValue(input.Targets[0].Id))
+	return &elbv2.DeregisterTargetsOutput{}, nil
+}
+
+func (m *mockELBV2) DescribeTargetHealthWithContext(ctx aws.Context, input *elbv2.DescribeTargetHealthInput, opts ...request.Option) (*elbv2.DescribeTargetHealthOutput, error) {
+	arn := aws.StringValue(input.TargetGroupArn)
+	state := m.states[len(m.states)-1]
+	if m.checks[arn] < len(m.states) {
+		state = m.states[m.checks[arn]]
+	}
+	m.checks[arn]++
+	return &elbv2.DescribeTargetHealthOutput{
+		TargetHealthDescriptions: []*elbv2.TargetHealthDescription{
+			{Target: input.Targets[0], TargetHealth: &elbv2.TargetHealth{State: aws.String(state)}},
+		},
+	}, nil
+}
+
+func TestDrainInstanceFromTargetGroups(t *testing.T) {
+	defer func(d time.Duration) { targetHealthPollInterval = d }(targetHealthPollInterval)
+	targetHealthPollInterval = time.Millisecond
+
+	m := &mockELBV2{
+		states: []string{elbv2.TargetHealthStateEnumHealthy, elbv2.TargetHealthStateEnumDraining, elbv2.TargetHealthStateEnumDraining, elbv2.TargetHealthStateEnumUnused},
+		checks: map[string]int{},
+	}
+	p := &AWSProvider{
+		autoScaling:  &mockTargetGroups{arns: []string{"tg-1", "tg-2"}},
+		elbv2:        m,
+		DrainMode:    DrainModeTargetGroups,
+		DrainTimeout: time.Minute,
+	}
+
+	if err := p.DrainInstance(context.Background(), "asg_api", "i-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"tg-1 i-1", "tg-2 i-1"}; !reflect.DeepEqual(m.deregistered, expected) {
+		t.Errorf("Expected the instance to be deregistered from each target group %v, but got %v", expected, m.deregistered)
+	}
+
+	if expected := map[string]int{"tg-1": 4, "tg-2": 4}; !reflect.DeepEqual(m.checks, expected) {
+		t.Errorf("Expected the health to be checked until the instance was unused %v, but got %v", expected, m.checks)
+	}
+}
+
+func TestDrainInstanceFromTargetGroupsGivesUpAfterTheTimeout(t *testing.T) {
+	defer func(d time.Duration) { targetHealthPollInterval = d }(targetHealthPollInterval)
+	targetHealthPollInterval = time.Millisecond
+
+	m := &mockELBV2{states: []string{elbv2.TargetHealthStateEnumDraining}, checks: map[string]int{}}
+	p := &AWSProvider{
+		autoScaling:  &mockTargetGroups{arns: []string{"tg-1"}},
+		elbv2:        m,
+		DrainMode:    DrainModeTargetGroups,
+		DrainTimeout: 20 * time.Millisecond,
+	}
+
+	if err := p.DrainInstance(context.Background(), "asg_api", "i-1"); err != nil {
+		t.Errorf("Expected the instance to be terminated anyway once the timeout elapsed, but got %v", err)
+	}
+
+	if m.checks["tg-1"] < 2 {
+		t.Errorf("Expected the health to be polled until the timeout, but got %d checks", m.checks["tg-1"])
+	}
+}
+
+func TestParseDrainMode(t *testing.T) {
+	for _, s := range []string{"standby", "targetGroups"} {
+		if mode, err := ParseDrainMode(s); err != nil || string(mode) != s {
+			t.Errorf("Expected %q to be valid, but got %q, %v", s, mode, err)
+		}
+	}
+
+	if _, err := ParseDrainMode("deregister"); err == nil {
+		t.Error("Expected an unknown drain mode to be rejected")
+	}
+}
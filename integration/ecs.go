@@ -0,0 +1,496 @@
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// ecsAPI is the part of the ECS API used by the ECSProvider.
+type ecsAPI interface {
+	ListServicesPagesWithContext(ctx aws.Context, input *ecs.ListServicesInput, fn func(*ecs.ListServicesOutput, bool) bool, opts ...request.Option) error
+	DescribeServicesWithContext(ctx aws.Context, input *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error)
+	ListTasksPagesWithContext(ctx aws.Context, input *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool, opts ...request.Option) error
+	DescribeTasksWithContext(ctx aws.Context, input *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error)
+	StopTaskWithContext(ctx aws.Context, input *ecs.StopTaskInput, opts ...request.Option) (*ecs.StopTaskOutput, error)
+}
+
+// The maximum number of services and tasks ECS describes in a single call.
+const (
+	maxDescribeServicesBatchSize = 10
+	maxDescribeTasksBatchSize    = 100
+)
+
+// stopTaskReason is recorded against the tasks stopped by terminator, and shown in the ECS console.
+const stopTaskReason = "Stopped by terminator, the task wasn't running the expected version."
+
+// ECSProvider provides data from ECS. The services of the cluster take the place of auto-scaling groups, and
+// their tasks take the place of instances, so the ID of each instance is the ARN of a task. Tasks are
+// "terminated" by stopping them, so that the service starts replacements.
+type ECSProvider struct {
+	ecs ecsAPI
+	// Cluster is the name or ARN of the ECS cluster which runs the services.
+	Cluster string
+	// ProbeRetries is the number of times a failed version probe is retried.
+	ProbeRetries int
+	// ProbeBackoff is the delay before the first retry of a version probe, doubling on each retry.
+	ProbeBackoff time.Duration
+	// ThrottleRetries is the number of times an AWS API call which was throttled is retried.
+	ThrottleRetries int
+	// ThrottleBackoff is the delay before the first retry of a throttled AWS API call, doubling on each retry.
+	ThrottleBackoff time.Duration
+	// Concurrency is the maximum number of tasks in a service which are probed at the same time.
+	Concurrency int
+	// TLSConfig configures probes of HTTPS version endpoints, nil uses the default configuration.
+	TLSConfig    *tls.Config
+	probeClients probeClients
+	// ProbeHeaders are sent with each request to the version endpoint, e.g. an Authorization header.
+	ProbeHeaders http.Header
+	// MaxResponseBytes, when greater than zero, is the largest version endpoint response which will be read.
+	MaxResponseBytes int64
+	// VersionHeader, when set, is the response header which the version is read from instead of the body.
+	VersionHeader string
+	// VersionJSONPath, when set, is the path to the version within a JSON response, e.g. ".app.version".
+	VersionJSONPath string
+	// VersionRegex, when set, extracts the version from the response using its capture group, e.g.
+	// `Version: (\S+)`, after VersionJSONPath is applied.
+	VersionRegex *regexp.Regexp
+	// AllowPartialDetails is set when services are acted on even if the details of some healthy tasks couldn't
+	// be read, see AutoScalingGroup.AllowPartialDetails.
+	AllowPartialDetails bool
+	// MinInstanceAge, when greater than zero, spares tasks started more recently than the age from being stopped.
+	MinInstanceAge time.Duration
+	// MaxInstanceAge, when greater than zero, makes tasks started longer ago than the age candidates for being stopped.
+	MaxInstanceAge time.Duration
+	// VersionScheme determines how versions are compared, see AWSProvider.VersionScheme.
+	VersionScheme VersionScheme
+	// NormalizeVersions removes build metadata and git describe suffixes from the versions of tasks.
+	NormalizeVersions bool
+}
+
+// NewECSProvider creates an ECSProvider.
+// region, the default AWS region e.g. "eu-west-1"
+// cluster, the name or ARN of the ECS cluster
+// role, the IAM role to assume, if any
+// endpoint, when set, the URL of the AWS APIs, e.g. "http://localhost:4566" for LocalStack
+// profile, when set, the named profile of the shared credentials and config files to use
+func NewECSProvider(region string, cluster string, role AssumeRole, endpoint string, profile string) (*ECSProvider, error) {
+	sess, err := session.NewSessionWithOptions(sessionOptions(region, endpoint, profile))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a session, %-v", err)
+	}
+
+	if role.ARN != "" {
+		sess = sess.Copy(&aws.Config{Credentials: newAssumeRoleCredentials(sts.New(sess), role)})
+	}
+
+	return &ECSProvider{
+		ecs:             ecs.New(sess),
+		Cluster:         cluster,
+		Concurrency:     1,
+		ThrottleRetries: defaultThrottleRetries,
+		ThrottleBackoff: defaultThrottleBackoff,
+	}, nil
+}
+
+// DescribeAutoScalingGroups provides information about the services of the cluster, or when names are given,
+// the named services.
+func (p *ECSProvider) DescribeAutoScalingGroups(ctx context.Context, names []string, scheme string, port int, path string) ([]AutoScalingGroup, error) {
+	slog.Debug("retrieving data on ECS services", "cluster", p.Cluster, "services", names)
+	start := time.Now()
+
+	if len(names) == 0 {
+		var err error
+		names, err = p.listServices(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the services of cluster %s, %-v", p.Cluster, err)
+		}
+	}
+
+	services, err := p.describeServices(ctx, names)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the description of the services of cluster %s, %-v", p.Cluster, err)
+	}
+
+	var groups []AutoScalingGroup
+
+	for _, s := range services {
+		serviceName := aws.StringValue(s.ServiceName)
+		slog.Debug("getting task details for this service", "service", serviceName)
+
+		tasks, err := p.serviceTasks(ctx, serviceName)
+
+		if err != nil {
+			slog.Warn("failed to get the tasks of the service, skipping this service", "service", serviceName, "error", err)
+			continue
+		}
+
+		instances := make([]*autoscaling.Instance, len(tasks))
+		tasksByARN := map[string]*ecs.Task{}
+		for i, task := range tasks {
+			instances[i] = taskInstance(task)
+			tasksByARN[aws.StringValue(task.TaskArn)] = task
+		}
+
+		details := getDetailsConcurrently(instances, serviceName, p.Concurrency, func(taskARN string) (*InstanceDetail, error) {
+			return p.getTaskDetail(ctx, tasksByARN[taskARN], scheme, port, path)
+		})
+
+		if len(details) == 0 {
+			slog.Warn("failed to get task details, skipping this service", "service", serviceName)
+			continue
+		}
+
+		group := NewAutoScalingGroup(serviceName, instances, details)
+		group.DesiredCapacity = int(aws.Int64Value(s.DesiredCount))
+		group.AllowPartialDetails = p.AllowPartialDetails
+		group.MinimumAge = p.MinInstanceAge
+		group.MaximumAge = p.MaxInstanceAge
+
+		slog.Debug("retrieved all task details", "service", serviceName)
+		groups = append(groups, group)
+	}
+
+	slog.Debug("time: *ECSProvider.DescribeAutoScalingGroups()", "duration", time.Since(start))
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("No valid services found.")
+	}
+
+	return groups, nil
+}
+
+// listServices returns the names of the services of the cluster.
+func (p *ECSProvider) listServices(ctx context.Context) ([]string, error) {
+	var names []string
+	err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+		// A throttled page restarts the pagination, so the services of earlier attempts are discarded.
+		names = nil
+		return p.ecs.ListServicesPagesWithContext(ctx, &ecs.ListServicesInput{
+			Cluster: aws.String(p.Cluster),
+		}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+			for _, arn := range page.ServiceArns {
+				names = append(names, serviceName(aws.StringValue(arn)))
+			}
+			return true
+		})
+	})
+	return names, err
+}
+
+// serviceName returns the name of the service from its ARN, e.g. "api" from
+// "arn:aws:ecs:eu-west-1:123456789012:service/cluster/api".
+func serviceName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// describeServices describes the services, in batches of up to 10 services. Services which don't exist are skipped.
+func (p *ECSProvider) describeServices(ctx context.Context, names []string) ([]*ecs.Service, error) {
+	var services []*ecs.Service
+
+	for _, b := range batch(names, maxDescribeServicesBatchSize) {
+		var output *ecs.DescribeServicesOutput
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() (err error) {
+			output, err = p.ecs.DescribeServicesWithContext(ctx, &ecs.DescribeServicesInput{
+				Cluster:  aws.String(p.Cluster),
+				Services: convert(b),
+			})
+			return err
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range output.Failures {
+			slog.Warn("failed to describe the service", "service", aws.StringValue(f.Arn), "reason", aws.StringValue(f.Reason))
+		}
+
+		services = append(services, output.Services...)
+	}
+
+	return services, nil
+}
+
+// serviceTasks returns the tasks of the service which ECS intends to keep running.
+func (p *ECSProvider) serviceTasks(ctx context.Context, serviceName string) ([]*ecs.Task, error) {
+	var taskARNs []string
+	err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+		taskARNs = nil
+		return p.ecs.ListTasksPagesWithContext(ctx, &ecs.ListTasksInput{
+			Cluster:       aws.String(p.Cluster),
+			ServiceName:   aws.String(serviceName),
+			DesiredStatus: aws.String(ecs.DesiredStatusRunning),
+		}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+			for _, arn := range page.TaskArns {
+				taskARNs = append(taskARNs, aws.StringValue(arn))
+			}
+			return true
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.describeTasks(ctx, taskARNs)
+}
+
+// describeTasks describes the tasks, in batches of up to 100 tasks.
+func (p *ECSProvider) describeTasks(ctx context.Context, taskARNs []string) ([]*ecs.Task, error) {
+	var tasks []*ecs.Task
+
+	for _, b := range batch(taskARNs, maxDescribeTasksBatchSize) {
+		var output *ecs.DescribeTasksOutput
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() (err error) {
+			output, err = p.ecs.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+				Cluster: aws.String(p.Cluster),
+				Tasks:   convert(b),
+			})
+			return err
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, output.Tasks...)
+	}
+
+	return tasks, nil
+}
+
+// taskInstance describes the task as an auto-scaling instance, so that services are evaluated in the same way
+// as auto-scaling groups.
+func taskInstance(task *ecs.Task) *autoscaling.Instance {
+	healthStatus := "Healthy"
+	if aws.StringValue(task.HealthStatus) == ecs.HealthStatusUnhealthy {
+		healthStatus = "Unhealthy"
+	}
+
+	return &autoscaling.Instance{
+		InstanceId:       task.TaskArn,
+		HealthStatus:     aws.String(healthStatus),
+		LifecycleState:   aws.String(taskLifecycleState(aws.StringValue(task.LastStatus))),
+		AvailabilityZone: task.AvailabilityZone,
+	}
+}
+
+// taskLifecycleState returns the auto-scaling lifecycle state which corresponds to the last status of a task.
+func taskLifecycleState(lastStatus string) string {
+	switch lastStatus {
+	case "RUNNING":
+		return autoscaling.LifecycleStateInService
+	case "PROVISIONING", "PENDING", "ACTIVATING":
+		return autoscaling.LifecycleStatePending
+	}
+	return autoscaling.LifecycleStateTerminating
+}
+
+// taskAddress returns the private IP address of the task, which is only known for tasks using the awsvpc
+// network mode.
+func taskAddress(task *ecs.Task) (string, error) {
+	for _, c := range task.Containers {
+		for _, ni := range c.NetworkInterfaces {
+			if ip := aws.StringValue(ni.PrivateIpv4Address); ip != "" {
+				return ip, nil
+			}
+		}
+	}
+
+	for _, a := range task.Attachments {
+		for _, d := range a.Details {
+			if aws.StringValue(d.Name) == "privateIPv4Address" && aws.StringValue(d.Value) != "" {
+				return aws.StringValue(d.Value), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("task %s doesn't have a private IP address, only tasks using the awsvpc network mode can be probed", aws.StringValue(task.TaskArn))
+}
+
+// GetDetail returns information about the task, reading its version from the endpoint. The endpoint can be a
+// comma-separated list of paths, which are tried in order.
+func (p *ECSProvider) GetDetail(ctx context.Context, taskARN string, scheme string, port int, endpoint string) (*InstanceDetail, error) {
+	tasks, err := p.describeTasks(ctx, []string{taskARN})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("Could not find a task with ARN %s", taskARN)
+	}
+
+	return p.getTaskDetail(ctx, tasks[0], scheme, port, endpoint)
+}
+
+// GetInstanceDetails returns the details of the tasks of the service.
+func (p *ECSProvider) GetInstanceDetails(ctx context.Context, instances []*autoscaling.Instance, serviceName string, scheme string, port int, path string) (InstanceDetails, error) {
+	details := getDetailsConcurrently(instances, serviceName, p.Concurrency, func(taskARN string) (*InstanceDetail, error) {
+		return p.GetDetail(ctx, taskARN, scheme, port, path)
+	})
+
+	if len(details) <= 0 {
+		return nil, fmt.Errorf("Couldn't get any task details")
+	}
+
+	return details, nil
+}
+
+// getTaskDetail probes each of the paths of the endpoint of the task in turn for its version.
+func (p *ECSProvider) getTaskDetail(ctx context.Context, task *ecs.Task, scheme string, port int, endpoint string) (*InstanceDetail, error) {
+	taskARN := aws.StringValue(task.TaskArn)
+	host, err := taskAddress(task)
+
+	if err != nil {
+		return nil, err
+	}
+
+	launchTime := aws.TimeValue(task.StartedAt)
+	if launchTime.IsZero() {
+		launchTime = aws.TimeValue(task.CreatedAt)
+	}
+
+	return getDetailFromPaths(taskARN, endpoint, func(path string) (*InstanceDetail, error) {
+		path, err := renderPath(path, taskARN, host)
+
+		if err != nil {
+			return nil, err
+		}
+
+		complete := probeURL(scheme, host, port, path)
+
+		if _, err := url.Parse(complete); err != nil {
+			return nil, fmt.Errorf("Failed to parse URL %s - %-v", complete, err)
+		}
+
+		pr := probe{
+			client:           p.probeClients.get(p.TLSConfig),
+			headers:          p.ProbeHeaders,
+			versionHeader:    p.VersionHeader,
+			maxResponseBytes: p.MaxResponseBytes,
+		}
+
+		versionNumber, err := getURLWithRetries(ctx, pr, complete, p.ProbeRetries, p.ProbeBackoff)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
+		}
+
+		if p.VersionJSONPath != "" {
+			versionNumber, err = getJSONPath(versionNumber, p.VersionJSONPath)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
+			}
+		}
+
+		if p.VersionRegex != nil {
+			versionNumber, err = getVersionMatch(versionNumber, p.VersionRegex)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get version number from URL %s with error %-v", complete, err)
+			}
+		}
+
+		detail, err := newInstanceDetail(taskARN, versionNumber, launchTime, p.VersionScheme, p.NormalizeVersions)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &detail, nil
+	})
+}
+
+// TerminateInstances stops the given tasks, so that their services start replacements. If a task fails to
+// stop, the remaining tasks are still attempted, and the returned error describes each failure.
+func (p *ECSProvider) TerminateInstances(ctx context.Context, taskARNs []string) error {
+	var failures []string
+
+	for _, arn := range taskARNs {
+		params := &ecs.StopTaskInput{
+			Cluster: aws.String(p.Cluster),
+			Task:    aws.String(arn),
+			Reason:  aws.String(stopTaskReason),
+		}
+
+		err := withThrottlingRetries(ctx, p.ThrottleRetries, p.ThrottleBackoff, func() error {
+			_, err := p.ecs.StopTaskWithContext(ctx, params)
+			return err
+		})
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s failed, %v", arn, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to stop tasks: %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// TerminateGroupInstances stops the given tasks. The desired count of a service can't be decremented by
+// stopping its tasks.
+func (p *ECSProvider) TerminateGroupInstances(ctx context.Context, taskARNs []string, shouldDecrementDesiredCapacity bool) error {
+	if shouldDecrementDesiredCapacity {
+		return fmt.Errorf("the desired count of ECS services can't be decremented")
+	}
+
+	return p.TerminateInstances(ctx, taskARNs)
+}
+
+// CompleteLifecycle does nothing, since ECS services don't have lifecycle hooks.
+func (p *ECSProvider) CompleteLifecycle(ctx context.Context, serviceName string, taskARN string) error {
+	return nil
+}
+
+// DrainInstance isn't supported for ECS services, ECS drains the connections of stopped tasks from their load
+// balancers itself.
+func (p *ECSProvider) DrainInstance(ctx context.Context, serviceName string, taskARN string) error {
+	return fmt.Errorf("draining tasks of ECS services isn't supported")
+}
+
+// EnterStandby isn't supported for ECS services, tasks don't have a standby state.
+func (p *ECSProvider) EnterStandby(ctx context.Context, serviceName string, taskARNs []string) error {
+	return fmt.Errorf("moving tasks of ECS services into standby isn't supported")
+}
+
+// SuspendProcesses isn't supported for ECS services.
+func (p *ECSProvider) SuspendProcesses(ctx context.Context, serviceName string, processes []string) error {
+	return fmt.Errorf("suspending the processes of ECS services isn't supported")
+}
+
+// ResumeProcesses isn't supported for ECS services.
+func (p *ECSProvider) ResumeProcesses(ctx context.Context, serviceName string, processes []string) error {
+	return fmt.Errorf("resuming the processes of ECS services isn't supported")
+}
+
+// Notify isn't supported for ECS services.
+func (p *ECSProvider) Notify(ctx context.Context, topicARN string, terminated map[string][]string) error {
+	return fmt.Errorf("SNS notifications aren't supported for ECS services")
+}
+
+// PutMetrics isn't supported for ECS services.
+func (p *ECSProvider) PutMetrics(ctx context.Context, namespace string, metrics []GroupMetrics) error {
+	return fmt.Errorf("CloudWatch metrics aren't supported for ECS services")
+}
@@ -0,0 +1,219 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// mockECS is a cluster of services, whose tasks are stored by ARN.
+type mockECS struct {
+	services map[string][]*ecs.Task
+	stopped  []*ecs.StopTaskInput
+	fail     map[string]bool
+}
+
+func (m *mockECS) ListServicesPagesWithContext(ctx aws.Context, input *ecs.ListServicesInput, fn func(*ecs.ListServicesOutput, bool) bool, opts ...request.Option) error {
+	page := &ecs.ListServicesOutput{}
+	for name := range m.services {
+		page.ServiceArns = append(page.ServiceArns, aws.String("arn:aws:ecs:eu-west-1:123456789012:service/cluster/"+name))
+	}
+	fn(page, true)
+	return nil
+}
+
+func (m *mockECS) DescribeServicesWithContext(ctx aws.Context, input *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	output := &ecs.DescribeServicesOutput{}
+	for _, name := range input.Services {
+		tasks, ok := m.services[aws.StringValue(name)]
+		if !ok {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: name, Reason: aws.String("MISSING")})
+			continue
+		}
+		output.Services = append(output.Services, &ecs.Service{ServiceName: name, DesiredCount: aws.Int64(int64(len(tasks)))})
+	}
+	return output, nil
+}
+
+func (m *mockECS) ListTasksPagesWithContext(ctx aws.Context, input *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool, opts ...request.Option) error {
+	page := &ecs.ListTasksOutput{}
+	for _, task := range m.services[aws.StringValue(input.ServiceName)] {
+		page.TaskArns = append(page.TaskArns, task.TaskArn)
+	}
+	fn(page, true)
+	return nil
+}
+
+func (m *mockECS) DescribeTasksWithContext(ctx aws.Context, input *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error) {
+	output := &ecs.DescribeTasksOutput{}
+	for _, arn := range input.Tasks {
+		for _, tasks := range m.services {
+			for _, task := range tasks {
+				if aws.StringValue(task.TaskArn) == aws.StringValue(arn) {
+					output.Tasks = append(output.Tasks, task)
+				}
+			}
+		}
+	}
+	return output, nil
+}
+
+func (m *mockECS) StopTaskWithContext(ctx aws.Context, input *ecs.StopTaskInput, opts ...request.Option) (*ecs.StopTaskOutput, error) {
+	m.stopped = append(m.stopped, input)
+	if m.fail[aws.StringValue(input.Task)] {
+		return nil, errors.New("task not found")
+	}
+	return &ecs.StopTaskOutput{}, nil
+}
+
+func newMockTask(arn string, lastStatus string, healthStatus string) *ecs.Task {
+	return &ecs.Task{
+		TaskArn:      aws.String(arn),
+		LastStatus:   aws.String(lastStatus),
+		HealthStatus: aws.String(healthStatus),
+		StartedAt:    aws.Time(time.Now()),
+		Containers: []*ecs.Container{
+			{NetworkInterfaces: []*ecs.NetworkInterface{{PrivateIpv4Address: aws.String("127.0.0.1")}}},
+		},
+	}
+}
+
+func TestECSServicesAreDescribedAsGroups(t *testing.T) {
+	versions := map[string]string{"task-1": "1.0.0", "task-2": "1.1.0", "task-3": "1.2.0"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, versions[strings.TrimPrefix(r.URL.Path, "/version/")])
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	m := &mockECS{services: map[string][]*ecs.Task{
+		"api": {
+			newMockTask("task-1", "RUNNING", ecs.HealthStatusHealthy),
+			newMockTask("task-2", "RUNNING", ecs.HealthStatusUnhealthy),
+			newMockTask("task-3", "PENDING", ecs.HealthStatusUnknown),
+		},
+	}}
+	p := &ECSProvider{ecs: m, Cluster: "cluster", Concurrency: 1}
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version/{{.InstanceID}}")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 || groups[0].Name != "api" || groups[0].DesiredCapacity != 3 {
+		t.Fatalf("Expected the api service with a desired count of 3, but got %+v", groups)
+	}
+
+	states := map[string]bool{}
+	for _, instance := range groups[0].Instances {
+		states[instance.ID] = instance.IsHealthy()
+	}
+	expected := map[string]bool{"task-1": true, "task-2": false, "task-3": false}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected the health of the tasks to be %v, but got %v", expected, states)
+	}
+
+	if len(groups[0].InstanceDetails) != 3 {
+		t.Fatalf("Expected the version of each task, but got %+v", groups[0].InstanceDetails)
+	}
+	for _, d := range groups[0].InstanceDetails {
+		if d.Version() != versions[d.ID] {
+			t.Errorf("Expected the version of %s to be %s, but got %s", d.ID, versions[d.ID], d.Version())
+		}
+	}
+}
+
+func TestECSServicesWhichDoNotExistAreSkipped(t *testing.T) {
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	m := &mockECS{services: map[string][]*ecs.Task{
+		"api": {newMockTask("task-1", "RUNNING", ecs.HealthStatusHealthy)},
+	}}
+	p := &ECSProvider{ecs: m, Cluster: "cluster", Concurrency: 1}
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), []string{"api", "web"}, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 || groups[0].Name != "api" {
+		t.Errorf("Expected only the api service, but got %+v", groups)
+	}
+}
+
+func TestTasksWithoutAPrivateIPCannotBeProbed(t *testing.T) {
+	task := newMockTask("task-1", "RUNNING", ecs.HealthStatusHealthy)
+	task.Containers = nil
+	p := &ECSProvider{ecs: &mockECS{services: map[string][]*ecs.Task{"api": {task}}}, Cluster: "cluster"}
+
+	_, err := p.GetDetail(context.Background(), "task-1", "http", 80, "/version")
+
+	if err == nil || !strings.Contains(err.Error(), "awsvpc") {
+		t.Errorf("Expected an error explaining the task can't be probed, but got %v", err)
+	}
+}
+
+func TestTaskAddressIsReadFromTheAttachment(t *testing.T) {
+	task := &ecs.Task{
+		TaskArn: aws.String("task-1"),
+		Attachments: []*ecs.Attachment{
+			{Details: []*ecs.KeyValuePair{
+				{Name: aws.String("subnetId"), Value: aws.String("subnet-1")},
+				{Name: aws.String("privateIPv4Address"), Value: aws.String("10.0.0.5")},
+			}},
+		},
+	}
+
+	ip, err := taskAddress(task)
+
+	if err != nil || ip != "10.0.0.5" {
+		t.Errorf("Expected the address of the network interface attachment, but got %q, %v", ip, err)
+	}
+}
+
+func TestTerminatingTasksStopsThem(t *testing.T) {
+	m := &mockECS{fail: map[string]bool{"task-2": true}}
+	p := &ECSProvider{ecs: m, Cluster: "cluster"}
+
+	err := p.TerminateInstances(context.Background(), []string{"task-1", "task-2", "task-3"})
+
+	if len(m.stopped) != 3 {
+		t.Fatalf("Expected every task to be stopped, but got %d calls", len(m.stopped))
+	}
+
+	for _, input := range m.stopped {
+		if aws.StringValue(input.Cluster) != "cluster" || aws.StringValue(input.Reason) == "" {
+			t.Errorf("Expected the task to be stopped in the cluster with a reason, but got %+v", input)
+		}
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "task-2 failed") || strings.Contains(err.Error(), "task-1") {
+		t.Errorf("Expected the error to describe the failed task, but got %v", err)
+	}
+}
+
+func TestTheDesiredCountOfServicesIsNeverDecremented(t *testing.T) {
+	m := &mockECS{}
+	p := &ECSProvider{ecs: m, Cluster: "cluster"}
+
+	err := p.TerminateGroupInstances(context.Background(), []string{"task-1"}, true)
+
+	if err == nil || len(m.stopped) != 0 {
+		t.Errorf("Expected an error and no stopped tasks, but got %v and %d calls", err, len(m.stopped))
+	}
+}
@@ -2,6 +2,7 @@ package integration
 
 import (
   "time"
+  "strconv"
   "strings"
 
   "github.com/blang/semver"
@@ -12,18 +13,86 @@ type Instance struct {
 	ID             string
 	HealthStatus   string
 	LifecycleState string
+	// ProtectedFromScaleIn is set when the group's scale-in protection applies to the instance.
+	ProtectedFromScaleIn bool
+	// AvailabilityZone is the zone the instance is running in, e.g. "eu-west-1a".
+	AvailabilityZone string
+	// FailedHealthCheck is set when the instance didn't pass the health check at the health URL, so it's
+	// unhealthy whatever its HealthStatus.
+	FailedHealthCheck bool
+	// Weight is the capacity the instance provides to a group of mixed instance types, see
+	// AutoScalingGroup.UseWeightedCapacity. It's 1 when the group doesn't weight its instances.
+	Weight float64
+	// InstanceType is the EC2 instance type, e.g. "m5.large".
+	InstanceType string
+	// LaunchTemplateName and LaunchTemplateVersion are the launch template the instance was launched from, and
+	// LaunchConfigurationName is its launch configuration, when the group uses one instead.
+	LaunchTemplateName      string
+	LaunchTemplateVersion   string
+	LaunchConfigurationName string
+}
+
+// parseWeight reads the weighted capacity of an instance, which is 1 when the group doesn't weight its
+// instances.
+func parseWeight(weightedCapacity string) float64 {
+	weight, err := strconv.ParseFloat(weightedCapacity, 64)
+	if err != nil || weight <= 0 {
+		return 1
+	}
+	return weight
 }
 
 func (instance Instance) IsHealthy() bool {
-	return strings.EqualFold(instance.HealthStatus, "Healthy") &&
+	return !instance.FailedHealthCheck &&
+		strings.EqualFold(instance.HealthStatus, "Healthy") &&
 		strings.EqualFold(instance.LifecycleState, "InService")
 }
 
+// IsTerminating returns true when the instance is already being removed, e.g. by a previous run, and
+// will shortly disappear from the group.
+func (instance Instance) IsTerminating() bool {
+	state := strings.ToLower(instance.LifecycleState)
+	return strings.HasPrefix(state, "terminating") ||
+		state == "terminated" ||
+		state == "shutting-down"
+}
+
+// IsWaitingToTerminate returns true when the termination of the instance is paused by a lifecycle hook.
+func (instance Instance) IsWaitingToTerminate() bool {
+	return strings.EqualFold(instance.LifecycleState, "Terminating:Wait")
+}
+
+// IsStandby returns true when the instance has been put into standby, so it isn't serving traffic.
+func (instance Instance) IsStandby() bool {
+	state := strings.ToLower(instance.LifecycleState)
+	return state == "standby" || state == "enteringstandby"
+}
+
+// IsPending returns true when the instance is still being launched into the group.
+func (instance Instance) IsPending() bool {
+	return strings.HasPrefix(strings.ToLower(instance.LifecycleState), "pending")
+}
+
 // InstanceDetail provides information about the instance from EC2.
 type InstanceDetail struct {
 	ID            string
 	VersionNumber semver.Version
-	LaunchTime    time.Time
+	// RawVersion is the version as returned by the instance, before it was parsed.
+	RawVersion string
+	// Warnings describe how a RawVersion which wasn't a valid semantic version was interpreted.
+	Warnings   []string
+	LaunchTime time.Time
+	// Scheme is the scheme the version was read with. When it isn't semver, VersionNumber isn't set, and the
+	// version is compared using RawVersion.
+	Scheme VersionScheme
+}
+
+// Version returns the version of the instance in its scheme, e.g. "1.2.3" or "4821".
+func (d InstanceDetail) Version() string {
+	if !d.Scheme.IsSemver() {
+		return TrimVersion(d.RawVersion)
+	}
+	return d.VersionNumber.String()
 }
 
 // InstanceDetails implements a sorted type for InstanceDetail.
@@ -33,10 +102,33 @@ func (slice InstanceDetails) Len() int {
 	return len(slice)
 }
 
+// Less orders instances by version, and then oldest first within a version.
 func (slice InstanceDetails) Less(i, j int) bool {
-	return slice[i].VersionNumber.LT(slice[j].VersionNumber) || slice[i].LaunchTime.Before(slice[j].LaunchTime)
+	if !slice[i].VersionNumber.Equals(slice[j].VersionNumber) {
+		return slice[i].VersionNumber.LT(slice[j].VersionNumber)
+	}
+	return slice[i].LaunchTime.Before(slice[j].LaunchTime)
 }
 
 func (slice InstanceDetails) Swap(i, j int) {
 	slice[i], slice[j] = slice[j], slice[i]
 }
+
+// ModalVersion returns the most common version. When versions are equally common, the highest is returned.
+func (slice InstanceDetails) ModalVersion() semver.Version {
+	counts := map[string]int{}
+	var modal semver.Version
+	modalCount := 0
+
+	for _, d := range slice {
+		key := d.VersionNumber.String()
+		counts[key]++
+
+		if counts[key] > modalCount || (counts[key] == modalCount && d.VersionNumber.GT(modal)) {
+			modal = d.VersionNumber
+			modalCount = counts[key]
+		}
+	}
+
+	return modal
+}
@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/blang/semver"
+)
+
+// VersionSource determines where the version of an instance is read from.
+type VersionSource string
+
+const (
+	// VersionSourceHTTP reads the application version from the instance's version endpoint.
+	VersionSourceHTTP VersionSource = "http"
+	// VersionSourceSSMInventory reads the operating system version from SSM Inventory.
+	VersionSourceSSMInventory VersionSource = "ssmInventory"
+	// VersionSourceTag reads the application version from an EC2 tag of the instance, see
+	// AWSProvider.VersionTagKey.
+	VersionSourceTag VersionSource = "tag"
+)
+
+// ParseVersionSource validates the name of a version source.
+func ParseVersionSource(s string) (VersionSource, error) {
+	switch VersionSource(s) {
+	case VersionSourceHTTP, VersionSourceSSMInventory, VersionSourceTag:
+		return VersionSource(s), nil
+	}
+	return "", fmt.Errorf("unknown version source %q, expected %q, %q or %q", s, VersionSourceHTTP, VersionSourceSSMInventory, VersionSourceTag)
+}
+
+const instanceInformationType = "AWS:InstanceInformation"
+
+// GetInventoryDetail returns the launch time of the instance and the version of its operating system
+// as recorded by SSM Inventory, e.g. Amazon Linux "2" becomes 2.0.0 and Ubuntu "20.04" becomes 20.4.0.
+func (p *AWSProvider) GetInventoryDetail(ctx context.Context, instanceID string) (*InstanceDetail, error) {
+	instance, err := p.describeInstance(ctx, instanceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inventory, err := p.ssm.ListInventoryEntriesWithContext(ctx, &ssm.ListInventoryEntriesInput{
+		InstanceId: aws.String(instanceID),
+		TypeName:   aws.String(instanceInformationType),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the SSM inventory of instance %s with error %-v", instanceID, err)
+	}
+
+	version, err := getPlatformVersion(inventory.Entries)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the OS version of instance %s from SSM inventory with error %-v", instanceID, err)
+	}
+
+	return &InstanceDetail{
+		ID:            instanceID,
+		VersionNumber: version,
+		LaunchTime:    aws.TimeValue(instance.LaunchTime),
+	}, nil
+}
+
+func getPlatformVersion(entries []map[string]*string) (semver.Version, error) {
+	for _, entry := range entries {
+		if v, ok := entry["PlatformVersion"]; ok && aws.StringValue(v) != "" {
+			return ParseOSVersion(aws.StringValue(v))
+		}
+	}
+
+	return semver.Version{}, fmt.Errorf("no PlatformVersion found in inventory")
+}
+
+// ParseOSVersion converts an operating system version such as "2", "7.9" or "20.04" into a semantic version.
+func ParseOSVersion(s string) (semver.Version, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ".", 3)
+
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+
+		if err != nil {
+			return semver.Version{}, fmt.Errorf("invalid OS version %q", s)
+		}
+
+		parts[i] = strconv.FormatUint(n, 10)
+	}
+
+	return semver.ParseTolerant(strings.Join(parts, "."))
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestParseOSVersion(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		expectError bool
+	}{
+		{input: "2", expected: "2.0.0"},
+		{input: "7.9", expected: "7.9.0"},
+		{input: "20.04", expected: "20.4.0"},
+		{input: "10.0.17763", expected: "10.0.17763"},
+		{input: "", expectError: true},
+		{input: "rolling", expectError: true},
+	}
+
+	for _, test := range tests {
+		actual, err := ParseOSVersion(test.input)
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Expected an error parsing %q, but got %s", test.input, actual)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Failed to parse %q with error %v", test.input, err)
+			continue
+		}
+
+		if actual.String() != test.expected {
+			t.Errorf("Expected %q to be parsed as %s, but got %s", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestGetPlatformVersion(t *testing.T) {
+	entries := []map[string]*string{
+		{
+			"PlatformName":    aws.String("Ubuntu"),
+			"PlatformVersion": aws.String("18.04"),
+		},
+	}
+
+	actual, err := getPlatformVersion(entries)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.String() != "18.4.0" {
+		t.Errorf("Expected 18.4.0, but got %s", actual)
+	}
+
+	if _, err := getPlatformVersion(nil); err == nil {
+		t.Error("Expected an error when the inventory has no entries.")
+	}
+}
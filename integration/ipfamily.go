@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// IPFamily determines whether instances are probed on their IPv4 or IPv6 address.
+type IPFamily string
+
+const (
+	// IPFamilyAuto probes the IPv4 address of instances, or their IPv6 address if they're IPv6-only.
+	IPFamilyAuto IPFamily = "auto"
+	// IPFamilyIPv4 only probes the IPv4 address of instances.
+	IPFamilyIPv4 IPFamily = "ipv4"
+	// IPFamilyIPv6 only probes the IPv6 address of instances.
+	IPFamilyIPv6 IPFamily = "ipv6"
+)
+
+// ParseIPFamily validates the name of an IP family.
+func ParseIPFamily(s string) (IPFamily, error) {
+	switch IPFamily(s) {
+	case IPFamilyAuto, IPFamilyIPv4, IPFamilyIPv6:
+		return IPFamily(s), nil
+	}
+	return "", fmt.Errorf("unknown IP family %q, expected %q, %q or %q", s, IPFamilyAuto, IPFamilyIPv4, IPFamilyIPv6)
+}
+
+// ipv6Address returns the IPv6 address of the instance, or of its first network interface with one.
+func ipv6Address(instance *ec2.Instance) string {
+	if ip := aws.StringValue(instance.Ipv6Address); ip != "" {
+		return ip
+	}
+
+	for _, ni := range instance.NetworkInterfaces {
+		for _, address := range ni.Ipv6Addresses {
+			if ip := aws.StringValue(address.Ipv6Address); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	return ""
+}
+
+// probeURL returns the URL of the endpoint on the host, where IPv6 hosts are enclosed in brackets, e.g.
+// http://[2001:db8::1]:8080/version.
+func probeURL(scheme string, host string, port int, endpoint string) string {
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + endpoint
+}
@@ -0,0 +1,38 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// getJSONPath extracts the string at the path, e.g. ".app.version", from a JSON document.
+func getJSONPath(body string, path string) (string, error) {
+	var document interface{}
+
+	if err := json.Unmarshal([]byte(body), &document); err != nil {
+		return "", fmt.Errorf("Failed to parse the response as JSON with error %-v", err)
+	}
+
+	current := document
+
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		object, ok := current.(map[string]interface{})
+
+		if !ok {
+			return "", fmt.Errorf("Failed to find %s in the response, %q is not within an object", path, key)
+		}
+
+		if current, ok = object[key]; !ok {
+			return "", fmt.Errorf("Failed to find %s in the response, %q is missing", path, key)
+		}
+	}
+
+	value, ok := current.(string)
+
+	if !ok {
+		return "", fmt.Errorf("Failed to find %s in the response, the value %v is not a string", path, current)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,76 @@
+package integration
+
+import "testing"
+
+func TestGetJSONPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		path        string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Top level field.",
+			body:     `{"version":"1.2.3","commit":"abc"}`,
+			path:     ".version",
+			expected: "1.2.3",
+		},
+		{
+			name:     "Nested field.",
+			body:     `{"app":{"version":"v2.0.1"}}`,
+			path:     ".app.version",
+			expected: "v2.0.1",
+		},
+		{
+			name:     "The leading dot is optional.",
+			body:     `{"app":{"version":"2.0.1"}}`,
+			path:     "app.version",
+			expected: "2.0.1",
+		},
+		{
+			name:        "Missing field.",
+			body:        `{"commit":"abc"}`,
+			path:        ".version",
+			expectError: true,
+		},
+		{
+			name:        "Path through a non-object.",
+			body:        `{"app":"2.0.1"}`,
+			path:        ".app.version",
+			expectError: true,
+		},
+		{
+			name:        "Value isn't a string.",
+			body:        `{"version":{"major":1}}`,
+			path:        ".version",
+			expectError: true,
+		},
+		{
+			name:        "Body isn't JSON.",
+			body:        `1.2.3`,
+			path:        ".version",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := getJSONPath(test.body, test.path)
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("For test \"%s\", expected an error, but got %q", test.name, actual)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("For test \"%s\", unexpected error %v", test.name, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("For test \"%s\", expected %q, but got %q", test.name, test.expected, actual)
+		}
+	}
+}
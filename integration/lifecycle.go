@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// terminatingTransition is the lifecycle transition of hooks which run when an instance is terminated.
+const terminatingTransition = "autoscaling:EC2_INSTANCE_TERMINATING"
+
+// lifecycleActionContinue lets the termination of the instance continue.
+const lifecycleActionContinue = "CONTINUE"
+
+// CompleteLifecycle completes the termination lifecycle hooks of the group for the instance, so that it doesn't
+// wait in the Terminating:Wait state until the hooks time out.
+func (p *AWSProvider) CompleteLifecycle(ctx context.Context, groupName string, instanceID string) error {
+	out, err := p.autoScaling.DescribeLifecycleHooksWithContext(ctx, &autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: aws.String(groupName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe the lifecycle hooks of group %s, %v", groupName, err)
+	}
+
+	var failures []string
+	for _, hook := range out.LifecycleHooks {
+		if aws.StringValue(hook.LifecycleTransition) != terminatingTransition {
+			continue
+		}
+
+		slog.Info("completing lifecycle action", "group", groupName, "instance", instanceID, "hook", aws.StringValue(hook.LifecycleHookName))
+
+		params := &autoscaling.CompleteLifecycleActionInput{
+			AutoScalingGroupName:  aws.String(groupName),
+			LifecycleHookName:     hook.LifecycleHookName,
+			InstanceId:            aws.String(instanceID),
+			LifecycleActionResult: aws.String(lifecycleActionContinue),
+		}
+
+		if _, err := p.autoScaling.CompleteLifecycleActionWithContext(ctx, params); err != nil {
+			failures = append(failures, fmt.Sprintf("%s failed, %v", aws.StringValue(hook.LifecycleHookName), err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to complete the lifecycle actions of instance %s: %s", instanceID, strings.Join(failures, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+)
+
+// mockLifecycle tracks the lifecycle state of instances, moving them out of Terminating:Wait once every
+// termination hook has been completed.
+type mockLifecycle struct {
+	autoscalingiface.AutoScalingAPI
+	hooks     []*autoscaling.LifecycleHook
+	states    map[string]string
+	completed map[string][]string
+	fail      map[string]bool
+}
+
+func (m *mockLifecycle) DescribeLifecycleHooksWithContext(ctx aws.Context, input *autoscaling.DescribeLifecycleHooksInput, opts ...request.Option) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+	return &autoscaling.DescribeLifecycleHooksOutput{LifecycleHooks: m.hooks}, nil
+}
+
+func (m *mockLifecycle) CompleteLifecycleActionWithContext(ctx aws.Context, input *autoscaling.CompleteLifecycleActionInput, opts ...request.Option) (*autoscaling.CompleteLifecycleActionOutput, error) {
+	id, hook := aws.StringValue(input.InstanceId), aws.StringValue(input.LifecycleHookName)
+	if m.states[id] != "Terminating:Wait" {
+		return nil, errors.New("no active lifecycle action found")
+	}
+	if m.fail[hook] {
+		return nil, errors.New("access denied")
+	}
+	if aws.StringValue(input.LifecycleActionResult) != "CONTINUE" {
+		return nil, errors.New("unexpected result " + aws.StringValue(input.LifecycleActionResult))
+	}
+
+	m.completed[id] = append(m.completed[id], hook)
+	if len(m.completed[id]) == m.terminatingHooks() {
+		m.states[id] = "Terminating:Proceed"
+	}
+	return &autoscaling.CompleteLifecycleActionOutput{}, nil
+}
+
+func (m *mockLifecycle) terminatingHooks() (count int) {
+	for _, h := range m.hooks {
+		if aws.StringValue(h.LifecycleTransition) == terminatingTransition {
+			count++
+		}
+	}
+	return count
+}
+
+func newMockLifecycle(states map[string]string) *mockLifecycle {
+	return &mockLifecycle{
+		hooks: []*autoscaling.LifecycleHook{
+			{LifecycleHookName: aws.String("launch"), LifecycleTransition: aws.String("autoscaling:EC2_INSTANCE_LAUNCHING")},
+			{LifecycleHookName: aws.String("drain"), LifecycleTransition: aws.String(terminatingTransition)},
+			{LifecycleHookName: aws.String("backup"), LifecycleTransition: aws.String(terminatingTransition)},
+		},
+		states:    states,
+		completed: map[string][]string{},
+		fail:      map[string]bool{},
+	}
+}
+
+func TestCompleteLifecycle(t *testing.T) {
+	m := newMockLifecycle(map[string]string{"i-1": "Terminating:Wait"})
+	p := &AWSProvider{autoScaling: m}
+
+	if err := p.CompleteLifecycle(context.Background(), "asg_api", "i-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if completed := strings.Join(m.completed["i-1"], ","); completed != "drain,backup" {
+		t.Errorf("Expected only the termination hooks to be completed, but got %s", completed)
+	}
+
+	if m.states["i-1"] != "Terminating:Proceed" {
+		t.Errorf("Expected the instance to proceed with termination, but it's %s", m.states["i-1"])
+	}
+}
+
+func TestCompleteLifecycleFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         string
+		failingHook   string
+		expectedState string
+		expectedError string
+	}{
+		{
+			name:          "instances which aren't waiting can't be completed",
+			state:         "InService",
+			expectedState: "InService",
+			expectedError: "no active lifecycle action found",
+		},
+		{
+			name:          "the remaining hooks are completed when a hook fails",
+			state:         "Terminating:Wait",
+			failingHook:   "drain",
+			expectedState: "Terminating:Wait",
+			expectedError: "drain failed, access denied",
+		},
+	}
+
+	for _, test := range tests {
+		m := newMockLifecycle(map[string]string{"i-1": test.state})
+		m.fail[test.failingHook] = true
+		p := &AWSProvider{autoScaling: m}
+
+		err := p.CompleteLifecycle(context.Background(), "asg_api", "i-1")
+
+		if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf("For test \"%s\", expected error containing %q, but got %v", test.name, test.expectedError, err)
+		}
+
+		if m.states["i-1"] != test.expectedState {
+			t.Errorf("For test \"%s\", expected state %s, but got %s", test.name, test.expectedState, m.states["i-1"])
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// maxMetricDataBatchSize is the maximum number of metric data points accepted by a PutMetricData call.
+const maxMetricDataBatchSize = 1000
+
+// GroupMetrics are the metrics published for an auto-scaling group after a run.
+type GroupMetrics struct {
+	Group string
+	// Evaluated is the number of instances whose version was read.
+	Evaluated int
+	// Mismatched is the number of instances which weren't running the expected version.
+	Mismatched int
+	// Terminated is the number of instances which were terminated.
+	Terminated int
+}
+
+// PutMetrics publishes the InstancesEvaluated, MismatchedInstances and InstancesTerminated metrics of each
+// group to CloudWatch, with an AutoScalingGroupName dimension.
+func (p *AWSProvider) PutMetrics(ctx context.Context, namespace string, metrics []GroupMetrics) error {
+	var data []*cloudwatch.MetricDatum
+
+	for _, m := range metrics {
+		dimensions := []*cloudwatch.Dimension{
+			{Name: aws.String("AutoScalingGroupName"), Value: aws.String(m.Group)},
+		}
+
+		data = append(data,
+			newCountDatum("InstancesEvaluated", dimensions, m.Evaluated),
+			newCountDatum("MismatchedInstances", dimensions, m.Mismatched),
+			newCountDatum("InstancesTerminated", dimensions, m.Terminated))
+	}
+
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxMetricDataBatchSize {
+			n = maxMetricDataBatchSize
+		}
+
+		_, err := p.cloudWatch.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(namespace),
+			MetricData: data[:n],
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to put metrics in namespace %s, %v", namespace, err)
+		}
+
+		data = data[n:]
+	}
+
+	return nil
+}
+
+func newCountDatum(name string, dimensions []*cloudwatch.Dimension, value int) *cloudwatch.MetricDatum {
+	return &cloudwatch.MetricDatum{
+		MetricName: aws.String(name),
+		Dimensions: dimensions,
+		Unit:       aws.String(cloudwatch.StandardUnitCount),
+		Value:      aws.Float64(float64(value)),
+	}
+}
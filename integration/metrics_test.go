@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+type mockCloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (m *mockCloudWatch) PutMetricDataWithContext(ctx aws.Context, input *cloudwatch.PutMetricDataInput, opts ...request.Option) (*cloudwatch.PutMetricDataOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestPutMetrics(t *testing.T) {
+	m := &mockCloudWatch{}
+	p := &AWSProvider{cloudWatch: m}
+
+	err := p.PutMetrics(context.Background(), "Terminator", []GroupMetrics{
+		{Group: "asg_api", Evaluated: 3, Mismatched: 2, Terminated: 1},
+		{Group: "asg_web", Evaluated: 4, Mismatched: 0, Terminated: 0},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.inputs) != 1 {
+		t.Fatalf("Expected a single call, but got %d", len(m.inputs))
+	}
+
+	if aws.StringValue(m.inputs[0].Namespace) != "Terminator" {
+		t.Errorf("Expected the Terminator namespace, but got %q", aws.StringValue(m.inputs[0].Namespace))
+	}
+
+	type point struct {
+		group string
+		name  string
+		value float64
+	}
+
+	var actual []point
+	for _, d := range m.inputs[0].MetricData {
+		if len(d.Dimensions) != 1 || aws.StringValue(d.Dimensions[0].Name) != "AutoScalingGroupName" {
+			t.Errorf("Expected an AutoScalingGroupName dimension, but got %v", d.Dimensions)
+			continue
+		}
+		if aws.StringValue(d.Unit) != cloudwatch.StandardUnitCount {
+			t.Errorf("Expected a count, but got %q", aws.StringValue(d.Unit))
+		}
+		actual = append(actual, point{
+			group: aws.StringValue(d.Dimensions[0].Value),
+			name:  aws.StringValue(d.MetricName),
+			value: aws.Float64Value(d.Value),
+		})
+	}
+
+	expected := []point{
+		{"asg_api", "InstancesEvaluated", 3},
+		{"asg_api", "MismatchedInstances", 2},
+		{"asg_api", "InstancesTerminated", 1},
+		{"asg_web", "InstancesEvaluated", 4},
+		{"asg_web", "MismatchedInstances", 0},
+		{"asg_web", "InstancesTerminated", 0},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,46 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// Notify publishes a summary of the instances terminated from each group to the SNS topic.
+func (p *AWSProvider) Notify(ctx context.Context, topicARN string, terminated map[string][]string) error {
+	subject, message := summarise(terminated)
+
+	_, err := p.sns.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn: aws.String(topicARN),
+		Subject:  aws.String(subject),
+		Message:  aws.String(message),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to publish to SNS topic %s, %v", topicARN, err)
+	}
+
+	return nil
+}
+
+// summarise returns a subject and message listing the instances terminated from each group.
+func summarise(terminated map[string][]string) (subject string, message string) {
+	groups := make([]string, 0, len(terminated))
+	total := 0
+	for g, ids := range terminated {
+		groups = append(groups, g)
+		total += len(ids)
+	}
+	sort.Strings(groups)
+
+	var sb strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&sb, "%s: %s\n", g, strings.Join(terminated[g], ", "))
+	}
+
+	return fmt.Sprintf("Terminator terminated %d instances", total), sb.String()
+}
@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+type mockSNS struct {
+	snsiface.SNSAPI
+	published []*sns.PublishInput
+}
+
+func (m *mockSNS) PublishWithContext(ctx aws.Context, input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+	m.published = append(m.published, input)
+	return &sns.PublishOutput{MessageId: aws.String("1")}, nil
+}
+
+func TestNotifyPublishesTheTerminatedInstances(t *testing.T) {
+	m := &mockSNS{}
+	p := &AWSProvider{sns: m}
+
+	err := p.Notify(context.Background(), "arn:aws:sns:eu-west-1:123456789012:terminator", map[string][]string{
+		"asg_web": {"i-3"},
+		"asg_api": {"i-1", "i-2"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.published) != 1 {
+		t.Fatalf("Expected a single message to be published, but got %d", len(m.published))
+	}
+
+	input := m.published[0]
+
+	if aws.StringValue(input.TopicArn) != "arn:aws:sns:eu-west-1:123456789012:terminator" {
+		t.Errorf("Expected the message to be published to the topic, but got %q", aws.StringValue(input.TopicArn))
+	}
+
+	if expected := "Terminator terminated 3 instances"; aws.StringValue(input.Subject) != expected {
+		t.Errorf("Expected the subject %q, but got %q", expected, aws.StringValue(input.Subject))
+	}
+
+	expected := "asg_api: i-1, i-2\nasg_web: i-3\n"
+	if actual := aws.StringValue(input.Message); actual != expected {
+		t.Errorf("Expected the message %q, but got %q", expected, actual)
+	}
+
+	for _, id := range []string{"i-1", "i-2", "i-3"} {
+		if !strings.Contains(aws.StringValue(input.Message), id) {
+			t.Errorf("Expected the message to contain %s", id)
+		}
+	}
+}
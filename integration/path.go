@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// pathData is the data available to a templated version endpoint path.
+type pathData struct {
+	InstanceID string
+	PrivateIP  string
+}
+
+// renderPath renders the path of the version endpoint for an instance, e.g. "/instances/{{.InstanceID}}/version".
+// Paths which aren't templates are returned unchanged.
+func renderPath(path string, instanceID string, privateIP string) (string, error) {
+	if !strings.Contains(path, "{{") {
+		return path, nil
+	}
+
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(path)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid path template %q, %v", path, err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, pathData{InstanceID: instanceID, PrivateIP: privateIP}); err != nil {
+		return "", fmt.Errorf("failed to render path template %q, %v", path, err)
+	}
+
+	return rendered.String(), nil
+}
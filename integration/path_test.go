@@ -0,0 +1,58 @@
+package integration
+
+import "testing"
+
+func TestRenderPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "plain paths are unchanged",
+			path:     "/version/",
+			expected: "/version/",
+		},
+		{
+			name:     "the instance ID is rendered",
+			path:     "/instances/{{.InstanceID}}/version",
+			expected: "/instances/i-1/version",
+		},
+		{
+			name:     "the private IP is rendered",
+			path:     "/version?host={{.PrivateIP}}",
+			expected: "/version?host=10.0.0.1",
+		},
+		{
+			name:        "unknown fields are rejected",
+			path:        "/instances/{{.Name}}/version",
+			expectError: true,
+		},
+		{
+			name:        "invalid templates are rejected",
+			path:        "/instances/{{.InstanceID/version",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := renderPath(test.path, "i-1", "10.0.0.1")
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("For test \"%s\", expected an error, but got %q", test.name, actual)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("For test \"%s\", unexpected error %v", test.name, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("For test \"%s\", expected %q, but got %q", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// ProcessLaunch is the auto-scaling process which launches instances to replace the ones which were terminated.
+const ProcessLaunch = "Launch"
+
+// SuspendProcesses stops the auto-scaling group running the processes, e.g. ProcessLaunch, until they're resumed.
+func (p *AWSProvider) SuspendProcesses(ctx context.Context, groupName string, processes []string) error {
+	_, err := p.autoScaling.SuspendProcessesWithContext(ctx, &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: aws.String(groupName),
+		ScalingProcesses:     aws.StringSlice(processes),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to suspend the %v processes of group %s, %v", processes, groupName, err)
+	}
+
+	return nil
+}
+
+// ResumeProcesses lets the auto-scaling group run the processes which were suspended.
+func (p *AWSProvider) ResumeProcesses(ctx context.Context, groupName string, processes []string) error {
+	_, err := p.autoScaling.ResumeProcessesWithContext(ctx, &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: aws.String(groupName),
+		ScalingProcesses:     aws.StringSlice(processes),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to resume the %v processes of group %s, %v", processes, groupName, err)
+	}
+
+	return nil
+}
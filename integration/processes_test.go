@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+)
+
+type mockProcesses struct {
+	autoscalingiface.AutoScalingAPI
+	calls     []string
+	resumeErr error
+}
+
+func (m *mockProcesses) SuspendProcessesWithContext(ctx aws.Context, input *autoscaling.ScalingProcessQuery, opts ...request.Option) (*autoscaling.SuspendProcessesOutput, error) {
+	m.calls = append(m.calls, "suspend "+aws.StringValue(input.AutoScalingGroupName)+" "+strings.Join(aws.StringValueSlice(input.ScalingProcesses), ","))
+	return &autoscaling.SuspendProcessesOutput{}, nil
+}
+
+func (m *mockProcesses) ResumeProcessesWithContext(ctx aws.Context, input *autoscaling.ScalingProcessQuery, opts ...request.Option) (*autoscaling.ResumeProcessesOutput, error) {
+	m.calls = append(m.calls, "resume "+aws.StringValue(input.AutoScalingGroupName)+" "+strings.Join(aws.StringValueSlice(input.ScalingProcesses), ","))
+	return &autoscaling.ResumeProcessesOutput{}, m.resumeErr
+}
+
+func TestSuspendingAndResumingProcesses(t *testing.T) {
+	m := &mockProcesses{}
+	p := &AWSProvider{autoScaling: m}
+
+	if err := p.SuspendProcesses(context.Background(), "asg_api", []string{ProcessLaunch}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ResumeProcesses(context.Background(), "asg_api", []string{ProcessLaunch}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.calls) != 2 || m.calls[0] != "suspend asg_api Launch" || m.calls[1] != "resume asg_api Launch" {
+		t.Errorf("Expected the Launch process to be suspended and resumed, but got %v", m.calls)
+	}
+}
+
+func TestResumingProcessesReportsTheGroup(t *testing.T) {
+	p := &AWSProvider{autoScaling: &mockProcesses{resumeErr: errors.New("AccessDenied")}}
+
+	err := p.ResumeProcesses(context.Background(), "asg_api", []string{ProcessLaunch})
+
+	if err == nil || !strings.Contains(err.Error(), "asg_api") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Expected the error to describe the group, but got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ProbeTransport determines how version probes reach an instance.
+type ProbeTransport string
+
+const (
+	// ProbeTransportDirect connects to the version endpoint on the instance's IP address.
+	ProbeTransportDirect ProbeTransport = "direct"
+	// ProbeTransportSSH connects to the instance over SSH and probes the version endpoint on its localhost.
+	ProbeTransportSSH ProbeTransport = "ssh"
+)
+
+// ParseProbeTransport validates the name of a probe transport.
+func ParseProbeTransport(s string) (ProbeTransport, error) {
+	switch ProbeTransport(s) {
+	case ProbeTransportDirect, ProbeTransportSSH:
+		return ProbeTransport(s), nil
+	}
+	return "", fmt.Errorf("unknown probe transport %q, expected %q or %q", s, ProbeTransportDirect, ProbeTransportSSH)
+}
+
+// SSHConfig configures the SSH tunnel used when the probe transport is ProbeTransportSSH.
+type SSHConfig struct {
+	// Bastion is the host:port of the bastion to connect through, or empty to connect to instances directly.
+	Bastion string
+	// User is the user to connect to the bastion and instances as.
+	User string
+	// KeyFile is the path to the private key used to authenticate.
+	KeyFile string
+	// KnownHostsFile is the path to the known_hosts file used to verify the bastion and instances.
+	KnownHostsFile string
+}
+
+func (c SSHConfig) clientConfig() (*ssh.ClientConfig, error) {
+	key, err := ioutil.ReadFile(c.KeyFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key %s, %-v", c.KeyFile, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s, %-v", c.KeyFile, err)
+	}
+
+	hostKeyCallback, err := knownhosts.New(c.KnownHostsFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts %s, %-v", c.KnownHostsFile, err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// sshTunnel is an SSH connection to an instance, used to reach ports which are only bound to its localhost.
+type sshTunnel struct {
+	// clients holds the connection to the instance, followed by the connection to the bastion, if any.
+	clients []*ssh.Client
+}
+
+func openSSHTunnel(config SSHConfig, instanceIP string) (*sshTunnel, error) {
+	clientConfig, err := config.clientConfig()
+
+	if err != nil {
+		return nil, err
+	}
+
+	target := net.JoinHostPort(instanceIP, "22")
+
+	if config.Bastion == "" {
+		instance, err := ssh.Dial("tcp", target, clientConfig)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s, %-v", target, err)
+		}
+
+		return &sshTunnel{clients: []*ssh.Client{instance}}, nil
+	}
+
+	bastion, err := ssh.Dial("tcp", config.Bastion, clientConfig)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to bastion %s, %-v", config.Bastion, err)
+	}
+
+	conn, err := bastion.Dial("tcp", target)
+
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("failed to connect to %s through bastion %s, %-v", target, config.Bastion, err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, target, clientConfig)
+
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("failed to connect to %s through bastion %s, %-v", target, config.Bastion, err)
+	}
+
+	return &sshTunnel{clients: []*ssh.Client{ssh.NewClient(c, chans, reqs), bastion}}, nil
+}
+
+// Dial opens a connection from the instance, e.g. to "localhost:80".
+func (t *sshTunnel) Dial(network, address string) (net.Conn, error) {
+	return t.clients[0].Dial(network, address)
+}
+
+// Close closes the connections to the instance and bastion.
+func (t *sshTunnel) Close() error {
+	var err error
+
+	for _, c := range t.clients {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
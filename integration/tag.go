@@ -0,0 +1,40 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// Tag is a tag of an auto-scaling group, e.g. terminator:enabled=true.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// ParseTag parses a tag in the form key=value.
+func ParseTag(s string) (Tag, error) {
+	parts := strings.SplitN(s, "=", 2)
+
+	if len(parts) != 2 || parts[0] == "" {
+		return Tag{}, fmt.Errorf("invalid tag %q, expected key=value", s)
+	}
+
+	return Tag{Key: parts[0], Value: parts[1]}, nil
+}
+
+func (t Tag) String() string {
+	return t.Key + "=" + t.Value
+}
+
+// isOn returns true if the group has the tag.
+func (t Tag) isOn(g *autoscaling.Group) bool {
+	for _, tag := range g.Tags {
+		if aws.StringValue(tag.Key) == t.Key && aws.StringValue(tag.Value) == t.Value {
+			return true
+		}
+	}
+	return false
+}
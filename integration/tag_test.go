@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Tag
+		expectError bool
+	}{
+		{
+			name:     "A key and value are split on the first equals sign.",
+			input:    "terminator:enabled=true",
+			expected: Tag{Key: "terminator:enabled", Value: "true"},
+		},
+		{
+			name:     "The value can be empty.",
+			input:    "terminator:enabled=",
+			expected: Tag{Key: "terminator:enabled", Value: ""},
+		},
+		{
+			name:     "The value can contain equals signs.",
+			input:    "query=a=b",
+			expected: Tag{Key: "query", Value: "a=b"},
+		},
+		{
+			name:        "A tag without a value is invalid.",
+			input:       "terminator:enabled",
+			expectError: true,
+		},
+		{
+			name:        "A tag without a key is invalid.",
+			input:       "=true",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := ParseTag(test.input)
+
+		if test.expectError != (err != nil) {
+			t.Errorf("For test \"%s\", expected error %v, but got %v", test.name, test.expectError, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("For test \"%s\", expected %+v, but got %+v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestDescribeAutoScalingGroupsFiltersByTag(t *testing.T) {
+	server, requests := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	tag := func(g *autoscaling.Group, key string, value string) *autoscaling.Group {
+		g.Tags = append(g.Tags, &autoscaling.TagDescription{Key: aws.String(key), Value: aws.String(value)})
+		return g
+	}
+
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{
+		tag(newMockGroup("asg_api", "i-1"), "terminator:enabled", "true"),
+		tag(newMockGroup("asg_web", "i-2"), "terminator:enabled", "false"),
+		newMockGroup("bastion", "i-3"),
+		tag(tag(newMockGroup("asg_worker", "i-4"), "team", "platform"), "terminator:enabled", "true"),
+	})
+	p.SelectorTag = &Tag{Key: "terminator:enabled", Value: "true"}
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.Name
+	}
+
+	expected := []string{"asg_api", "asg_worker"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the tagged groups %v, but got %v", expected, names)
+	}
+
+	if *requests != 2 {
+		t.Errorf("Expected only the instances of tagged groups to be probed, but got %d requests", *requests)
+	}
+}
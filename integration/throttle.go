@@ -0,0 +1,39 @@
+package integration
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// The defaults of the retries of throttled AWS API calls.
+const (
+	defaultThrottleRetries = 5
+	defaultThrottleBackoff = time.Second
+)
+
+// withThrottlingRetries makes the AWS API call, retrying up to retries times when AWS throttles it, e.g. with
+// a Throttling or RequestLimitExceeded error. The delay between attempts starts at backoff and doubles after
+// each attempt. Other errors aren't retried.
+func withThrottlingRetries(ctx context.Context, retries int, backoff time.Duration, call func() error) (err error) {
+	delay := backoff
+
+	for attempt := 0; ; attempt++ {
+		err = call()
+
+		if err == nil || attempt >= retries || !request.IsErrorThrottle(err) {
+			return err
+		}
+
+		slog.Warn("the AWS API call was throttled, retrying", "attempt", attempt+1, "delay", delay, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+func newThrottlingError() error {
+	return awserr.New("Throttling", "Rate exceeded", nil)
+}
+
+func TestThrottledTerminationsAreRetried(t *testing.T) {
+	m := &mockEC2{terminateErr: map[int]error{1: newThrottlingError(), 2: awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)}}
+	p := &AWSProvider{ec2: m, ThrottleRetries: 3, ThrottleBackoff: time.Millisecond}
+
+	if err := p.TerminateInstances(context.Background(), []string{"i-1", "i-2"}); err != nil {
+		t.Errorf("Expected the termination to succeed once it wasn't throttled, but got %v", err)
+	}
+
+	if len(m.terminateCalls) != 3 {
+		t.Errorf("Expected 2 throttled calls and a successful call, but got %d calls", len(m.terminateCalls))
+	}
+}
+
+func TestThrottlingRetriesAreLimited(t *testing.T) {
+	calls := 0
+	err := withThrottlingRetries(context.Background(), 2, time.Millisecond, func() error {
+		calls++
+		return newThrottlingError()
+	})
+
+	if err == nil || calls != 3 {
+		t.Errorf("Expected the throttling error after 3 calls, but got %v after %d calls", err, calls)
+	}
+}
+
+func TestOtherErrorsAreNotRetried(t *testing.T) {
+	calls := 0
+	withThrottlingRetries(context.Background(), 2, time.Millisecond, func() error {
+		calls++
+		return errors.New("UnauthorizedOperation")
+	})
+
+	if calls != 1 {
+		t.Errorf("Expected errors other than throttling not to be retried, but got %d calls", calls)
+	}
+}
+
+// mockThrottledAutoScaling is throttled after returning the first page, until it has been called throttles times.
+type mockThrottledAutoScaling struct {
+	mockAutoScaling
+	throttles int
+	calls     int
+}
+
+func (m *mockThrottledAutoScaling) DescribeAutoScalingGroupsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool, opts ...request.Option) error {
+	m.calls++
+	if m.calls <= m.throttles {
+		fn(m.pages[0], false)
+		return newThrottlingError()
+	}
+	return m.mockAutoScaling.DescribeAutoScalingGroupsPagesWithContext(ctx, input, fn, opts...)
+}
+
+func TestThrottledDescriptionsAreRetried(t *testing.T) {
+	server, _ := newFlakyServer(0, http.StatusOK)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server,
+		[]*autoscaling.Group{newMockGroup("asg_api", "i-1")},
+		[]*autoscaling.Group{newMockGroup("asg_web", "i-2")})
+	m := &mockThrottledAutoScaling{mockAutoScaling: *p.autoScaling.(*mockAutoScaling), throttles: 2}
+	p.autoScaling = m
+	p.ThrottleRetries = 2
+	p.ThrottleBackoff = time.Millisecond
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.Name
+	}
+
+	if expected := []string{"asg_api", "asg_web"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the groups of the successful attempt %v, but got %v", expected, names)
+	}
+	if m.calls != 3 {
+		t.Errorf("Expected 2 throttled calls and a successful call, but got %d calls", m.calls)
+	}
+}
@@ -0,0 +1,96 @@
+package integration
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// ParseVersion parses the version returned by an instance, ignoring surrounding whitespace, quotes and a "v"
+// prefix. Versions which aren't valid semantic versions, but can be interpreted as one, e.g. "1.2" or "1.2.3.4",
+// are coerced, and the returned warnings describe how the version was interpreted.
+func ParseVersion(raw string) (version semver.Version, warnings []string, err error) {
+	s := strings.TrimPrefix(TrimVersion(raw), "v")
+
+	if version, err = semver.Make(s); err == nil {
+		return version, nil, nil
+	}
+
+	core, suffix := s, ""
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		core, suffix = s[:i], s[i:]
+	}
+
+	parts := strings.Split(core, ".")
+
+	if len(parts) > 3 {
+		warnings = append(warnings, fmt.Sprintf("%q has more than 3 components, %q was ignored", raw, strings.Join(parts[3:], ".")))
+		parts = parts[:3]
+	}
+
+	if len(parts) < 3 {
+		warnings = append(warnings, fmt.Sprintf("%q has fewer than 3 components, the missing components were set to 0", raw))
+		for len(parts) < 3 {
+			parts = append(parts, "0")
+		}
+	}
+
+	for i, part := range parts {
+		n, parseErr := strconv.ParseUint(part, 10, 64)
+
+		if parseErr != nil {
+			return semver.Version{}, nil, err
+		}
+
+		if normalised := strconv.FormatUint(n, 10); normalised != part {
+			warnings = append(warnings, fmt.Sprintf("%q has leading zeroes, %q was read as %s", raw, part, normalised))
+			parts[i] = normalised
+		}
+	}
+
+	if version, err = semver.Make(strings.Join(parts, ".") + suffix); err != nil {
+		return semver.Version{}, nil, err
+	}
+
+	return version, warnings, nil
+}
+
+// gitDescribeSuffix matches the suffix git describe adds to the tag, the number of commits since the tag
+// and the abbreviated commit hash, e.g. "-4-gdeadbee".
+var gitDescribeSuffix = regexp.MustCompile(`-?\d+-g[0-9a-f]{4,40}$`)
+
+// NormalizeVersion removes the build metadata and any git describe suffix from the version, so that
+// "1.2.3-4-gdeadbee" and "1.2.3+build" both become "1.2.3".
+func NormalizeVersion(v semver.Version) semver.Version {
+	v.Build = nil
+
+	if len(v.Pre) == 0 {
+		return v
+	}
+
+	pre := make([]string, len(v.Pre))
+	for i, p := range v.Pre {
+		pre[i] = p.String()
+	}
+	joined := strings.Join(pre, ".")
+
+	trimmed := gitDescribeSuffix.ReplaceAllString(joined, "")
+	if trimmed == joined {
+		return v
+	}
+
+	v.Pre = nil
+	if trimmed == "" {
+		return v
+	}
+
+	normalized, err := semver.Make(v.String() + "-" + trimmed)
+	if err != nil {
+		return v
+	}
+
+	return normalized
+}
@@ -0,0 +1,76 @@
+package integration
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		raw              string
+		expected         string
+		expectedWarnings int
+		expectError      bool
+	}{
+		{raw: "1.2.3", expected: "1.2.3"},
+		{raw: "\"v1.2.3\"\n", expected: "1.2.3"},
+		{raw: "0.0.3-0-g03d102e", expected: "0.0.3-0-g03d102e"},
+		{raw: "1.2", expected: "1.2.0", expectedWarnings: 1},
+		{raw: "v2", expected: "2.0.0", expectedWarnings: 1},
+		{raw: "1.2.3.4", expected: "1.2.3", expectedWarnings: 1},
+		{raw: "1.2.3.4-beta", expected: "1.2.3-beta", expectedWarnings: 1},
+		{raw: "01.2.03", expected: "1.2.3", expectedWarnings: 2},
+		{raw: "1.2-rc1", expected: "1.2.0-rc1", expectedWarnings: 1},
+		{raw: "", expectError: true},
+		{raw: "latest", expectError: true},
+		{raw: "1.x.3", expectError: true},
+	}
+
+	for _, test := range tests {
+		actual, warnings, err := ParseVersion(test.raw)
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Expected an error parsing %q, but got %s", test.raw, actual)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Failed to parse %q with error %v", test.raw, err)
+			continue
+		}
+
+		if actual.String() != test.expected {
+			t.Errorf("Expected %q to be parsed as %s, but got %s", test.raw, test.expected, actual)
+		}
+
+		if len(warnings) != test.expectedWarnings {
+			t.Errorf("Expected %d warnings parsing %q, but got %v", test.expectedWarnings, test.raw, warnings)
+		}
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{raw: "v1.2.3-4-gdeadbee", expected: "1.2.3"},
+		{raw: "1.2.3+build", expected: "1.2.3"},
+		{raw: "1.2.3", expected: "1.2.3"},
+		{raw: "0.0.3-0-g03d102e", expected: "0.0.3"},
+		{raw: "1.2.3-rc.1-4-gdeadbee+build.5", expected: "1.2.3-rc.1"},
+		{raw: "1.2.3-beta", expected: "1.2.3-beta"},
+	}
+
+	for _, test := range tests {
+		parsed, _, err := ParseVersion(test.raw)
+
+		if err != nil {
+			t.Errorf("Failed to parse %q with error %v", test.raw, err)
+			continue
+		}
+
+		if actual := NormalizeVersion(parsed); actual.String() != test.expected {
+			t.Errorf("For %q, expected %s, but got %s", test.raw, test.expected, actual)
+		}
+	}
+}
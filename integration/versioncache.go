@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"sync"
+	"time"
+)
+
+// versionCacheKey identifies a probe of an instance. The launch time is part of the key, so that an instance
+// which was stopped and started again is probed again.
+type versionCacheKey struct {
+	instanceID string
+	launchTime int64
+	url        string
+}
+
+type versionCacheEntry struct {
+	detail  InstanceDetail
+	expires time.Time
+}
+
+// versionCache remembers the details reported by instances, so that they aren't probed again on each run. The
+// zero value is an empty cache, and it's safe for concurrent use.
+type versionCache struct {
+	m       sync.Mutex
+	entries map[versionCacheKey]versionCacheEntry
+}
+
+func newVersionCacheKey(instanceID string, launchTime time.Time, url string) versionCacheKey {
+	return versionCacheKey{instanceID: instanceID, launchTime: launchTime.UnixNano(), url: url}
+}
+
+// get returns the cached detail, if it hasn't expired.
+func (c *versionCache) get(key versionCacheKey, now time.Time) (InstanceDetail, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return InstanceDetail{}, false
+	}
+
+	if !now.Before(e.expires) {
+		delete(c.entries, key)
+		return InstanceDetail{}, false
+	}
+
+	return e.detail, true
+}
+
+// put caches the detail until the ttl has passed. Expired entries are removed, so that the entries of instances
+// which were terminated, and so are never looked up again, don't build up.
+func (c *versionCache) put(key versionCacheKey, detail InstanceDetail, now time.Time, ttl time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[versionCacheKey]versionCacheEntry{}
+	}
+
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = versionCacheEntry{detail: detail, expires: now.Add(ttl)}
+}
@@ -0,0 +1,33 @@
+package integration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// getVersionMatch extracts the version from the body of a response using the first capture group of the
+// pattern, e.g. `Version: (\S+)` reads 1.4.2 from "Version: 1.4.2 (build 99)".
+func getVersionMatch(body string, pattern *regexp.Regexp) (string, error) {
+	match := pattern.FindStringSubmatch(body)
+
+	if match == nil || len(match) < 2 {
+		return "", fmt.Errorf("Failed to find a version matching %s in the response %q", pattern, truncate(body, 100))
+	}
+
+	version := strings.TrimSpace(match[1])
+
+	if version == "" {
+		return "", fmt.Errorf("Failed to find a version matching %s in the response, the capture group is empty", pattern)
+	}
+
+	return version, nil
+}
+
+// truncate shortens s to at most n bytes, so that large responses aren't written to the logs.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGetVersionMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		pattern     string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "Prefix and build suffix.",
+			body:     "Version: 1.4.2 (build 99)",
+			pattern:  `Version: (\S+)`,
+			expected: "1.4.2",
+		},
+		{
+			name:     "HTML page.",
+			body:     "<html>\n<body>\n<footer><span class=\"version\">v2.0.1</span></footer>\n</body>\n</html>",
+			pattern:  `<span class="version">v?([^<]+)</span>`,
+			expected: "2.0.1",
+		},
+		{
+			name:     "Multiline output with several numbers.",
+			body:     "uptime: 3600\ncommit: 3f2a1b\napp 1.10.0-rc.1 built 2024-03-15\n",
+			pattern:  `(?m)^app (\S+)`,
+			expected: "1.10.0-rc.1",
+		},
+		{
+			name:     "Whitespace around the match is trimmed.",
+			body:     "version=  1.2.3  ;",
+			pattern:  `version=([^;]+);`,
+			expected: "1.2.3",
+		},
+		{
+			name:        "No match.",
+			body:        "Service Unavailable",
+			pattern:     `Version: (\S+)`,
+			expectError: true,
+		},
+		{
+			name:        "Empty capture group.",
+			body:        "Version: ",
+			pattern:     `Version: (\S*)`,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := getVersionMatch(test.body, regexp.MustCompile(test.pattern))
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error, but got %q", test.name, actual)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("%s: expected %q, but got %q", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package integration
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// getTagDetail returns information about the instance, reading its version from the VersionTagKey tag instead of
+// probing it, e.g. for instances which don't serve a version endpoint.
+func (p *AWSProvider) getTagDetail(instance *ec2.Instance, instanceID string) (*InstanceDetail, error) {
+	for _, tag := range instance.Tags {
+		if aws.StringValue(tag.Key) != p.VersionTagKey {
+			continue
+		}
+
+		detail, err := newInstanceDetail(instanceID, aws.StringValue(tag.Value), aws.TimeValue(instance.LaunchTime), p.VersionScheme, p.NormalizeVersions)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &detail, nil
+	}
+
+	return nil, fmt.Errorf("instance %s doesn't have the %s tag", instanceID, p.VersionTagKey)
+}
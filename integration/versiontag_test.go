@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestVersionsAreReadFromTheTagWithoutProbing(t *testing.T) {
+	server, requests := newFlakyServer(0, 200)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server, []*autoscaling.Group{newMockGroup("asg_api", "i-1", "i-2")})
+	p.ec2 = &mockEC2{tags: []*ec2.Tag{
+		{Key: aws.String("Name"), Value: aws.String("api")},
+		{Key: aws.String("app:version"), Value: aws.String("2.4.1")},
+	}}
+	p.VersionSource = VersionSourceTag
+	p.VersionTagKey = "app:version"
+
+	groups, err := p.DescribeAutoScalingGroups(context.Background(), nil, "http", port, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 || len(groups[0].InstanceDetails) != 2 {
+		t.Fatalf("Expected the details of both instances, but got %+v", groups)
+	}
+
+	for _, d := range groups[0].InstanceDetails {
+		if d.Version() != "2.4.1" {
+			t.Errorf("Expected the version of %s to be read from the tag, but got %s", d.ID, d.Version())
+		}
+	}
+
+	if *requests != 0 {
+		t.Errorf("Expected no HTTP probes, but got %d", *requests)
+	}
+}
+
+func TestInstancesWithoutTheVersionTagHaveNoDetail(t *testing.T) {
+	server, requests := newFlakyServer(0, 200)
+	defer server.Close()
+
+	p, port := newMockAWSProvider(server)
+	p.VersionSource = VersionSourceTag
+	p.VersionTagKey = "app:version"
+
+	_, err := p.GetDetail(context.Background(), "i-1", "http", port, "/version")
+
+	if err == nil || !strings.Contains(err.Error(), "doesn't have the app:version tag") {
+		t.Errorf("Expected an error describing the missing tag, but got %v", err)
+	}
+
+	if *requests != 0 {
+		t.Errorf("Expected no HTTP probes, but got %d", *requests)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/a-h/terminator/terminate"
+)
+
+// lambdaEvent is the payload of a scheduled invocation, e.g. from EventBridge. Fields which are present
+// override the defaults of the command-line flags, e.g. {"autoScalingGroups":["asg_api"],"canonical":"1.2.0"}
+type lambdaEvent struct {
+	remoteConfig
+	AutoScalingGroups []string `json:"autoScalingGroups"`
+	MaxTerminations   *int     `json:"maxTerminations"`
+}
+
+// lambdaResult summarises the instances terminated by an invocation.
+type lambdaResult struct {
+	DryRun     bool          `json:"dryRun"`
+	Terminated []string      `json:"terminated"`
+	Groups     []lambdaGroup `json:"groups"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type lambdaGroup struct {
+	Name    string   `json:"name"`
+	Outcome string   `json:"outcome"`
+	Message string   `json:"message"`
+	Targets []string `json:"targets,omitempty"`
+}
+
+// handleLambda terminates instances according to the event, starting from the defaults of the command-line
+// flags, and the region of the Lambda function from the environment.
+func handleLambda(ctx context.Context, event lambdaEvent) (lambdaResult, error) {
+	p, _, ok := configure(nil)
+
+	if !ok {
+		return lambdaResult{}, fmt.Errorf("invalid default configuration")
+	}
+
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		p.region = region
+	}
+
+	p, err := event.apply(p)
+
+	if err != nil {
+		return lambdaResult{}, fmt.Errorf("invalid event, %v", err)
+	}
+
+	cloud, err := newCloudProvider(p)
+
+	if err != nil {
+		return lambdaResult{}, fmt.Errorf("failed to create an AWS session, %v", err)
+	}
+
+	p.Report = terminate.NewReport(p.IsDryRun)
+
+	run, err := terminate.Run(ctx, cloud, p.Options)
+
+	result := lambdaResult{
+		DryRun:     p.IsDryRun,
+		Terminated: run.Terminated,
+		Groups:     []lambdaGroup{},
+	}
+	for _, g := range p.Report.Groups() {
+		result.Groups = append(result.Groups, lambdaGroup{Name: g.Name, Outcome: g.Outcome, Message: g.Message, Targets: g.Targets})
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	slog.Info("lambda invocation complete", "dryRun", result.DryRun, "terminated", result.Terminated, "error", result.Error)
+
+	// Reaching the maximum number of terminations stops the run safely, so it doesn't fail the invocation.
+	if err != nil && err != terminate.ErrMaxTerminations {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// apply overrides the parameters with the fields which are present in the event.
+func (e lambdaEvent) apply(p parameters) (parameters, error) {
+	p, err := e.remoteConfig.apply(p)
+
+	if err != nil {
+		return p, err
+	}
+
+	if len(e.AutoScalingGroups) > 0 {
+		p.AutoScalingGroups = e.AutoScalingGroups
+	}
+
+	if e.MaxTerminations != nil {
+		if *e.MaxTerminations < unlimitedTerminations {
+			return p, fmt.Errorf("invalid maxTerminations %d, use %d for unlimited", *e.MaxTerminations, unlimitedTerminations)
+		}
+		p.MaxTerminations = maxTerminations(*e.MaxTerminations)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,12 @@
+//go:build lambda
+
+package main
+
+import "github.com/aws/aws-lambda-go/lambda"
+
+// Build with -tags lambda to run terminator as a Lambda function, e.g. on an EventBridge schedule.
+func init() {
+	start = func() {
+		lambda.Start(handleLambda)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate"
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestLambdaHandler(t *testing.T) {
+	defer func(n func(parameters) (integration.CloudProvider, error)) { newCloudProvider = n }(newCloudProvider)
+	t.Setenv("AWS_REGION", "europa-westmoreland-1")
+
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C"),
+		terminatetest.NewHealthyGroup("Group2", "1.1.0", "D", "E", "F"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.0.0", nil, time.Now(), nil)
+
+	var region string
+	newCloudProvider = func(p parameters) (integration.CloudProvider, error) {
+		region = p.region
+		return mp, nil
+	}
+
+	var event lambdaEvent
+	payload := `{"autoScalingGroups":["Group1","Group2"],"canonical":"1.1.0","isDryRun":false,"minimumInstanceCount":1,"maxTerminations":-1}`
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := handleLambda(context.Background(), event)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if region != "europa-westmoreland-1" {
+		t.Errorf("Expected the region to be read from the environment, but got %q", region)
+	}
+
+	if result.DryRun {
+		t.Error("Expected the event to disable the dry run")
+	}
+
+	sort.Strings(result.Terminated)
+	if expected := []string{"A", "B"}; !reflect.DeepEqual(result.Terminated, expected) || !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, result.Terminated)
+	}
+
+	if len(result.Groups) != 2 || result.Groups[0].Outcome != terminate.OutcomePassed || result.Groups[1].Outcome != terminate.OutcomePassed {
+		t.Errorf("Expected both groups to pass, but got %+v", result.Groups)
+	}
+
+	if _, err := json.Marshal(result); err != nil {
+		t.Errorf("Expected the result to be serialisable, but got %v", err)
+	}
+}
+
+func TestLambdaHandlerRejectsInvalidEvents(t *testing.T) {
+	defer func(n func(parameters) (integration.CloudProvider, error)) { newCloudProvider = n }(newCloudProvider)
+	newCloudProvider = func(p parameters) (integration.CloudProvider, error) {
+		t.Error("Expected no provider to be created for an invalid event")
+		return nil, nil
+	}
+
+	mode := "random"
+	if _, err := handleLambda(context.Background(), lambdaEvent{remoteConfig: remoteConfig{Mode: &mode}}); err == nil {
+		t.Error("Expected an invalid mode to be rejected")
+	}
+}
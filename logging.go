@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// The log formats.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// newLogger creates a logger which writes messages at the level or above to w in the format.
+func newLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return nil, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+
+	switch format {
+	case logFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case logFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q, expected %q or %q", format, logFormatText, logFormatJSON)
+}
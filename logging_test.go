@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/a-h/terminator/terminate"
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestLogLevels(t *testing.T) {
+	defer func(l *slog.Logger) { slog.SetDefault(l) }(slog.Default())
+
+	tests := []struct {
+		name          string
+		level         string
+		expectTimings bool
+	}{
+		{
+			name:          "Timings are suppressed at info level.",
+			level:         "info",
+			expectTimings: false,
+		},
+		{
+			name:          "Timings are logged at debug level.",
+			level:         "debug",
+			expectTimings: true,
+		},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		logger, err := newLogger(buf, test.level, logFormatText)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slog.SetDefault(logger)
+
+		mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.0.0", "G": "1.0.0"}, nil)
+		terminate.Run(context.Background(), mp, terminate.Options{
+			MinimumInstanceCount: 1,
+			IsDryRun:             true,
+			Canonical:            "1.0.0",
+		})
+
+		output := buf.String()
+
+		if !strings.Contains(output, "Terminator activated") {
+			t.Errorf("For test \"%s\", expected info messages to be logged, but got %s", test.name, output)
+		}
+
+		if actual := strings.Contains(output, "time: "); actual != test.expectTimings {
+			t.Errorf("For test \"%s\", expected timings logged to be %v, but got %s", test.name, test.expectTimings, output)
+		}
+	}
+}
+
+func TestJSONLogFormat(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger, err := newLogger(buf, "warn", logFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("ignored")
+	logger.Warn("no action taken", "group", "Group1")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a single JSON log entry, but got %q, %v", buf.String(), err)
+	}
+
+	if entry["msg"] != "no action taken" || entry["group"] != "Group1" || entry["level"] != "WARN" {
+		t.Errorf("Unexpected log entry %v", entry)
+	}
+}
+
+func TestInvalidLoggingConfiguration(t *testing.T) {
+	if _, err := newLogger(new(bytes.Buffer), "verbose", logFormatText); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+
+	if _, err := newLogger(new(bytes.Buffer), "info", "xml"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -1,68 +1,1046 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate"
+	"github.com/blang/semver"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 var version string
 
+// The report formats.
+const (
+	reportFormatNone  = ""
+	reportFormatJUnit = "junit"
+)
+
+// The output formats.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// unlimitedTerminations is the value of the maxTerminations flag, and of the Lambda event, which disables the cap
+// on terminations.
+const unlimitedTerminations = -1
+
+// maxTerminations returns the cap on terminations of the maxTerminations value, nil when it's unlimited.
+func maxTerminations(n int) *int {
+	if n == unlimitedTerminations {
+		return nil
+	}
+	return &n
+}
+
 var regionFlag = flag.String("region", "eu-west-1", "Specifies the default region used.")
+var advisoryFlag = flag.Bool("advisory", false, "When set, warns about groups whose instances aren't all running the expected version, and exits with code 5 if there are any, but never terminates anything.")
 var isDryRunFlag = flag.Bool("isDryRun", true, "Specifies whether to do a dry run (test) of the termination. If this is specified, the termination will not occur.")
+var confirmFlag = flag.Bool("confirm", false, "When set, the instances to terminate in each group are printed, and the group is only terminated if yes is typed at the prompt.")
+var yesFlag = flag.Bool("yes", false, "When set, the confirm prompt is skipped, e.g. when run by automation.")
+var forceFlag = flag.Bool("force", false, "The same as yes.")
 var minimumInstanceCountFlag = flag.Int("minimumInstanceCount", 1, "Specifies the minimum number of instances to leave in the auto-scaling group.")
 var schemeFlag = flag.String("scheme", "http", "Chooses the scheme, e.g. http or https.")
 var portFlag = flag.Int("port", 80, "The TCP port to run communications over.")
-var versionURLFlag = flag.String("path", "/version/", "Specifies the URL path which will be connected to (after the private IP address of the instance. The expectation is a version number should be returned, e.g. 1.1.4")
+var versionURLFlag = flag.String("path", "/version/", "Specifies the URL path which will be connected to (after the private IP address of the instance. The expectation is a version number should be returned, e.g. 1.1.4. The path can include the {{.InstanceID}} and {{.PrivateIP}} of the instance, e.g. /instances/{{.InstanceID}}/version. A comma-separated list of paths, e.g. /version,/status, are tried in order until one returns a version.")
 var versionFlag = flag.Bool("version", false, "When set, just displays the version and quits.")
 
-var canonicalFlag = flag.String("canonical", "1.0.0", "The canonical version to check against when terminating instances.")
+var canonicalURLFlag = flag.String("canonicalURL", "", "When set, a URL, e.g. of an object published by the deployment pipeline, whose body is the canonical version. It's fetched at the start of each run instead of using canonical, and if it can't be fetched, the run is aborted.")
+var canonicalFlag = flag.String("canonical", "1.0.0", "The canonical version to check against when terminating instances. When set to auto, each group is evaluated independently, and instances running a lower version than the highest version of the healthy instances in the group are terminated.")
+var modeFlag = flag.String("mode", terminate.ModeCanonical, "Either canonical, to terminate instances which don't match the canonical version, or enforceGroupModal, to terminate instances which don't match the most common version in their group.")
+var minimumHealthyPercentageFlag = flag.Float64("minimumHealthyPercentage", 0, "When set, the percentage of the healthy instances in each auto-scaling group to leave, e.g. 50. When minimumInstanceCount is larger, it's used instead.")
+var maxTerminationsFlag = flag.Int("maxTerminations", 10, "The maximum number of instances to terminate in a single run across all groups, once reached the remaining groups are skipped. Set to -1 for unlimited.")
+var terminationBatchSizeFlag = flag.Int("terminationBatchSize", 0, "When set, the maximum number of instances in a group to terminate at once, defaults to terminating them all at once.")
+var terminationCooldownFlag = flag.Duration("terminationCooldown", 0, "When terminationBatchSize is set, how long to wait between terminating batches, giving the auto-scaling group time to launch replacements.")
+var rollingFlag = flag.Bool("rolling", false, "When set, after terminating each batch of instances, wait for the auto-scaling group to have as many healthy instances as its desired capacity before terminating the next batch. The batch size defaults to 1.")
+var rollingTimeoutFlag = flag.Duration("rollingTimeout", 10*time.Minute, "When rolling is set, how long to wait for the auto-scaling group to replace terminated instances before giving up on the group.")
+var canaryFlag = flag.Bool("canary", false, "When set, terminate a single instance in each group first, and only terminate the rest once its replacement reports the expected version. Requires the http versionSource.")
+var canaryTimeoutFlag = flag.Duration("canaryTimeout", 10*time.Minute, "When canary is set, how long to wait for the replacement of the canary instance before giving up on the group.")
+var drainBeforeTerminateFlag = flag.Bool("drainBeforeTerminate", false, "When set, instances are moved into standby, deregistering them from their load balancers, and given drainTimeout to finish serving their connections before they're terminated.")
+var terminateDirectionFlag = flag.String("terminateDirection", string(integration.DirectionBoth), "Which instances are terminated, either both, to terminate instances whose version is below or above the canonical version, below, e.g. to spare a canary running a newer version during a progressive rollout, or above.")
+var standbyFirstFlag = flag.Bool("standbyFirst", false, "When set, each batch of instances is moved into standby before it's terminated, so that the auto-scaling group launches replacements while the old instances are still running, and its capacity doesn't dip.")
+var drainModeFlag = flag.String("drainMode", string(integration.DrainModeStandby), "When drainBeforeTerminate is set, how instances are drained, either standby, or targetGroups to deregister them from the target groups of their auto-scaling group, and wait until the target groups report them as unused, or drainTimeout elapses.")
+var drainTimeoutFlag = flag.Duration("drainTimeout", 5*time.Minute, "When drainBeforeTerminate is set, how long to wait for the connections of drained instances to complete before terminating them.")
+var noReplaceFlag = flag.Bool("noReplace", false, "When set, the Launch process of each auto-scaling group is suspended while its instances are terminated, and resumed afterwards, so that the group doesn't launch replacements until the run has finished with it. Can't be used with rolling or canary.")
+var detachFlag = flag.Bool("detach", false, "When set, instances are terminated through the auto-scaling API instead of the EC2 API, so that the group's desired capacity can be decremented.")
+var shouldDecrementDesiredCapacityFlag = flag.Bool("shouldDecrementDesiredCapacity", false, "When detach is set, decrement the desired capacity of the group for each terminated instance, shrinking the group instead of launching replacements.")
+var completeLifecycleHooksFlag = flag.Bool("completeLifecycleHooks", false, "When set, complete the termination lifecycle hooks of instances waiting in the Terminating:Wait state, and of instances terminated with detach, so they don't wait for the hooks to time out.")
+var singleGroupFlag = flag.Bool("singleGroup", false, "When set, only one of the matching auto-scaling groups is processed, the others are deferred to later runs.")
+var singleGroupSelectionFlag = flag.String("singleGroupSelection", terminate.SelectionFirstAlphabetical, "When singleGroup is set, how the group is selected, either firstAlphabetical, mostDrift (the most instances which don't match the canonical version) or oldestInstances.")
+var maxProbeFailureRateFlag = flag.Float64("maxProbeFailureRate", 0, "When set, the largest fraction of the instances, e.g. 0.3, whose version can fail to be read before a group is left alone, or, across all of the groups, before the run is aborted, rather than acting on partial data.")
+var concurrencyFlag = flag.Int("concurrency", 10, "The maximum number of instances in a group to probe for their version at the same time.")
+var metricsAddrFlag = flag.String("metricsAddr", "", "When set with interval, the address to serve Prometheus metrics on at /metrics, e.g. :9090.")
+var runTimeoutFlag = flag.Duration("runTimeout", 0, "When set, the longest terminator runs for, e.g. 30m, so that runs started by cron don't overlap. When it elapses, the remaining groups are skipped, and a summary of what was done is logged.")
+var intervalFlag = flag.Duration("interval", 0, "When set, terminator runs repeatedly until interrupted, waiting the interval, e.g. 15m, between the end of one run and the start of the next.")
+var reportFlag = flag.Bool("report", false, "When set, prints the version, launch time and health of each instance, and the most common, highest and lowest versions in each group, without terminating anything. The same as the report subcommand, e.g. terminator report -autoScalingGroups=asg_api.")
+var reportFormatFlag = flag.String("reportFormat", reportFormatNone, "When set to junit, a JUnit XML report with a test case for each group is written to the outputFile.")
+var outputFlag = flag.String("output", outputText, "Either text, or json to write a summary of the instances evaluated in each group, and which were selected for termination, to the outputFile.")
+var planFileFlag = flag.String("planFile", "", "When set during a dry run, the file to write the plan of the run to, listing each instance, its current and expected version, and whether it would be terminated. The plan is JSON when the file has a .json extension, otherwise it's a table.")
+var outputFileFlag = flag.String("outputFile", "", "The file to write the report to, defaults to stdout.")
+var configFileFlag = flag.String("config", "", "When set, the path of a YAML file of flag values, e.g. region: eu-west-2 Flags set on the command line override the values in the file.")
+var configURLFlag = flag.String("configURL", "", "When set, a URL of a JSON document which overrides the canonical, isDryRun, minimumInstanceCount and mode flags. It's fetched before each run, and if it's unavailable or invalid, the last good configuration is used.")
+var probeRetriesFlag = flag.Int("probeRetries", 2, "The number of times to retry a version probe which fails with a network error or a 5xx response.")
+var probeBackoffFlag = flag.Duration("probeBackoff", 500*time.Millisecond, "The delay before the first retry of a failed version probe, doubling on each subsequent retry.")
+var versionCacheTTLFlag = flag.Duration("versionCacheTTL", 0, "When set, the version reported by an instance is reused for the duration, e.g. 1h, instead of probing it on each run with --interval. Instances are probed again when their launch time changes.")
+var maxResponseBytesFlag = flag.Int64("maxResponseBytes", 64*1024, "The largest response body to read from the version endpoint, larger responses are treated as a failed probe.")
+var versionHeaderFlag = flag.String("versionHeader", "", "When set, the version is read from this response header of the version endpoint, e.g. X-App-Version, instead of the body.")
+var versionJSONPathFlag = flag.String("versionJSONPath", "", "When set, the version endpoint is expected to return JSON, and the version is read from this path, e.g. .version or .app.version")
+var insecureSkipVerifyFlag = flag.Bool("insecureSkipVerify", false, "When the scheme is https, accept any certificate presented by instances, e.g. self-signed certificates.")
+var caBundleFlag = flag.String("caBundle", "", "When the scheme is https, the path to a PEM file of certificate authorities to trust.")
+var probeTransportFlag = flag.String("probeTransport", "direct", "How to reach the version endpoint, either direct (the instance's private IP) or ssh (the instance's localhost, through an SSH tunnel).")
+var sshBastionFlag = flag.String("sshBastion", "", "When probeTransport is ssh, the host:port of the bastion to connect through. Leave empty to connect to instances directly.")
+var sshUserFlag = flag.String("sshUser", "ec2-user", "When probeTransport is ssh, the user to connect as.")
+var sshKeyFlag = flag.String("sshKey", "", "When probeTransport is ssh, the path to the private key to authenticate with.")
+var sshKnownHostsFlag = flag.String("sshKnownHosts", os.ExpandEnv("$HOME/.ssh/known_hosts"), "When probeTransport is ssh, the path to the known_hosts file used to verify host keys.")
+var versionSourceFlag = flag.String("versionSource", "http", "Where to read the version of each instance from, either http (the version endpoint), ssmInventory (the OS version recorded by SSM Inventory) or tag (the EC2 tag named by versionTagKey).")
+var versionTagKeyFlag = flag.String("versionTagKey", "", "With the tag versionSource, the key of the EC2 tag the version of each instance is read from, e.g. app:version.")
+var versionSchemeFlag = flag.String("versionScheme", "semver", "How versions are compared with the canonical version, either semver, integer (build numbers, e.g. 4821) or lexicographic (e.g. dates like 2024.03.15). The integer and lexicographic schemes require a canonical version, and can't be used with canonicalRange, the enforceGroupModal mode or the ssmInventory versionSource.")
+var minimumOSVersionFlag = flag.String("minimumOSVersion", "", "When versionSource is ssmInventory, instances running an OS version lower than this, e.g. 2 or 20.04, are terminated.")
+
+var snsTopicARNFlag = flag.String("snsTopicArn", "", "When set, the ARN of an SNS topic to publish a summary of the terminated instances to. Nothing is published during a dry run, or when no instances were terminated.")
+var slackWebhookURLFlag = flag.String("slackWebhookURL", "", "When set, the URL of a Slack incoming webhook to post a summary of the terminated instances to. Nothing is posted during a dry run, or when no instances were terminated.")
+var emitMetricsFlag = flag.Bool("emitMetrics", false, "When set, the InstancesEvaluated, MismatchedInstances and InstancesTerminated metrics of each auto-scaling group are published to CloudWatch after the run.")
+var metricNamespaceFlag = flag.String("metricNamespace", "Terminator", "When emitMetrics is set, the CloudWatch namespace to publish the metrics to.")
+var profileFlag = flag.String("profile", "", "When set, the named profile of the AWS shared credentials and config files, e.g. ~/.aws/credentials, to use instead of the default credentials.")
+var awsEndpointFlag = flag.String("awsEndpoint", "", "When set, the URL of the AWS APIs to use instead of the AWS endpoints of the region, e.g. http://localhost:4566 to test against LocalStack.")
+var assumeRoleARNFlag = flag.String("assumeRoleArn", "", "When set, the ARN of an IAM role to assume, e.g. to manage auto-scaling groups in another account.")
+var externalIDFlag = flag.String("externalId", "", "When assumeRoleArn is set, the external ID required by the role's trust policy.")
+var ecsClusterFlag = flag.String("ecsCluster", "", "When set, the name of an ECS cluster whose services are processed instead of auto-scaling groups, e.g. autoScalingGroups=api,web selects the api and web services. Tasks are stopped instead of terminated, so that their services start replacements. Only tasks using the awsvpc network mode can be probed.")
+var selectorTagFlag = flag.String("selectorTag", "", "When set, only autoscaling groups with this tag are processed, e.g. terminator:enabled=true")
+var minInstanceAgeFlag = flag.Duration("minInstanceAge", 0, "When set, instances launched more recently than the age, e.g. 5m, are never terminated, since they may not have reported their version yet.")
+var maxInstanceAgeFlag = flag.Duration("maxInstanceAge", 0, "When set, instances launched longer ago than the age, e.g. 720h, are terminated even when they run the expected version, still leaving minimumInstanceCount instances.")
+var normalizeVersionsFlag = flag.Bool("normalizeVersions", false, "When set, build metadata and git describe suffixes are removed from the versions returned by instances, e.g. 1.2.3-4-gdeadbee and 1.2.3+build are read as 1.2.3.")
+var ipFamilyFlag = flag.String("ipFamily", string(integration.IPFamilyAuto), "Which address version probes connect to, either ipv4, ipv6, or auto, to use the IPv4 address of each instance, or its IPv6 address if it's IPv6-only.")
+var portTagFlag = flag.String("portTag", "", "When set, the key of an EC2 tag, e.g. terminator:port, whose value is the port to probe each instance on, instead of port. Instances without the tag are probed on port.")
+var usePublicIPFlag = flag.Bool("usePublicIP", false, "When set, version probes connect to the public IP address of each instance instead of its private IP address, e.g. when running outside the VPC.")
+var healthURLFlag = flag.String("healthURL", "", "When set, the path of a health endpoint, e.g. /healthz, which is probed before the version endpoint. Instances which fail the health check are treated as unhealthy.")
+var allowPartialDetailsFlag = flag.Bool("allowPartialDetails", false, "When set, groups where the version of some healthy instances couldn't be read are still acted on, using the instances whose version could be read. Instances with an unknown version are never terminated, and don't count towards minimumInstanceCount.")
+var useWeightedCapacityFlag = flag.Bool("useWeightedCapacity", false, "When set, minimumInstanceCount is the capacity to leave in each auto-scaling group, where each instance provides its weighted capacity, e.g. in groups of mixed instance types, instead of a number of instances.")
+var useAsgMinSizeFlag = flag.Bool("useAsgMinSize", false, "When set, the MinSize of each auto-scaling group is the number of healthy instances to leave in it, instead of minimumInstanceCount.")
+var standbyCountsAsHealthyFlag = flag.Bool("standbyCountsAsHealthy", false, "When set, healthy instances in standby count as healthy instances, and can be terminated. Otherwise they're ignored, like pending instances.")
+var logLevelFlag = flag.String("logLevel", "info", "The minimum level of log messages written to stderr, either debug, info, warn or error.")
+var logFormatFlag = flag.String("logFormat", logFormatText, "The format of log messages, either text or json.")
 
+var regionsFlag asgParams
 var autoScalingGroupsFlag asgParams
+var autoScalingGroupsRegexFlag regexpParam
+var excludeAutoScalingGroupsFlag asgParams
+var canonicalRangeFlag rangeParam
+var versionRegexFlag versionRegexParam
+var headersFlag = headerParams{}
+var versionOverridesFlag = versionOverrideParams{}
+var freezeWindowFlag freezeWindowParams
+var groupConfigFlag = groupConfigParams{}
 
 func init() {
 	// Tie the command-line flag to the intervalFlag variable and
 	// set a usage message.
 	flag.Var(&autoScalingGroupsFlag, "autoScalingGroups", "Comma-separated list of autoscaling group names.")
+	flag.Var(&excludeAutoScalingGroupsFlag, "excludeAutoScalingGroups", "Comma-separated list of autoscaling group names which are never processed, e.g. bastion-asg.")
+	flag.Var(&autoScalingGroupsRegexFlag, "autoScalingGroupsRegex", "A regular expression which must match the whole name of an autoscaling group for it to be selected, e.g. web-prod-.* Can't be used with autoScalingGroups.")
+	flag.Var(&regionsFlag, "regions", "Comma-separated list of regions to process in turn, e.g. eu-west-1,us-east-1. Can't be used with region.")
+	flag.Var(&canonicalRangeFlag, "canonicalRange", "A range of versions to check against instead of the canonical version, e.g. \">=1.2.0 <2.0.0\". Instances running a version outside the range are terminated. Can't be used with canonical.")
+	flag.Var(&versionRegexFlag, "versionRegex", "When set, a regular expression with a single capture group which extracts the version from the response of the version endpoint, e.g. \"Version: (\\S+)\" reads 1.4.2 from \"Version: 1.4.2 (build 99)\". It's applied after versionJSONPath.")
+	flag.Var(versionOverridesFlag, "versionOverrides", "During a dry run, a comma-separated list of instance IDs and the versions to treat them as running, e.g. i-0abc=1.2.0,i-0def=1.1.0, instead of probing them. Instances without an override are treated as if their version couldn't be read. Use it to rehearse the instances canonical and minimumInstanceCount would select.")
+	flag.Var(groupConfigFlag, "groupConfig", "A JSON object of auto-scaling group names and the canonical version and minimumInstanceCount to use for them instead of the canonical and minimumInstanceCount flags, e.g. {\"asg_api\":{\"canonical\":\"1.2.0\",\"minimumInstanceCount\":3}}. Usually set by the groupConfig mapping of the config file.")
+	flag.Var(&freezeWindowFlag, "freezeWindow", "A comma-separated list of the periods when nothing is terminated, even during a dry run, e.g. \"Fri 17:00-Mon 09:00\" or \"Mon-Fri 09:00-17:00\". The times are UTC, unless the period ends with a time zone, e.g. \"Mon-Fri 09:00-17:00 Europe/London\". Can be repeated.")
+	flag.Var(headersFlag, "header", "A header to send to the version endpoint, e.g. \"Authorization: Bearer ${TOKEN}\". Environment variables are expanded. Can be repeated.")
+
+	// Report invalid flags through the exit code contract instead of the flag package exiting directly.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
 }
 
+// parameters holds the options of a run, alongside the command-line settings used to create the AWS provider.
 type parameters struct {
-	region               string
-	isDryRun             bool
-	minimumInstanceCount int
-	scheme               string
-	port                 int
-	versionURL           string
-	autoScalingGroups    asgParams
-	canonical            string
+	terminate.Options
+
+	region                 string
+	regions                []string
+	assumeRole             integration.AssumeRole
+	awsEndpoint            string
+	profile                string
+	ecsCluster             string
+	autoScalingGroupsRegex *regexp.Regexp
+	selectorTag            *integration.Tag
+	standbyCountsAsHealthy bool
+	useAsgMinSize          bool
+	useWeightedCapacity    bool
+	allowPartialDetails    bool
+	healthURL              string
+	canonicalURL           string
+	minInstanceAge         time.Duration
+	maxInstanceAge         time.Duration
+	normalizeVersions      bool
+	usePublicIP            bool
+	portTag                string
+	ipFamily               integration.IPFamily
+	versionTagKey          string
+	concurrency            int
+	probeRetries           int
+	probeBackoff           time.Duration
+	maxResponseBytes       int64
+	versionCacheTTL        time.Duration
+	headers                http.Header
+	versionOverrides       map[string]string
+	versionHeader          string
+	versionJSONPath        string
+	versionRegex           *regexp.Regexp
+	tlsConfig              *tls.Config
+	probeTransport         integration.ProbeTransport
+	ssh                    integration.SSHConfig
+}
+
+// exit ends the process with the given exit code, tests replace it to observe the code.
+var exit = os.Exit
+
+// newCloudProvider creates the provider used to access AWS, tests replace it with a mock.
+var newCloudProvider = func(p parameters) (integration.CloudProvider, error) {
+	if p.ecsCluster != "" {
+		return newECSProvider(p)
+	}
+
+	aws, err := integration.NewAWSProvider(p.region, p.assumeRole, p.awsEndpoint, p.profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aws.VersionSource = p.VersionSource
+	aws.VersionTagKey = p.versionTagKey
+	aws.Concurrency = p.concurrency
+	aws.ProbeRetries = p.probeRetries
+	aws.ProbeBackoff = p.probeBackoff
+	aws.MaxResponseBytes = p.maxResponseBytes
+	aws.VersionCacheTTL = p.versionCacheTTL
+	aws.ProbeHeaders = p.headers
+	aws.VersionOverrides = p.versionOverrides
+	aws.VersionHeader = p.versionHeader
+	aws.VersionJSONPath = p.versionJSONPath
+	aws.VersionRegex = p.versionRegex
+	aws.GroupNamePattern = p.autoScalingGroupsRegex
+	aws.SelectorTag = p.selectorTag
+	aws.StandbyCountsAsHealthy = p.standbyCountsAsHealthy
+	aws.UseMinSize = p.useAsgMinSize
+	aws.UseWeightedCapacity = p.useWeightedCapacity
+	aws.AllowPartialDetails = p.allowPartialDetails
+	aws.HealthURL = p.healthURL
+	aws.MinInstanceAge = p.minInstanceAge
+	aws.MaxInstanceAge = p.maxInstanceAge
+	aws.NormalizeVersions = p.normalizeVersions
+	aws.VersionScheme = p.VersionScheme
+	aws.UsePublicIP = p.usePublicIP
+	aws.PortTag = p.portTag
+	aws.IPFamily = p.ipFamily
+	aws.TLSConfig = p.tlsConfig
+	aws.ProbeTransport = p.probeTransport
+	aws.SSH = p.ssh
+	aws.DrainMode = p.DrainMode
+	aws.DrainTimeout = p.DrainTimeout
+
+	return aws, nil
+}
+
+// newECSProvider creates the provider used to access the services of the ECS cluster.
+func newECSProvider(p parameters) (integration.CloudProvider, error) {
+	ecs, err := integration.NewECSProvider(p.region, p.ecsCluster, p.assumeRole, p.awsEndpoint, p.profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ecs.Concurrency = p.concurrency
+	ecs.ProbeRetries = p.probeRetries
+	ecs.ProbeBackoff = p.probeBackoff
+	ecs.MaxResponseBytes = p.maxResponseBytes
+	ecs.ProbeHeaders = p.headers
+	ecs.VersionHeader = p.versionHeader
+	ecs.VersionJSONPath = p.versionJSONPath
+	ecs.VersionRegex = p.versionRegex
+	ecs.AllowPartialDetails = p.allowPartialDetails
+	ecs.MinInstanceAge = p.minInstanceAge
+	ecs.MaxInstanceAge = p.maxInstanceAge
+	ecs.NormalizeVersions = p.normalizeVersions
+	ecs.VersionScheme = p.VersionScheme
+	ecs.TLSConfig = p.tlsConfig
+
+	return ecs, nil
+}
+
+// commandReport is the subcommand which prints the versions running in each group without terminating
+// anything, the same as --report.
+const commandReport = "report"
+
+// expandCommand replaces a subcommand at the start of the arguments with the flag it's short for.
+func expandCommand(args []string) []string {
+	if len(args) > 0 && args[0] == commandReport {
+		return append([]string{"-report"}, args[1:]...)
+	}
+	return args
+}
+
+// start runs terminator, the lambda build replaces it to start the Lambda handler instead.
+var start = func() {
+	exit(run(os.Args[1:]))
 }
 
 func main() {
-	flag.Parse()
+	start()
+}
+
+// run executes terminator with the given command-line arguments and returns the exit code.
+func run(args []string) int {
+	p, code, ok := configure(expandCommand(args))
+
+	if !ok {
+		return code
+	}
+
+	clouds, err := newRegionalClouds(p)
+
+	if err != nil {
+		slog.Error("failed to create an AWS session", "error", err)
+		return exitError
+	}
+
+	// Cancel in-flight AWS calls and probes when interrupted.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The timeout cancels the run in the same way as an interruption.
+	if *runTimeoutFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *runTimeoutFlag)
+		defer cancel()
+	}
+
+	if *metricsAddrFlag != "" {
+		reg := prometheus.NewRegistry()
+		p.Prometheus = terminate.NewPrometheusMetrics(reg)
+		go serveMetrics(ctx, *metricsAddrFlag, reg)
+	}
+
+	var refresher *configRefresher
+	if *configURLFlag != "" {
+		refresher = newConfigRefresher(*configURLFlag, p)
+	}
+
+	if *reportFlag {
+		if refresher != nil {
+			p = refresher.refresh(ctx)
+		}
+		for _, rc := range clouds {
+			if len(clouds) > 1 {
+				fmt.Printf("%s:\n", rc.region)
+			}
+			if err := printVersionDetails(ctx, os.Stdout, rc.cloud, p.Options); err != nil {
+				fmt.Println("Failed to get the version details, ", err)
+				return exitError
+			}
+		}
+		return exitOK
+	}
+
+	if *intervalFlag > 0 {
+		slog.Info("running on an interval until interrupted", "interval", *intervalFlag)
+		runEvery(ctx, *intervalFlag, func(ctx context.Context) {
+			runOnce(ctx, clouds, p, refresher)
+		})
+		slog.Info("interrupted, stopping")
+		return exitOK
+	}
+
+	return runOnce(ctx, clouds, p, refresher)
+}
+
+// configure parses the command-line arguments into the parameters of a run. When the arguments are invalid,
+// or there's nothing left to do, e.g. the version was printed, ok is false and code is the exit code.
+func configure(args []string) (p parameters, code int, ok bool) {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return p, exitOK, false
+		}
+		return p, exitInvalidArguments, false
+	}
 
 	if *versionFlag {
 		fmt.Println(version)
-		return
+		return p, exitOK, false
+	}
+
+	// The conflicts between flags are checked against the command line, since the config file leaves out the
+	// values which conflict with it.
+	setOnCommandLine := commandLineFlags(flag.CommandLine)
+
+	if *configFileFlag != "" {
+		c, err := loadConfigFile(*configFileFlag)
+
+		if err != nil {
+			fmt.Println("Invalid config, ", err)
+			return p, exitInvalidArguments, false
+		}
+
+		if err := c.apply(flag.CommandLine); err != nil {
+			fmt.Println("Invalid config, ", err)
+			return p, exitInvalidArguments, false
+		}
+	}
+
+	logger, err := newLogger(os.Stderr, *logLevelFlag, *logFormatFlag)
+
+	if err != nil {
+		fmt.Println("Invalid logging configuration, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	slog.SetDefault(logger)
+
+	if len(autoScalingGroupsFlag) > 0 && autoScalingGroupsRegexFlag.Regexp != nil {
+		fmt.Println("Invalid autoScalingGroupsRegex, it can't be used with autoScalingGroups")
+		return p, exitInvalidArguments, false
+	}
+
+	if len(regionsFlag) > 0 && setOnCommandLine["region"] {
+		fmt.Println("Invalid regions, it can't be used with region")
+		return p, exitInvalidArguments, false
+	}
+
+	regions := []string(regionsFlag)
+	if len(regions) == 0 {
+		regions = []string{*regionFlag}
+	}
+
+	if canonicalRangeFlag.Range != nil && setOnCommandLine["canonical"] {
+		fmt.Println("Invalid canonicalRange, it can't be used with canonical")
+		return p, exitInvalidArguments, false
+	}
+
+	if *canonicalURLFlag != "" && (setOnCommandLine["canonical"] || canonicalRangeFlag.Range != nil) {
+		fmt.Println("Invalid canonicalURL, it can't be used with canonical or canonicalRange")
+		return p, exitInvalidArguments, false
+	}
+
+	if *modeFlag != terminate.ModeCanonical && *modeFlag != terminate.ModeEnforceGroupModal {
+		fmt.Printf("Invalid mode %q, expected %q or %q\n", *modeFlag, terminate.ModeCanonical, terminate.ModeEnforceGroupModal)
+		return p, exitInvalidArguments, false
+	}
+
+	if *minimumHealthyPercentageFlag < 0 || *minimumHealthyPercentageFlag > 100 {
+		fmt.Printf("Invalid minimumHealthyPercentage %g, expected a percentage between 0 and 100\n", *minimumHealthyPercentageFlag)
+		return p, exitInvalidArguments, false
+	}
+
+	if *maxProbeFailureRateFlag < 0 || *maxProbeFailureRateFlag > 1 {
+		fmt.Printf("Invalid maxProbeFailureRate %g, expected a fraction between 0 and 1\n", *maxProbeFailureRateFlag)
+		return p, exitInvalidArguments, false
+	}
+
+	if *maxTerminationsFlag < unlimitedTerminations {
+		fmt.Printf("Invalid maxTerminations %d, expected a number of instances or %d for unlimited\n", *maxTerminationsFlag, unlimitedTerminations)
+		return p, exitInvalidArguments, false
+	}
+
+	if !terminate.IsValidSelection(*singleGroupSelectionFlag) {
+		fmt.Printf("Invalid singleGroupSelection %q, expected %q, %q or %q\n", *singleGroupSelectionFlag,
+			terminate.SelectionFirstAlphabetical, terminate.SelectionMostDrift, terminate.SelectionOldestInstances)
+		return p, exitInvalidArguments, false
+	}
+
+	if *reportFormatFlag != reportFormatNone && *reportFormatFlag != reportFormatJUnit {
+		fmt.Printf("Invalid reportFormat %q, expected %q\n", *reportFormatFlag, reportFormatJUnit)
+		return p, exitInvalidArguments, false
+	}
+
+	if *outputFlag != outputText && *outputFlag != outputJSON {
+		fmt.Printf("Invalid output %q, expected %q or %q\n", *outputFlag, outputText, outputJSON)
+		return p, exitInvalidArguments, false
+	}
+
+	if *outputFlag == outputJSON && *reportFormatFlag == reportFormatJUnit {
+		fmt.Println("Invalid output, the json output and the junit reportFormat can't be used together")
+		return p, exitInvalidArguments, false
+	}
+
+	versionSource, err := integration.ParseVersionSource(*versionSourceFlag)
+
+	if err != nil {
+		fmt.Println("Invalid versionSource, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	versionScheme, err := integration.ParseVersionScheme(*versionSchemeFlag)
+
+	if err != nil {
+		fmt.Println("Invalid versionScheme, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	if *canaryFlag && versionSource == integration.VersionSourceSSMInventory {
+		fmt.Printf("Invalid canary, the replacement instance can't be checked with the %q versionSource\n", integration.VersionSourceSSMInventory)
+		return p, exitInvalidArguments, false
+	}
+
+	if versionSource == integration.VersionSourceTag && *versionTagKeyFlag == "" {
+		fmt.Printf("Invalid versionTagKey, it's required with the %q versionSource\n", integration.VersionSourceTag)
+		return p, exitInvalidArguments, false
+	}
+
+	ipFamily, err := integration.ParseIPFamily(*ipFamilyFlag)
+
+	if err != nil {
+		fmt.Println("Invalid ipFamily, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	terminateDirection, err := integration.ParseDirection(*terminateDirectionFlag)
+
+	if err != nil {
+		fmt.Println("Invalid terminateDirection, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	drainMode, err := integration.ParseDrainMode(*drainModeFlag)
+
+	if err != nil {
+		fmt.Println("Invalid drainMode, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	if drainMode != integration.DrainModeStandby && !*drainBeforeTerminateFlag {
+		fmt.Println("Invalid drainMode, instances are only drained when drainBeforeTerminate is set")
+		return p, exitInvalidArguments, false
+	}
+
+	if *shouldDecrementDesiredCapacityFlag && !*detachFlag {
+		fmt.Println("Invalid shouldDecrementDesiredCapacity, the desired capacity can only be decremented when detach is set")
+		return p, exitInvalidArguments, false
+	}
+
+	if *standbyFirstFlag && (*noReplaceFlag || *shouldDecrementDesiredCapacityFlag) {
+		fmt.Println("Invalid standbyFirst, the instances in standby are replaced, so it can't be used with noReplace or shouldDecrementDesiredCapacity")
+		return p, exitInvalidArguments, false
+	}
+
+	if *standbyFirstFlag && *drainBeforeTerminateFlag && drainMode == integration.DrainModeStandby {
+		fmt.Println("Invalid standbyFirst, instances are already moved into standby by drainBeforeTerminate, use drainMode=targetGroups to drain them first")
+		return p, exitInvalidArguments, false
+	}
+
+	if *noReplaceFlag && (*rollingFlag || *canaryFlag) {
+		fmt.Println("Invalid noReplace, instances aren't replaced while launches are suspended, so it can't be used with rolling or canary")
+		return p, exitInvalidArguments, false
+	}
+
+	if *shouldDecrementDesiredCapacityFlag && *canaryFlag {
+		fmt.Println("Invalid canary, the canary instance isn't replaced when shouldDecrementDesiredCapacity is set")
+		return p, exitInvalidArguments, false
+	}
+
+	if *maxInstanceAgeFlag > 0 && *maxInstanceAgeFlag <= *minInstanceAgeFlag {
+		fmt.Printf("Invalid maxInstanceAge %v, it must be greater than minInstanceAge %v\n", *maxInstanceAgeFlag, *minInstanceAgeFlag)
+		return p, exitInvalidArguments, false
 	}
 
-	aws, err := integration.NewAWSProvider(*regionFlag)
+	var selectorTag *integration.Tag
+	if *selectorTagFlag != "" {
+		tag, err := integration.ParseTag(*selectorTagFlag)
+
+		if err != nil {
+			fmt.Println("Invalid selectorTag, ", err)
+			return p, exitInvalidArguments, false
+		}
+
+		selectorTag = &tag
+	}
+
+	probeTransport, err := integration.ParseProbeTransport(*probeTransportFlag)
 
 	if err != nil {
-		fmt.Println("Failed to create an AWS session, ", err)
-		return
+		fmt.Println("Invalid probeTransport, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	if *intervalFlag < 0 {
+		fmt.Printf("Invalid interval %v, it can't be negative\n", *intervalFlag)
+		return p, exitInvalidArguments, false
+	}
+
+	if *runTimeoutFlag < 0 {
+		fmt.Printf("Invalid runTimeout %v, it can't be negative\n", *runTimeoutFlag)
+		return p, exitInvalidArguments, false
+	}
+
+	if *metricsAddrFlag != "" && *intervalFlag == 0 {
+		fmt.Println("Invalid metricsAddr, metrics can only be served when running on an interval")
+		return p, exitInvalidArguments, false
+	}
+
+	tlsConfig, err := integration.NewTLSConfig(*insecureSkipVerifyFlag, *caBundleFlag)
+
+	if err != nil {
+		fmt.Println("Invalid TLS configuration, ", err)
+		return p, exitInvalidArguments, false
+	}
+
+	p = parameters{
+		Options: terminate.Options{
+			IsDryRun:                 *isDryRunFlag,
+			Advisory:                 *advisoryFlag,
+			MinimumInstanceCount:     *minimumInstanceCountFlag,
+			MinimumHealthyPercentage: *minimumHealthyPercentageFlag,
+			MaxTerminations:          maxTerminations(*maxTerminationsFlag),
+			TerminationBatchSize:     *terminationBatchSizeFlag,
+			TerminationCooldown:      *terminationCooldownFlag,
+			Rolling:                  *rollingFlag,
+			RollingTimeout:           *rollingTimeoutFlag,
+			Canary:                   *canaryFlag,
+			CanaryTimeout:            *canaryTimeoutFlag,
+			StandbyFirst:             *standbyFirstFlag,
+			DrainBeforeTerminate:     *drainBeforeTerminateFlag,
+			DrainMode:                drainMode,
+			DrainTimeout:             *drainTimeoutFlag,
+			NoReplace:                *noReplaceFlag,
+			Detach:                   *detachFlag,
+			DecrementDesiredCapacity: *shouldDecrementDesiredCapacityFlag,
+			CompleteLifecycleHooks:   *completeLifecycleHooksFlag,
+			SNSTopicARN:              *snsTopicARNFlag,
+			SlackWebhookURL:          *slackWebhookURLFlag,
+			EmitMetrics:              *emitMetricsFlag,
+			MetricNamespace:          *metricNamespaceFlag,
+			Scheme:                   *schemeFlag,
+			Port:                     *portFlag,
+			VersionURL:               *versionURLFlag,
+			AutoScalingGroups:        autoScalingGroupsFlag,
+			ExcludeAutoScalingGroups: excludeAutoScalingGroupsFlag,
+			Canonical:                *canonicalFlag,
+			CanonicalRange:           canonicalRangeFlag.VersionRange,
+			Mode:                     *modeFlag,
+			Direction:                terminateDirection,
+			SingleGroup:              *singleGroupFlag,
+			SingleGroupSelection:     *singleGroupSelectionFlag,
+			VersionSource:            versionSource,
+			VersionScheme:            versionScheme,
+			MinimumOSVersion:         *minimumOSVersionFlag,
+			Plan:                     *planFileFlag != "",
+			FreezeWindows:            freezeWindowFlag,
+			MaxProbeFailureRate:      *maxProbeFailureRateFlag,
+			GroupOverrides:           groupConfigFlag,
+		},
+		region:                 *regionFlag,
+		regions:                regions,
+		awsEndpoint:            *awsEndpointFlag,
+		profile:                *profileFlag,
+		ecsCluster:             *ecsClusterFlag,
+		autoScalingGroupsRegex: autoScalingGroupsRegexFlag.Regexp,
+		selectorTag:            selectorTag,
+		standbyCountsAsHealthy: *standbyCountsAsHealthyFlag,
+		useAsgMinSize:          *useAsgMinSizeFlag,
+		useWeightedCapacity:    *useWeightedCapacityFlag,
+		allowPartialDetails:    *allowPartialDetailsFlag,
+		healthURL:              *healthURLFlag,
+		canonicalURL:           *canonicalURLFlag,
+		minInstanceAge:         *minInstanceAgeFlag,
+		maxInstanceAge:         *maxInstanceAgeFlag,
+		normalizeVersions:      *normalizeVersionsFlag,
+		usePublicIP:            *usePublicIPFlag,
+		portTag:                *portTagFlag,
+		ipFamily:               ipFamily,
+		versionTagKey:          *versionTagKeyFlag,
+		concurrency:            *concurrencyFlag,
+		probeRetries:           *probeRetriesFlag,
+		probeBackoff:           *probeBackoffFlag,
+		maxResponseBytes:       *maxResponseBytesFlag,
+		versionCacheTTL:        *versionCacheTTLFlag,
+		headers:                http.Header(headersFlag),
+		versionOverrides:       versionOverridesFlag,
+		versionHeader:          *versionHeaderFlag,
+		versionJSONPath:        *versionJSONPathFlag,
+		versionRegex:           versionRegexFlag.Regexp,
+		tlsConfig:              tlsConfig,
+		probeTransport:         probeTransport,
+		ssh: integration.SSHConfig{
+			Bastion:        *sshBastionFlag,
+			User:           *sshUserFlag,
+			KeyFile:        *sshKeyFlag,
+			KnownHostsFile: *sshKnownHostsFlag,
+		},
+		assumeRole: integration.AssumeRole{
+			ARN:        *assumeRoleARNFlag,
+			ExternalID: *externalIDFlag,
+		},
+	}
+
+	p.Confirm = newConfirm(*confirmFlag, *yesFlag || *forceFlag, promptInput, os.Stdout)
+
+	if err := validate(p); err != nil {
+		fmt.Printf("Invalid arguments, %v\n", err)
+		return p, exitInvalidArguments, false
+	}
+
+	return p, exitOK, true
+}
+
+// promptInput is where the answers to confirm prompts are read from, tests replace it.
+var promptInput io.Reader = os.Stdin
+
+// newConfirm returns a function which prints the targets of a group to out, and reads the operator's answer
+// from in, confirming the termination if it's yes. It returns nil when confirmation isn't required.
+func newConfirm(confirm, skip bool, in io.Reader, out io.Writer) func(group string, targets []string) bool {
+	if !confirm || skip {
+		return nil
+	}
+
+	scanner := bufio.NewScanner(in)
+	return func(group string, targets []string) bool {
+		fmt.Fprintf(out, "Terminate %d instances in %s %v? Type yes to continue: ", len(targets), group, targets)
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return false
+		}
+
+		return strings.TrimSpace(scanner.Text()) == "yes"
+	}
+}
+
+// validate checks the parameters which can't be checked by the flag package, so that bad input fails before
+// anything is terminated.
+func validate(p parameters) error {
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("invalid port %d, expected a port between 1 and 65535", p.Port)
+	}
+
+	if p.Scheme != "http" && p.Scheme != "https" {
+		return fmt.Errorf("invalid scheme %q, expected %q or %q", p.Scheme, "http", "https")
+	}
+
+	if p.MinimumInstanceCount < 0 {
+		return fmt.Errorf("invalid minimumInstanceCount %d, it can't be negative", p.MinimumInstanceCount)
+	}
+
+	if err := validateCanonical(p.Canonical, p.VersionScheme); err != nil {
+		return err
+	}
+
+	if !p.VersionScheme.IsSemver() {
+		if p.CanonicalRange.Range != nil {
+			return fmt.Errorf("invalid canonicalRange, it can't be used with the %q versionScheme", p.VersionScheme)
+		}
+		if p.Mode != terminate.ModeCanonical {
+			return fmt.Errorf("invalid mode %q, only the %q mode can be used with the %q versionScheme", p.Mode, terminate.ModeCanonical, p.VersionScheme)
+		}
+		if p.VersionSource == integration.VersionSourceSSMInventory {
+			return fmt.Errorf("invalid versionSource %q, it can't be used with the %q versionScheme", p.VersionSource, p.VersionScheme)
+		}
+	}
+
+	for name, o := range p.GroupOverrides {
+		if o.Canonical != "" {
+			if err := validateCanonical(o.Canonical, p.VersionScheme); err != nil {
+				return fmt.Errorf("invalid groupConfig of %s, %v", name, err)
+			}
+		}
+		if o.MinimumInstanceCount != nil && *o.MinimumInstanceCount < 0 {
+			return fmt.Errorf("invalid groupConfig of %s, minimumInstanceCount %d can't be negative", name, *o.MinimumInstanceCount)
+		}
+	}
+
+	if len(p.versionOverrides) > 0 {
+		if err := validateVersionOverrides(p); err != nil {
+			return err
+		}
+	}
+
+	if p.ecsCluster != "" {
+		if err := validateECS(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateVersionOverrides checks that the version overrides are only used to rehearse a dry run, and that each
+// version can be compared using the version scheme.
+func validateVersionOverrides(p parameters) error {
+	if !p.IsDryRun {
+		return fmt.Errorf("invalid versionOverrides, they can only be used with a dry run")
+	}
+
+	if p.VersionSource != integration.VersionSourceHTTP {
+		return fmt.Errorf("invalid versionOverrides, they can only be used with the %q versionSource", integration.VersionSourceHTTP)
+	}
+
+	if p.healthURL != "" {
+		return fmt.Errorf("invalid versionOverrides, they can't be used with healthURL, since instances aren't probed")
+	}
+
+	for id, version := range p.versionOverrides {
+		var err error
+		if p.VersionScheme.IsSemver() {
+			_, _, err = integration.ParseVersion(version)
+		} else {
+			err = p.VersionScheme.Comparator().Validate(version)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid version override %q for instance %s, %v", version, id, err)
+		}
+	}
+
+	return nil
+}
+
+// validateECS checks that the options which only apply to auto-scaling groups aren't used with an ECS cluster.
+func validateECS(p parameters) error {
+	unsupported := []struct {
+		name string
+		set  bool
+	}{
+		{"detach", p.Detach},
+		{"noReplace", p.NoReplace},
+		{"drainBeforeTerminate", p.DrainBeforeTerminate},
+		{"standbyFirst", p.StandbyFirst},
+		{"snsTopicArn", p.SNSTopicARN != ""},
+		{"emitMetrics", p.EmitMetrics},
+		{"autoScalingGroupsRegex", p.autoScalingGroupsRegex != nil},
+		{"versionOverrides", len(p.versionOverrides) > 0},
+		{"selectorTag", p.selectorTag != nil},
+		{"useAsgMinSize", p.useAsgMinSize},
+		{"useWeightedCapacity", p.useWeightedCapacity},
+		{"healthURL", p.healthURL != ""},
+		{"usePublicIP", p.usePublicIP},
+		{"portTag", p.portTag != ""},
+		{"ipFamily", p.ipFamily == integration.IPFamilyIPv6},
+		{"versionCacheTTL", p.versionCacheTTL > 0},
+		{"probeTransport", p.probeTransport == integration.ProbeTransportSSH},
+		{"versionSource", p.VersionSource == integration.VersionSourceSSMInventory || p.VersionSource == integration.VersionSourceTag},
+	}
+
+	for _, option := range unsupported {
+		if option.set {
+			return fmt.Errorf("invalid %s, it can't be used with ecsCluster", option.name)
+		}
+	}
+
+	return nil
+}
+
+// validateCanonical checks that the canonical version can be compared using the version scheme.
+func validateCanonical(canonical string, scheme integration.VersionScheme) error {
+	if scheme.IsSemver() {
+		if canonical == terminate.CanonicalAuto {
+			return nil
+		}
+		if _, err := semver.Make(canonical); err != nil {
+			return fmt.Errorf("invalid canonical version %q, expected a semantic version, e.g. 1.2.0, or %q, %v", canonical, terminate.CanonicalAuto, err)
+		}
+		return nil
 	}
 
-	p := parameters{
-		region:               *regionFlag,
-		isDryRun:             *isDryRunFlag,
-		minimumInstanceCount: *minimumInstanceCountFlag,
-		scheme:               *schemeFlag,
-		port:                 *portFlag,
-		versionURL:           *versionURLFlag,
-		autoScalingGroups:    autoScalingGroupsFlag,
-		canonical:            *canonicalFlag,
+	if canonical == terminate.CanonicalAuto {
+		return fmt.Errorf("invalid canonical version %q, it can only be used with the %q versionScheme", canonical, integration.VersionSchemeSemver)
 	}
 
-	terminate(aws, p)
+	if err := scheme.Comparator().Validate(canonical); err != nil {
+		return fmt.Errorf("invalid canonical version %q, %v", canonical, err)
+	}
+
+	return nil
+}
+
+// runOnce terminates the instances once, refreshing the parameters from the remote configuration first, if
+// there is one, and returns the exit code of the run.
+func runOnce(ctx context.Context, clouds []regionalCloud, p parameters, refresher *configRefresher) int {
+	if refresher != nil {
+		p = refresher.refresh(ctx)
+	}
+
+	if p.canonicalURL != "" {
+		canonical, err := integration.GetCanonicalVersion(ctx, &http.Client{}, p.canonicalURL)
+		if err == nil {
+			err = validateCanonical(canonical, p.VersionScheme)
+		}
+		if err != nil {
+			slog.Error("failed to get the canonical version, the run was aborted", "canonicalURL", p.canonicalURL, "error", err)
+			return exitError
+		}
+		slog.Info("fetched the canonical version", "canonicalURL", p.canonicalURL, "canonical", canonical)
+		p.Canonical = canonical
+	}
+
+	if *reportFormatFlag == reportFormatJUnit || *outputFlag == outputJSON {
+		p.Report = terminate.NewReport(p.IsDryRun)
+	}
+
+	result, err := terminateRegions(ctx, clouds, p.Options)
+	logKept(result.Kept)
+
+	if p.Report != nil {
+		if err := writeReport(*outputFileFlag, p.Report, *outputFlag == outputJSON); err != nil {
+			slog.Error("failed to write the report", "error", err)
+			return exitError
+		}
+	}
+
+	if result.Plan != nil {
+		if err := result.Plan.WriteFile(*planFileFlag); err != nil {
+			slog.Error("failed to write the plan", "file", *planFileFlag, "error", err)
+			return exitError
+		}
+		slog.Info("wrote the plan", "file", *planFileFlag)
+	}
+
+	if err == terminate.ErrMaxTerminations || err == terminate.ErrTooManyProbeFailures {
+		slog.Warn("terminator stopped", "reason", err.Error())
+		return exitSafetyAbort
+	}
+
+	if err == terminate.ErrVersionSkew {
+		slog.Warn("terminator found version skew", "reason", err.Error())
+		return exitVersionSkew
+	}
+
+	if err == terminate.ErrInterrupted {
+		if ctx.Err() == context.DeadlineExceeded {
+			slog.Warn("terminator stopped", "reason", "the run timed out", "runTimeout", *runTimeoutFlag, "terminated", result.Terminated)
+			return exitError
+		}
+		slog.Warn("terminator stopped", "reason", err.Error(), "terminated", result.Terminated)
+		return exitInterrupted
+	}
+
+	if err != nil {
+		slog.Error("terminator failed", "error", err)
+		return exitError
+	}
+
+	if p.IsDryRun && len(result.Terminated) > 0 {
+		return exitDryRunWouldChange
+	}
+
+	return exitOK
+}
+
+// logKept summarises the instances which were kept, grouped by the reason they were kept.
+func logKept(kept []terminate.KeptInstance) {
+	var reasons []integration.KeepReason
+	byReason := map[integration.KeepReason][]string{}
+	for _, k := range kept {
+		if _, ok := byReason[k.Reason]; !ok {
+			reasons = append(reasons, k.Reason)
+		}
+		byReason[k.Reason] = append(byReason[k.Reason], k.Group+"/"+k.ID)
+	}
+
+	for _, reason := range reasons {
+		slog.Info("kept instances", "reason", string(reason), "count", len(byReason[reason]), "instances", byReason[reason])
+	}
+}
+
+// writeReport writes the JUnit report, or when asJSON is set, the JSON report, to the file, or stdout if the file is empty.
+func writeReport(file string, r *terminate.Report, asJSON bool) error {
+	write := terminate.WriteJUnit
+	if asJSON {
+		write = terminate.WriteJSON
+	}
+
+	if file == "" {
+		return write(os.Stdout, r)
+	}
+
+	f, err := os.Create(file)
+
+	if err != nil {
+		return err
+	}
+
+	if err := write(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// printVersionDetails prints a table of the version, launch time, instance type, launch template and health of
+// each instance, followed by the spread of versions within each group, without terminating anything. Instances
+// whose version couldn't be read are listed with an unknown version.
+func printVersionDetails(ctx context.Context, w io.Writer, cloud integration.CloudProvider, p terminate.Options) error {
+	groups, err := cloud.DescribeAutoScalingGroups(ctx, p.AutoScalingGroups, p.Scheme, p.Port, p.VersionURL)
+
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tINSTANCE\tVERSION\tLAUNCHED\tTYPE\tTEMPLATE\tHEALTH")
+
+	for _, g := range groups {
+		details := map[string]integration.InstanceDetail{}
+		for _, d := range g.InstanceDetails {
+			details[d.ID] = d
+		}
+
+		for _, instance := range g.Instances {
+			version, launched := "unknown", "unknown"
+			if d, ok := details[instance.ID]; ok {
+				version, launched = d.Version(), d.LaunchTime.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s %s\n", g.Name, instance.ID, version, launched, orUnknown(instance.InstanceType), launchedFrom(instance), instance.HealthStatus, instance.LifecycleState)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s => %d instances, %s\n", g.Name, len(g.InstanceDetails), terminate.NewVersionDetails(g.InstanceDetails))
+	}
+
+	return nil
+}
+
+// launchedFrom returns the launch template and version the instance was launched from, e.g. "web:3", or its
+// launch configuration, when the group uses one instead.
+func launchedFrom(instance integration.Instance) string {
+	if instance.LaunchTemplateName != "" {
+		return instance.LaunchTemplateName + ":" + orUnknown(instance.LaunchTemplateVersion)
+	}
+	return orUnknown(instance.LaunchConfigurationName)
+}
+
+// orUnknown returns the value, or "unknown" when it's empty.
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// commandLineFlags returns the names of the flags which have been set, rather than left at their defaults. It's
+// called before the config file is applied, to find the flags which were set on the command line.
+func commandLineFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
 }
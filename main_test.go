@@ -0,0 +1,629 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate"
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestExitCodes(t *testing.T) {
+	defer func(e func(int), n func(parameters) (integration.CloudProvider, error), a []string) {
+		exit, newCloudProvider, os.Args = e, n, a
+	}(exit, newCloudProvider, os.Args)
+
+	mock := func(p parameters) (integration.CloudProvider, error) {
+		return terminatetest.CreateTestData(map[string]string{}, nil), nil
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		provider func(p parameters) (integration.CloudProvider, error)
+		expected int
+	}{
+		{
+			name:     "Displaying the version succeeds.",
+			args:     []string{"-version=true"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "Unknown flags are invalid arguments.",
+			args:     []string{"-version=false", "-unknownFlag"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "An invalid autoscaling group pattern is an invalid argument.",
+			args:     []string{"-version=false", "-autoScalingGroupsRegex=web-prod-(.*"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "An unknown version source is an invalid argument.",
+			args:     []string{"-version=false", "-versionSource=carrierPigeon"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "An unknown IP family is an invalid argument.",
+			args:     []string{"-version=false", "-ipFamily=ipv5"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Probing the IPv6 addresses of instances succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=true", "-canonical=0.0.0", "-ipFamily=ipv6"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "Reading versions from tags without a tag key is an invalid argument.",
+			args:     []string{"-version=false", "-versionSource=tag"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name: "Failing to connect to AWS is an operational error.",
+			args: []string{"-version=false", "-versionSource=http"},
+			provider: func(p parameters) (integration.CloudProvider, error) {
+				return nil, errors.New("no credentials")
+			},
+			expected: exitError,
+		},
+		{
+			name:     "A dry run which would terminate instances reports the change.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=true", "-canonical=1.0.0"},
+			provider: mock,
+			expected: exitDryRunWouldChange,
+		},
+		{
+			name:     "A dry run with nothing to terminate succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=true", "-canonical=0.0.0"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "An invalid canonical version is an invalid argument.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=true", "-canonical=latest"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name: "Failing to describe the auto scaling groups is an operational error.",
+			args: []string{"-version=false", "-versionSource=http", "-isDryRun=true", "-canonical=1.0.0"},
+			provider: func(p parameters) (integration.CloudProvider, error) {
+				mp := terminatetest.CreateTestData(map[string]string{}, nil)
+				mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+					return nil, errors.New("throttled")
+				}
+				return mp, nil
+			},
+			expected: exitError,
+		},
+		{
+			name:     "An advisory run which finds version skew reports it.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-advisory=true", "-canonical=1.0.0"},
+			provider: mock,
+			expected: exitVersionSkew,
+		},
+		{
+			name:     "An advisory run without version skew succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-advisory=true", "-canonical=0.0.0"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "Terminating instances succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-advisory=false", "-canonical=1.0.0"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "Reaching the maximum number of terminations is a safety abort.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-canonical=1.0.0", "-maxTerminations=1"},
+			provider: mock,
+			expected: exitSafetyAbort,
+		},
+		{
+			name:     "A negative maximum number of terminations other than unlimited is an invalid argument.",
+			args:     []string{"-version=false", "-maxTerminations=-2"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Decrementing the desired capacity without detaching is an invalid argument.",
+			args:     []string{"-version=false", "-maxTerminations=-1", "-detach=false", "-shouldDecrementDesiredCapacity=true"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Decrementing the desired capacity of a canary deploy is an invalid argument.",
+			args:     []string{"-version=false", "-detach=true", "-shouldDecrementDesiredCapacity=true", "-canary=true"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Terminating instances in detach mode succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-canonical=1.0.0", "-canary=false", "-detach=true", "-shouldDecrementDesiredCapacity=true"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "A maximum instance age within the minimum instance age is an invalid argument.",
+			args:     []string{"-version=false", "-minInstanceAge=1h", "-maxInstanceAge=30m"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Suspending launches during a canary deploy is an invalid argument.",
+			args:     []string{"-version=false", "-noReplace=true", "-canary=true"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Suspending launches during a rolling deploy is an invalid argument.",
+			args:     []string{"-version=false", "-noReplace=true", "-canary=false", "-rolling=true"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Terminating instances with launches suspended succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-canonical=1.0.0", "-maxInstanceAge=0", "-noReplace=true", "-canary=false", "-rolling=false"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "An unknown terminate direction is an invalid argument.",
+			args:     []string{"-version=false", "-noReplace=false", "-terminateDirection=sideways"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Only terminating instances below canonical succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-canonical=1.0.0", "-maxInstanceAge=0", "-noReplace=false", "-terminateDirection=below"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "A maximum probe failure rate above 1 is an invalid argument.",
+			args:     []string{"-version=false", "-maxProbeFailureRate=1.5"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Limiting the probe failure rate succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=true", "-canonical=1.0.0", "-maxInstanceAge=0", "-maxProbeFailureRate=0.3"},
+			provider: mock,
+			expected: exitDryRunWouldChange,
+		},
+		{
+			name:     "Moving instances into standby while launches are suspended is an invalid argument.",
+			args:     []string{"-version=false", "-standbyFirst=true", "-noReplace=true"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+		{
+			name:     "Terminating instances from standby succeeds.",
+			args:     []string{"-version=false", "-versionSource=http", "-isDryRun=false", "-canonical=1.0.0", "-maxInstanceAge=0", "-standbyFirst=true", "-noReplace=false", "-shouldDecrementDesiredCapacity=false"},
+			provider: mock,
+			expected: exitOK,
+		},
+		{
+			name:     "A canonical range can't be used with a canonical version.",
+			args:     []string{"-version=false", "-maxInstanceAge=0", "-canonical=1.0.0", "-canonicalRange=>=1.2.0 <2.0.0"},
+			provider: mock,
+			expected: exitInvalidArguments,
+		},
+	}
+
+	for _, test := range tests {
+		actual := -1
+		exit = func(code int) { actual = code }
+		newCloudProvider = test.provider
+		os.Args = append([]string{"terminator"}, test.args...)
+
+		main()
+
+		if actual != test.expected {
+			t.Errorf("For test \"%s\" with arguments %v, expected exit code %d, but got %d", test.name, test.args, test.expected, actual)
+		}
+	}
+}
+
+func TestRunTimeoutStopsTheRun(t *testing.T) {
+	defer func(n func(parameters) (integration.CloudProvider, error), r rangeParam) {
+		newCloudProvider, canonicalRangeFlag = n, r
+		flag.Set("runTimeout", "0")
+	}(newCloudProvider, canonicalRangeFlag)
+	canonicalRangeFlag = rangeParam{}
+
+	// The groups are described slowly, so the run times out before any group is processed.
+	cloud := terminatetest.CreateTestData(map[string]string{}, nil)
+	describe := cloud.DescribeAutoScalingGroupsFunc
+	cloud.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		<-ctx.Done()
+		return describe(ctx, names, scheme, port, path)
+	}
+	newCloudProvider = func(p parameters) (integration.CloudProvider, error) {
+		return cloud, nil
+	}
+
+	start := time.Now()
+	code := run([]string{"-version=false", "-versionSource=http", "-isDryRun=false", "-canonical=1.0.0", "-maxInstanceAge=0", "-runTimeout=10ms"})
+
+	if code != exitError {
+		t.Errorf("Expected the run to stop with exit code %d, but got %d", exitError, code)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the run to stop shortly after the timeout, but it took %v", elapsed)
+	}
+	if len(cloud.TerminatedInstances) != 0 {
+		t.Errorf("Expected nothing to be terminated after the timeout, but got %v", cloud.TerminatedInstances)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() parameters {
+		return parameters{
+			Options: terminate.Options{
+				Scheme:               "http",
+				Port:                 80,
+				MinimumInstanceCount: 1,
+				Canonical:            "1.0.0",
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		modify        func(p *parameters)
+		expectedError string
+	}{
+		{
+			name:   "The defaults are valid.",
+			modify: func(p *parameters) {},
+		},
+		{
+			name:          "A port of 0 is invalid.",
+			modify:        func(p *parameters) { p.Port = 0 },
+			expectedError: "invalid port 0",
+		},
+		{
+			name:          "A port above 65535 is invalid.",
+			modify:        func(p *parameters) { p.Port = 70000 },
+			expectedError: "invalid port 70000",
+		},
+		{
+			name:   "The highest port is valid.",
+			modify: func(p *parameters) { p.Port = 65535 },
+		},
+		{
+			name: "Group configuration with a valid canonical version is valid.",
+			modify: func(p *parameters) {
+				p.GroupOverrides = map[string]terminate.GroupOverride{"asg_api": {Canonical: "1.1.0"}}
+			},
+		},
+		{
+			name: "Group configuration with an invalid canonical version is invalid.",
+			modify: func(p *parameters) {
+				p.GroupOverrides = map[string]terminate.GroupOverride{"asg_api": {Canonical: "latest"}}
+			},
+			expectedError: "invalid groupConfig of asg_api",
+		},
+		{
+			name: "Group configuration with a negative minimum instance count is invalid.",
+			modify: func(p *parameters) {
+				n := -1
+				p.GroupOverrides = map[string]terminate.GroupOverride{"asg_api": {MinimumInstanceCount: &n}}
+			},
+			expectedError: "invalid groupConfig of asg_api, minimumInstanceCount -1",
+		},
+		{
+			name:          "Schemes other than http and https are invalid.",
+			modify:        func(p *parameters) { p.Scheme = "ftp" },
+			expectedError: "invalid scheme \"ftp\"",
+		},
+		{
+			name:   "The https scheme is valid.",
+			modify: func(p *parameters) { p.Scheme = "https" },
+		},
+		{
+			name:          "A negative minimum instance count is invalid.",
+			modify:        func(p *parameters) { p.MinimumInstanceCount = -1 },
+			expectedError: "invalid minimumInstanceCount -1",
+		},
+		{
+			name:   "A minimum instance count of 0 is valid.",
+			modify: func(p *parameters) { p.MinimumInstanceCount = 0 },
+		},
+		{
+			name:          "A canonical version which isn't a semantic version is invalid.",
+			modify:        func(p *parameters) { p.Canonical = "latest" },
+			expectedError: "invalid canonical version \"latest\"",
+		},
+		{
+			name: "A build number is valid with the integer version scheme.",
+			modify: func(p *parameters) {
+				p.VersionScheme, p.Mode, p.VersionSource = integration.VersionSchemeInteger, terminate.ModeCanonical, integration.VersionSourceHTTP
+				p.Canonical = "4821"
+			},
+		},
+		{
+			name: "A semantic version is invalid with the integer version scheme.",
+			modify: func(p *parameters) {
+				p.VersionScheme, p.Mode, p.VersionSource = integration.VersionSchemeInteger, terminate.ModeCanonical, integration.VersionSourceHTTP
+			},
+			expectedError: "invalid canonical version \"1.0.0\", expected a whole number",
+		},
+		{
+			name: "The auto canonical version is invalid with the lexicographic version scheme.",
+			modify: func(p *parameters) {
+				p.VersionScheme, p.Mode, p.VersionSource = integration.VersionSchemeLexicographic, terminate.ModeCanonical, integration.VersionSourceHTTP
+				p.Canonical = terminate.CanonicalAuto
+			},
+			expectedError: "invalid canonical version \"auto\"",
+		},
+		{
+			name: "The enforceGroupModal mode is invalid with the lexicographic version scheme.",
+			modify: func(p *parameters) {
+				p.VersionScheme, p.Mode, p.VersionSource = integration.VersionSchemeLexicographic, terminate.ModeEnforceGroupModal, integration.VersionSourceHTTP
+				p.Canonical = "2024.03.15"
+			},
+			expectedError: "invalid mode \"enforceGroupModal\"",
+		},
+		{
+			name:   "The auto canonical version is valid.",
+			modify: func(p *parameters) { p.Canonical = terminate.CanonicalAuto },
+		},
+		{
+			name: "Version overrides are valid during a dry run.",
+			modify: func(p *parameters) {
+				p.IsDryRun, p.VersionSource, p.versionOverrides = true, integration.VersionSourceHTTP, map[string]string{"i-1": "1.2.0"}
+			},
+		},
+		{
+			name: "Version overrides are invalid when instances are terminated.",
+			modify: func(p *parameters) {
+				p.VersionSource, p.versionOverrides = integration.VersionSourceHTTP, map[string]string{"i-1": "1.2.0"}
+			},
+			expectedError: "invalid versionOverrides, they can only be used with a dry run",
+		},
+		{
+			name: "Version overrides must be versions of the version scheme.",
+			modify: func(p *parameters) {
+				p.IsDryRun, p.VersionSource, p.versionOverrides = true, integration.VersionSourceHTTP, map[string]string{"i-1": "latest"}
+			},
+			expectedError: "invalid version override \"latest\" for instance i-1",
+		},
+		{
+			name:   "An ECS cluster is valid.",
+			modify: func(p *parameters) { p.ecsCluster = "cluster" },
+		},
+		{
+			name: "Tasks of ECS services can't be detached.",
+			modify: func(p *parameters) {
+				p.ecsCluster, p.Detach = "cluster", true
+			},
+			expectedError: "invalid detach, it can't be used with ecsCluster",
+		},
+		{
+			name: "Tasks of ECS services can't be probed through SSH.",
+			modify: func(p *parameters) {
+				p.ecsCluster, p.probeTransport = "cluster", integration.ProbeTransportSSH
+			},
+			expectedError: "invalid probeTransport, it can't be used with ecsCluster",
+		},
+	}
+
+	for _, test := range tests {
+		p := valid()
+		test.modify(&p)
+
+		err := validate(p)
+
+		if test.expectedError == "" && err != nil {
+			t.Errorf("For test \"%s\", expected no error, but got %v", test.name, err)
+		}
+		if test.expectedError != "" && (err == nil || !strings.HasPrefix(err.Error(), test.expectedError)) {
+			t.Errorf("For test \"%s\", expected an error starting %q, but got %v", test.name, test.expectedError, err)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name     string
+		confirm  bool
+		input    string
+		expected bool
+	}{
+		{
+			name:     "Typing yes accepts the termination.",
+			confirm:  true,
+			input:    "yes\n",
+			expected: true,
+		},
+		{
+			name:     "Typing anything else declines the termination.",
+			confirm:  true,
+			input:    "y\n",
+			expected: false,
+		},
+		{
+			name:     "Closing the input declines the termination.",
+			confirm:  true,
+			input:    "",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		out := new(bytes.Buffer)
+		confirm := newConfirm(test.confirm, false, strings.NewReader(test.input), out)
+
+		if actual := confirm("Group2", []string{"D", "E"}); actual != test.expected {
+			t.Errorf("For test \"%s\", expected %v, but got %v", test.name, test.expected, actual)
+		}
+		if !strings.Contains(out.String(), "Group2 [D E]") {
+			t.Errorf("For test \"%s\", expected the targets to be printed, but got %q", test.name, out.String())
+		}
+	}
+}
+
+func TestConfirmCanBeSkipped(t *testing.T) {
+	if confirm := newConfirm(true, true, strings.NewReader(""), new(bytes.Buffer)); confirm != nil {
+		t.Error("Expected yes or force to skip the prompt")
+	}
+	if confirm := newConfirm(false, false, strings.NewReader(""), new(bytes.Buffer)); confirm != nil {
+		t.Error("Expected no prompt unless confirm is set")
+	}
+}
+
+func TestPrintVersionDetailsListsEveryInstance(t *testing.T) {
+	launched := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	cloud := terminatetest.CreateTestData(
+		map[string]string{"A": "1.0.0", "B": "1.1.0", "C": "1.1.0", "D": "1.1.0", "E": "1.1.0", "F": "1.1.0", "G": "1.2.0"},
+		map[string]time.Time{"A": launched, "B": launched, "C": launched, "D": launched, "E": launched, "F": launched, "G": launched})
+	cloud.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		t.Errorf("Expected the report not to terminate anything, but %v were terminated", instanceIDs)
+		return nil
+	}
+	cloud.TerminateGroupInstancesFunc = func(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error {
+		t.Errorf("Expected the report not to terminate anything, but %v were terminated", instanceIDs)
+		return nil
+	}
+	describe := cloud.DescribeAutoScalingGroupsFunc
+	cloud.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		groups, err := describe(ctx, names, scheme, port, path)
+		groups[0].Instances[0].InstanceType = "m5.large"
+		groups[0].Instances[0].LaunchTemplateName, groups[0].Instances[0].LaunchTemplateVersion = "web", "3"
+		groups[1].Instances[3].LaunchConfigurationName = "web-v2"
+		return groups, err
+	}
+
+	var out bytes.Buffer
+	if err := printVersionDetails(context.Background(), &out, cloud, terminate.Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	for _, expected := range []string{
+		"Group1  A         1.0.0    2024-03-15T09:30:00Z  m5.large  web:3     Healthy InService",
+		"Group1  C         1.1.0    2024-03-15T09:30:00Z  unknown   unknown   Healthy OutOfService",
+		"Group2  G         1.2.0    2024-03-15T09:30:00Z  unknown   web-v2    Healthy InService",
+		"Group2 => 4 instances, most common version 1.1.0 [D E F], highest 1.2.0 [G], lowest 1.1.0 [D E F]",
+	} {
+		found := false
+		for _, line := range lines {
+			if strings.TrimRight(line, " ") == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected the report to contain %q, but got:\n%s", expected, out.String())
+		}
+	}
+
+	for _, id := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		if !strings.Contains(out.String(), " "+id+" ") {
+			t.Errorf("Expected the report to list instance %s", id)
+		}
+	}
+}
+
+func TestTheReportCommandIsTheReportFlag(t *testing.T) {
+	actual := expandCommand([]string{"report", "-region=eu-west-1"})
+
+	if strings.Join(actual, " ") != "-report -region=eu-west-1" {
+		t.Errorf("Expected the report command to be expanded to the report flag, but got %v", actual)
+	}
+
+	if actual := expandCommand([]string{"-region=eu-west-1"}); strings.Join(actual, " ") != "-region=eu-west-1" {
+		t.Errorf("Expected arguments without a command to be unchanged, but got %v", actual)
+	}
+}
+
+func TestTheCanonicalVersionIsFetchedBeforeEachRun(t *testing.T) {
+	version, status := "1.1.0\n", http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, version)
+	}))
+	defer server.Close()
+
+	p := parameters{
+		Options: terminate.Options{
+			AutoScalingGroups:    []string{"Group2"},
+			Canonical:            "1.0.0",
+			MinimumInstanceCount: 2,
+			Mode:                 terminate.ModeCanonical,
+		},
+		canonicalURL: server.URL,
+	}
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+	clouds := []regionalCloud{{region: "eu-west-1", cloud: mp}}
+
+	if code := runOnce(context.Background(), clouds, p, nil); code != exitOK {
+		t.Fatalf("Expected exit code %d, but got %d", exitOK, code)
+	}
+
+	if strings.Join(mp.TerminatedInstances, ",") != "D,E" {
+		t.Errorf("Expected the instances below the fetched canonical version to be terminated, but got %v", mp.TerminatedInstances)
+	}
+
+	// The pipeline hasn't published a valid version, so the run is aborted.
+	for _, failure := range []struct {
+		version string
+		status  int
+	}{
+		{version: "AccessDenied", status: http.StatusForbidden},
+		{version: "latest", status: http.StatusOK},
+	} {
+		version, status = failure.version, failure.status
+		mp.TerminatedInstances = nil
+
+		if code := runOnce(context.Background(), clouds, p, nil); code != exitError {
+			t.Errorf("Expected the run to be aborted with exit code %d when the URL returns %q, but got %d", exitError, version, code)
+		}
+
+		if len(mp.TerminatedInstances) != 0 {
+			t.Errorf("Expected nothing to be terminated, but got %v", mp.TerminatedInstances)
+		}
+	}
+}
+
+func TestAnInterruptedRunHasItsOwnExitCode(t *testing.T) {
+	p := parameters{
+		Options: terminate.Options{
+			Canonical:            "1.1.0",
+			MinimumInstanceCount: 1,
+			Mode:                 terminate.ModeCanonical,
+		},
+	}
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	clouds := []regionalCloud{{region: "eu-west-1", cloud: mp}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if code := runOnce(ctx, clouds, p, nil); code != exitInterrupted {
+		t.Errorf("Expected exit code %d, but got %d", exitInterrupted, code)
+	}
+
+	if len(mp.TerminatedInstances) != 0 {
+		t.Errorf("Expected nothing to be terminated, but got %v", mp.TerminatedInstances)
+	}
+}
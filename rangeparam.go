@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/a-h/terminator/terminate"
+)
+
+// rangeParam is a flag which holds a range of semantic versions, e.g. ">=1.2.0 <2.0.0".
+type rangeParam struct {
+	terminate.VersionRange
+}
+
+func (r *rangeParam) String() string {
+	return r.Expression
+}
+
+func (r *rangeParam) Set(value string) error {
+	rng, err := terminate.ParseVersionRange(value)
+
+	if err != nil {
+		return err
+	}
+
+	r.VersionRange = rng
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func TestRangeParam(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		version    string
+		expected   bool
+	}{
+		{
+			name:       "Versions inside the range are accepted.",
+			expression: ">=1.2.0 <2.0.0",
+			version:    "1.9.3",
+			expected:   true,
+		},
+		{
+			name:       "The lower bound is inclusive.",
+			expression: ">=1.2.0 <2.0.0",
+			version:    "1.2.0",
+			expected:   true,
+		},
+		{
+			name:       "Versions below the range aren't accepted.",
+			expression: ">=1.2.0 <2.0.0",
+			version:    "1.1.9",
+			expected:   false,
+		},
+		{
+			name:       "The upper bound is exclusive.",
+			expression: ">=1.2.0 <2.0.0",
+			version:    "2.0.0",
+			expected:   false,
+		},
+	}
+
+	for _, test := range tests {
+		var r rangeParam
+		if err := r.Set(test.expression); err != nil {
+			t.Fatalf("For test \"%s\", expected the range to parse, but got %v", test.name, err)
+		}
+
+		if actual := r.Range(semver.MustParse(test.version)); actual != test.expected {
+			t.Errorf("For test \"%s\", expected %s in %q to be %v, but got %v", test.name, test.version, test.expression, test.expected, actual)
+		}
+
+		if r.String() != test.expression {
+			t.Errorf("For test \"%s\", expected the expression %q, but got %q", test.name, test.expression, r.String())
+		}
+	}
+}
+
+func TestInvalidRangeParam(t *testing.T) {
+	var r rangeParam
+	if err := r.Set(">=latest"); err == nil {
+		t.Error("Expected an invalid range to be rejected")
+	}
+}
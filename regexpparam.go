@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// regexpParam is a flag which holds a regular expression that must match the whole of a value.
+type regexpParam struct {
+	*regexp.Regexp
+	pattern string
+}
+
+func (r *regexpParam) String() string {
+	return r.pattern
+}
+
+func (r *regexpParam) Set(value string) error {
+	re, err := regexp.Compile("^(?:" + value + ")$")
+
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q, %v", value, err)
+	}
+
+	r.Regexp = re
+	r.pattern = value
+	return nil
+}
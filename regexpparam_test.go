@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestRegexpParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{
+			name:     "Matching names are selected.",
+			pattern:  "web-prod-.*",
+			value:    "web-prod-v2",
+			expected: true,
+		},
+		{
+			name:     "Non-matching names aren't selected.",
+			pattern:  "web-prod-.*",
+			value:    "web-dev-v2",
+			expected: false,
+		},
+		{
+			name:     "The pattern must match the whole name.",
+			pattern:  "web-prod-.*",
+			value:    "old-web-prod-v1",
+			expected: false,
+		},
+		{
+			name:     "Alternatives match the whole name.",
+			pattern:  "api|web",
+			value:    "api-dev",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		var r regexpParam
+		if err := r.Set(test.pattern); err != nil {
+			t.Fatalf("For test \"%s\", expected the pattern to compile, but got %v", test.name, err)
+		}
+
+		if actual := r.MatchString(test.value); actual != test.expected {
+			t.Errorf("For test \"%s\", expected %q matching %q to be %v, but got %v", test.name, test.value, test.pattern, test.expected, actual)
+		}
+
+		if r.String() != test.pattern {
+			t.Errorf("For test \"%s\", expected the pattern %q, but got %q", test.name, test.pattern, r.String())
+		}
+	}
+}
+
+func TestInvalidRegexpParam(t *testing.T) {
+	var r regexpParam
+	if err := r.Set("web-prod-(.*"); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
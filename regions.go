@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate"
+)
+
+// regionalCloud is the provider used to access a single region.
+type regionalCloud struct {
+	region string
+	cloud  integration.CloudProvider
+}
+
+// newRegionalClouds creates a provider for each of the regions.
+func newRegionalClouds(p parameters) ([]regionalCloud, error) {
+	clouds := make([]regionalCloud, len(p.regions))
+
+	for i, region := range p.regions {
+		p.region = region
+		cloud, err := newCloudProvider(p)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", region, err)
+		}
+
+		clouds[i] = regionalCloud{region: region, cloud: cloud}
+	}
+
+	return clouds, nil
+}
+
+// terminateRegions runs the termination pass in each region in turn, and combines the results. A failure in
+// one region doesn't stop the others, but the maximum number of terminations is shared by all of the regions.
+func terminateRegions(ctx context.Context, clouds []regionalCloud, o terminate.Options) (terminate.Result, error) {
+	combined := terminate.Result{Terminated: []string{}, Kept: []terminate.KeptInstance{}, NoActions: []terminate.NoAction{}}
+	var failures []string
+	skewed := false
+
+	// Messages are tagged with the region, so the output of each region can be told apart.
+	logger := slog.Default()
+	defer slog.SetDefault(logger)
+
+	for _, rc := range clouds {
+		slog.SetDefault(logger.With("region", rc.region))
+
+		regional := o
+		if o.MaxTerminations != nil {
+			remaining := *o.MaxTerminations - len(combined.Terminated)
+			regional.MaxTerminations = &remaining
+		}
+
+		result, err := terminate.Run(ctx, rc.cloud, regional)
+		combined.Terminated = append(combined.Terminated, result.Terminated...)
+		combined.Kept = append(combined.Kept, result.Kept...)
+		combined.NoActions = append(combined.NoActions, result.NoActions...)
+		combined.Summary.Add(result.Summary)
+		combined.Plan = mergePlans(combined.Plan, result.Plan)
+
+		if err == terminate.ErrMaxTerminations || err == terminate.ErrInterrupted || err == terminate.ErrTooManyProbeFailures {
+			return combined, err
+		}
+
+		if err == terminate.ErrVersionSkew {
+			skewed = true
+			continue
+		}
+
+		if err != nil {
+			slog.Error("failed to terminate instances in the region", "error", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", rc.region, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return combined, fmt.Errorf("%d of %d regions failed, %s", len(failures), len(clouds), strings.Join(failures, "; "))
+	}
+
+	if skewed {
+		return combined, terminate.ErrVersionSkew
+	}
+
+	return combined, nil
+}
+
+// mergePlans adds the groups of the next region's plan to the combined plan, so that a single plan covers all of
+// the regions.
+func mergePlans(combined *terminate.Plan, next *terminate.Plan) *terminate.Plan {
+	if next == nil {
+		return combined
+	}
+	if combined == nil {
+		combined = &terminate.Plan{Groups: []terminate.PlanGroup{}}
+	}
+
+	combined.Groups = append(combined.Groups, next.Groups...)
+	if next.Note != "" && !strings.Contains(combined.Note, next.Note) {
+		if combined.Note != "" {
+			combined.Note += "; "
+		}
+		combined.Note += next.Note
+	}
+
+	return combined
+}
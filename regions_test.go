@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate"
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func newRegionalMocks() (map[string]*terminatetest.MockProvider, func()) {
+	mocks := map[string]*terminatetest.MockProvider{
+		"eu-west-1": terminatetest.NewMockProvider([]integration.AutoScalingGroup{terminatetest.NewHealthyGroup("asg_eu", "1.0.0", "eu-a", "eu-b", "eu-c")}, "1.0.0", nil, time.Now(), nil),
+		"us-east-1": terminatetest.NewMockProvider([]integration.AutoScalingGroup{terminatetest.NewHealthyGroup("asg_us", "1.0.0", "us-a", "us-b", "us-c")}, "1.0.0", nil, time.Now(), nil),
+	}
+
+	previous := newCloudProvider
+	newCloudProvider = func(p parameters) (integration.CloudProvider, error) {
+		mp, ok := mocks[p.region]
+		if !ok {
+			return nil, errors.New("unknown region " + p.region)
+		}
+		return mp, nil
+	}
+
+	return mocks, func() { newCloudProvider = previous }
+}
+
+func TestTerminatingMultipleRegions(t *testing.T) {
+	mocks, restore := newRegionalMocks()
+	defer restore()
+
+	clouds, err := newRegionalClouds(parameters{regions: []string{"eu-west-1", "us-east-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := terminateRegions(context.Background(), clouds, terminate.Options{
+		MinimumInstanceCount: 2,
+		Canonical:            "1.1.0",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"eu-a", "us-a"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected the terminations of both regions %v to be combined, but got %v", expected, result.Terminated)
+	}
+	for region, mp := range mocks {
+		if len(mp.TerminatedInstances) != 1 {
+			t.Errorf("Expected an instance to be terminated in %s, but got %v", region, mp.TerminatedInstances)
+		}
+	}
+}
+
+func TestThePlansOfTheRegionsAreCombined(t *testing.T) {
+	_, restore := newRegionalMocks()
+	defer restore()
+
+	clouds, err := newRegionalClouds(parameters{regions: []string{"eu-west-1", "us-east-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := terminateRegions(context.Background(), clouds, terminate.Options{
+		IsDryRun:             true,
+		Plan:                 true,
+		MinimumInstanceCount: 2,
+		Canonical:            "1.1.0",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Plan == nil {
+		t.Fatal("Expected a plan")
+	}
+	groups := []string{}
+	for _, g := range result.Plan.Groups {
+		groups = append(groups, g.Name)
+	}
+	if expected := []string{"asg_eu", "asg_us"}; !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected the plan to cover the groups of both regions %v, but got %v", expected, groups)
+	}
+}
+
+func TestTheMaximumTerminationsIsSharedByTheRegions(t *testing.T) {
+	mocks, restore := newRegionalMocks()
+	defer restore()
+
+	clouds, err := newRegionalClouds(parameters{regions: []string{"eu-west-1", "us-east-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxTerminations := 3
+	result, err := terminateRegions(context.Background(), clouds, terminate.Options{
+		MinimumInstanceCount: 1,
+		MaxTerminations:      &maxTerminations,
+		Canonical:            "1.1.0",
+	})
+
+	if err != terminate.ErrMaxTerminations {
+		t.Errorf("Expected the maximum terminations to be reached, but got %v", err)
+	}
+
+	sort.Strings(result.Terminated)
+	if expected := []string{"eu-a", "eu-b", "us-a"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, result.Terminated)
+	}
+	if len(mocks["us-east-1"].TerminatedInstances) != 1 {
+		t.Errorf("Expected only the remaining termination in us-east-1, but got %v", mocks["us-east-1"].TerminatedInstances)
+	}
+}
+
+func TestAFailedRegionDoesNotStopTheOthers(t *testing.T) {
+	mocks, restore := newRegionalMocks()
+	defer restore()
+
+	mocks["eu-west-1"].DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		return nil, errors.New("access denied")
+	}
+
+	clouds, err := newRegionalClouds(parameters{regions: []string{"eu-west-1", "us-east-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := terminateRegions(context.Background(), clouds, terminate.Options{
+		MinimumInstanceCount: 2,
+		Canonical:            "1.1.0",
+	})
+
+	if err == nil || !strings.HasPrefix(err.Error(), "1 of 2 regions failed, eu-west-1:") {
+		t.Errorf("Expected the failure in eu-west-1 to be returned, but got %v", err)
+	}
+	if expected := []string{"us-a"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, result.Terminated)
+	}
+}
+
+func TestUnknownRegionsFailToConnect(t *testing.T) {
+	_, restore := newRegionalMocks()
+	defer restore()
+
+	if _, err := newRegionalClouds(parameters{regions: []string{"eu-west-1", "mars-north-1"}}); err == nil {
+		t.Error("Expected an error creating the provider of an unknown region")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/a-h/terminator/terminate"
+)
+
+// remoteConfig is the JSON document at --configURL. Fields which are present override the parameters set on
+// the command line, e.g. {"canonical":"1.2.0","isDryRun":false}
+type remoteConfig struct {
+	Canonical            *string `json:"canonical"`
+	IsDryRun             *bool   `json:"isDryRun"`
+	MinimumInstanceCount *int    `json:"minimumInstanceCount"`
+	Mode                 *string `json:"mode"`
+}
+
+func (c remoteConfig) apply(p parameters) (parameters, error) {
+	if c.Canonical != nil {
+		if err := validateCanonical(*c.Canonical, p.VersionScheme); err != nil {
+			return p, err
+		}
+		p.Canonical = *c.Canonical
+	}
+
+	if c.IsDryRun != nil {
+		if !*c.IsDryRun && len(p.versionOverrides) > 0 {
+			return p, errors.New("invalid isDryRun, versionOverrides can only be used with a dry run")
+		}
+		p.IsDryRun = *c.IsDryRun
+	}
+
+	if c.MinimumInstanceCount != nil {
+		if *c.MinimumInstanceCount < 0 {
+			return p, fmt.Errorf("invalid minimumInstanceCount %d, it can't be negative", *c.MinimumInstanceCount)
+		}
+		p.MinimumInstanceCount = *c.MinimumInstanceCount
+	}
+
+	if c.Mode != nil {
+		if *c.Mode != terminate.ModeCanonical && *c.Mode != terminate.ModeEnforceGroupModal {
+			return p, fmt.Errorf("invalid mode %q", *c.Mode)
+		}
+		if *c.Mode != terminate.ModeCanonical && !p.VersionScheme.IsSemver() {
+			return p, fmt.Errorf("invalid mode %q, only the %q mode can be used with the %q versionScheme", *c.Mode, terminate.ModeCanonical, p.VersionScheme)
+		}
+		p.Mode = *c.Mode
+	}
+
+	return p, nil
+}
+
+// remoteConfigTimeout is the longest the remote configuration takes to fetch before the run goes ahead with the
+// last good parameters.
+const remoteConfigTimeout = 30 * time.Second
+
+// configRefresher fetches the parameters from the remote configuration URL before each run. Each configuration
+// is applied to the initial parameters, so that removing a field from it restores the value set on the command
+// line. When the configuration can't be fetched, or is invalid, the last good parameters are kept.
+type configRefresher struct {
+	url      string
+	client   *http.Client
+	initial  parameters
+	lastGood parameters
+}
+
+func newConfigRefresher(url string, initial parameters) *configRefresher {
+	return &configRefresher{
+		url:      url,
+		client:   &http.Client{Timeout: remoteConfigTimeout},
+		initial:  initial,
+		lastGood: initial,
+	}
+}
+
+// refresh returns the parameters to use for the next run.
+func (r *configRefresher) refresh(ctx context.Context) parameters {
+	next, err := r.fetch(ctx)
+
+	if err != nil {
+		slog.Warn("failed to refresh the configuration, keeping the last good configuration", "url", r.url, "error", err)
+		return r.lastGood
+	}
+
+	for _, change := range describeChanges(r.lastGood, next) {
+		slog.Info("configuration changed", "url", r.url, "change", change)
+	}
+
+	r.lastGood = next
+	return next
+}
+
+func (r *configRefresher) fetch(ctx context.Context) (parameters, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", r.url, nil)
+
+	if err != nil {
+		return r.lastGood, err
+	}
+
+	resp, err := r.client.Do(request)
+
+	if err != nil {
+		return r.lastGood, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return r.lastGood, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var c remoteConfig
+	decoder := json.NewDecoder(resp.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&c); err != nil {
+		return r.lastGood, errors.New("invalid JSON, " + err.Error())
+	}
+
+	return c.apply(r.initial)
+}
+
+func describeChanges(from, to parameters) (changes []string) {
+	if from.Canonical != to.Canonical {
+		changes = append(changes, fmt.Sprintf("canonical from %s to %s", from.Canonical, to.Canonical))
+	}
+	if from.IsDryRun != to.IsDryRun {
+		changes = append(changes, fmt.Sprintf("isDryRun from %t to %t", from.IsDryRun, to.IsDryRun))
+	}
+	if from.MinimumInstanceCount != to.MinimumInstanceCount {
+		changes = append(changes, fmt.Sprintf("minimumInstanceCount from %d to %d", from.MinimumInstanceCount, to.MinimumInstanceCount))
+	}
+	if from.Mode != to.Mode {
+		changes = append(changes, fmt.Sprintf("mode from %s to %s", from.Mode, to.Mode))
+	}
+	return changes
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/terminate"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestRefreshingTheRemoteConfiguration(t *testing.T) {
+	config := `{"canonical":"1.1.0"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, config)
+	}))
+	defer server.Close()
+
+	r := newConfigRefresher(server.URL, parameters{
+		Options: terminate.Options{
+			Canonical:            "1.0.0",
+			IsDryRun:             true,
+			MinimumInstanceCount: 1,
+			Mode:                 terminate.ModeCanonical,
+		},
+	})
+
+	p := r.refresh(context.Background())
+
+	if p.Canonical != "1.1.0" || !p.IsDryRun || p.MinimumInstanceCount != 1 {
+		t.Errorf("Expected only the canonical version to change on the first run, but got %+v", p)
+	}
+
+	// The configuration is changed between runs.
+	config = `{"canonical":"1.2.0","isDryRun":false,"minimumInstanceCount":2}`
+	p = r.refresh(context.Background())
+
+	if p.Canonical != "1.2.0" || p.IsDryRun || p.MinimumInstanceCount != 2 {
+		t.Errorf("Expected the changed configuration to be applied on the second run, but got %+v", p)
+	}
+
+	invalid := []string{
+		`{"canonical":"latest"}`,
+		`{"minimumInstanceCount":-1}`,
+		`{"mode":"destroyEverything"}`,
+		`{"isDryRun":false,"unknown":true}`,
+		`{"canonical":`,
+	}
+
+	for _, config = range invalid {
+		p = r.refresh(context.Background())
+
+		if p.Canonical != "1.2.0" || p.IsDryRun || p.MinimumInstanceCount != 2 {
+			t.Errorf("Expected the invalid configuration %s to be ignored, but got %+v", config, p)
+		}
+	}
+
+	// Fields which are removed from the configuration revert to the values set on the command line.
+	config = `{"canonical":"1.2.0"}`
+	p = r.refresh(context.Background())
+
+	if p.Canonical != "1.2.0" || !p.IsDryRun || p.MinimumInstanceCount != 1 {
+		t.Errorf("Expected the removed fields to revert to their initial values, but got %+v", p)
+	}
+
+	server.Close()
+	p = r.refresh(context.Background())
+
+	if p.Canonical != "1.2.0" {
+		t.Errorf("Expected the last good configuration to be kept when the server is unavailable, but got %+v", p)
+	}
+}
+
+func TestRefreshingTheRemoteConfigurationTimesOut(t *testing.T) {
+	stop := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stop
+	}))
+	defer server.Close()
+	defer close(stop)
+
+	r := newConfigRefresher(server.URL, parameters{Options: terminate.Options{Canonical: "1.0.0"}})
+	r.client.Timeout = 10 * time.Millisecond
+
+	done := make(chan parameters)
+	go func() { done <- r.refresh(context.Background()) }()
+
+	select {
+	case p := <-done:
+		if p.Canonical != "1.0.0" {
+			t.Errorf("Expected the initial configuration to be kept when the server doesn't answer, but got %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the refresh to time out")
+	}
+}
+
+func TestRemoteConfigurationCannotEndADryRunWithVersionOverrides(t *testing.T) {
+	p := parameters{
+		Options:          terminate.Options{Canonical: "1.0.0", IsDryRun: true},
+		versionOverrides: map[string]string{"i-1": "1.2.0"},
+	}
+
+	if _, err := (remoteConfig{IsDryRun: aws.Bool(false)}).apply(p); err == nil {
+		t.Error("Expected an error when the remote configuration ends the dry run of rehearsed versions")
+	}
+}
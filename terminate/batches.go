@@ -0,0 +1,304 @@
+package terminate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+)
+
+// rollingPollInterval is how often the group is checked for replacement instances in rolling mode.
+const rollingPollInterval = 15 * time.Second
+
+// sleep waits for the duration, or until the context is cancelled, tests replace it to avoid waiting.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// terminateInBatches terminates the instances of the group terminationBatchSize at a time. Between batches, it
+// waits for the cooldown, and in rolling mode, for the group to replace the terminated instances, then checks the
+// next batch won't take the group below its minimum, e.g. because replacements are failing their health checks.
+// It returns the IDs of the instances which were terminated, alongside any error.
+func terminateInBatches(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, ids []string, p Options) ([]string, error) {
+	batchSize := p.TerminationBatchSize
+	if batchSize <= 0 && p.Rolling {
+		batchSize = 1
+	}
+	if batchSize <= 0 {
+		batchSize = len(ids)
+	}
+
+	terminated := []string{}
+	floor := g.Floor(p.MinimumInstanceCount, p.MinimumHealthyPercentage)
+
+	for start := 0; start < len(ids); start += batchSize {
+		if start > 0 && ctx.Err() != nil {
+			slog.Warn("interrupted, the remaining batches were skipped", "group", g.Name, "terminated", terminated)
+			return terminated, ctx.Err()
+		}
+
+		if start > 0 && p.Rolling {
+			if err := waitForHealthy(ctx, cloud, g, terminated, p); err != nil {
+				return terminated, err
+			}
+		}
+
+		if start > 0 && p.TerminationCooldown > 0 {
+			slog.Info("waiting before terminating the next batch", "group", g.Name, "cooldown", p.TerminationCooldown)
+
+			if err := sleep(ctx, p.TerminationCooldown); err != nil {
+				return terminated, err
+			}
+		}
+
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		batch := ids[start:end]
+
+		if start > 0 && floor > 0 {
+			if err := checkFloor(ctx, cloud, g, terminated, batch, floor, p); err != nil {
+				return terminated, err
+			}
+		}
+
+		if len(batch) < len(ids) {
+			slog.Info("terminating batch", "group", g.Name, "instances", batch)
+		}
+
+		if p.DrainBeforeTerminate {
+			if err := drain(ctx, cloud, g, batch, p); err != nil {
+				return terminated, err
+			}
+		}
+
+		if err := terminateInstances(ctx, cloud, g, batch, p); err != nil {
+			return terminated, err
+		}
+
+		terminated = append(terminated, batch...)
+	}
+
+	return terminated, nil
+}
+
+// checkFloor describes the group again, and returns an error if terminating the batch would leave less healthy
+// capacity than the floor. The instances which were already terminated aren't counted, since the group can
+// report them as healthy for a while after they're terminated.
+func checkFloor(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, terminated []string, batch []string, floor int, p Options) error {
+	groups, err := cloud.DescribeAutoScalingGroups(ctx, []string{g.Name}, p.Scheme, p.Port, p.VersionURL)
+
+	if err != nil {
+		return fmt.Errorf("failed to check the health of the group before terminating %v, %v", batch, err)
+	}
+
+	for _, current := range groups {
+		if current.Name != g.Name {
+			continue
+		}
+
+		remaining := current.HealthyCapacity(append(append([]string{}, terminated...), batch...))
+		if remaining < float64(floor) {
+			slog.Warn("stopping, the group's health has dropped", "group", g.Name, "healthy", current.HealthyCapacity(terminated), "floor", floor, "skipped", batch)
+			return fmt.Errorf("stopped before terminating %v, which would leave a healthy capacity of %v, below the minimum of %d", batch, remaining, floor)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("failed to check the health of the group before terminating %v, it wasn't found", batch)
+}
+
+// terminateInstances terminates the instances through the auto-scaling API in detach mode, so the group's
+// desired capacity can be decremented, and its lifecycle hooks run, otherwise through the EC2 API. With
+// standbyFirst, the instances are moved into standby first, and terminated from there.
+func terminateInstances(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, ids []string, p Options) error {
+	// Once started, the batch is terminated even if the run is interrupted, so it isn't left half-terminated.
+	ctx = context.WithoutCancel(ctx)
+
+	if p.StandbyFirst {
+		if err := cloud.EnterStandby(ctx, g.Name, ids); err != nil {
+			return err
+		}
+	}
+
+	if !p.Detach {
+		return cloud.TerminateInstances(ctx, ids)
+	}
+
+	if err := cloud.TerminateGroupInstances(ctx, ids, p.DecrementDesiredCapacity); err != nil {
+		return err
+	}
+
+	// Terminating through the auto-scaling API runs the group's termination lifecycle hooks.
+	if p.CompleteLifecycleHooks {
+		for _, id := range ids {
+			if err := cloud.CompleteLifecycle(ctx, g.Name, id); err != nil {
+				slog.Warn("failed to complete the lifecycle hooks, they'll be completed by the next run", "group", g.Name, "instance", id, "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// drain stops the instances receiving new connections, then waits for the drainTimeout to give the
+// connections they're already serving time to complete. With the targetGroups drain mode, the provider waits
+// for the target groups to finish draining each instance instead, so the instances are drained at the same time.
+func drain(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, ids []string, p Options) error {
+	if p.DrainMode == integration.DrainModeTargetGroups {
+		errs := make([]error, len(ids))
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, id string) {
+				defer wg.Done()
+				errs[i] = cloud.DrainInstance(ctx, g.Name, id)
+			}(i, id)
+		}
+		wg.Wait()
+
+		return errors.Join(errs...)
+	}
+
+	for _, id := range ids {
+		if err := cloud.DrainInstance(ctx, g.Name, id); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("waiting for connections to drain", "group", g.Name, "instances", ids, "drainTimeout", p.DrainTimeout)
+
+	return sleep(ctx, p.DrainTimeout)
+}
+
+// waitForHealthy polls the group until its healthy capacity, leaving out the terminated instances which the group
+// can still report as healthy, reaches its desired capacity, or the rollingTimeout is reached.
+func waitForHealthy(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, terminated []string, p Options) error {
+	ctx, cancel := context.WithTimeout(ctx, p.RollingTimeout)
+	defer cancel()
+
+	for {
+		if err := sleep(ctx, rollingPollInterval); err != nil {
+			if err == context.DeadlineExceeded {
+				return fmt.Errorf("timed out after %v waiting for %d healthy instances", p.RollingTimeout, g.DesiredCapacity)
+			}
+			return err
+		}
+
+		groups, err := cloud.DescribeAutoScalingGroups(ctx, []string{g.Name}, p.Scheme, p.Port, p.VersionURL)
+
+		if err != nil {
+			slog.Warn("failed to check for replacement instances", "group", g.Name, "error", err)
+			continue
+		}
+
+		for _, current := range groups {
+			if current.Name != g.Name {
+				continue
+			}
+
+			healthy := current.HealthyCapacity(terminated)
+			slog.Info("waiting for replacement instances", "group", g.Name, "healthy", healthy, "desired", current.DesiredCapacity)
+
+			if healthy >= float64(current.DesiredCapacity) {
+				return nil
+			}
+		}
+	}
+}
+
+// terminateWithCanary terminates a single instance of the group, and waits for its replacement to run the wanted
+// version before terminating the rest. If the replacement runs another version, the deploy is assumed to be
+// broken, and the rest of the instances are left running.
+func terminateWithCanary(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, ids []string, want targetVersion, p Options) ([]string, error) {
+	canary := ids[0]
+	slog.Info("terminating canary instance", "group", g.Name, "instance", canary)
+
+	if p.DrainBeforeTerminate {
+		if err := drain(ctx, cloud, g, []string{canary}, p); err != nil {
+			return []string{}, err
+		}
+	}
+
+	if err := terminateInstances(ctx, cloud, g, []string{canary}, p); err != nil {
+		return []string{}, err
+	}
+
+	if err := waitForReplacement(ctx, cloud, g, want, p); err != nil {
+		return []string{canary}, fmt.Errorf("canary %s failed, %v", canary, err)
+	}
+
+	slog.Info("canary succeeded, terminating the remaining instances", "group", g.Name, "count", len(ids)-1)
+
+	terminated, err := terminateInBatches(ctx, cloud, g, ids[1:], p)
+	return append([]string{canary}, terminated...), err
+}
+
+// waitForReplacement polls the group until a new healthy instance reports the wanted version, or the
+// canaryTimeout is reached.
+func waitForReplacement(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, want targetVersion, p Options) error {
+	ctx, cancel := context.WithTimeout(ctx, p.CanaryTimeout)
+	defer cancel()
+
+	existing := map[string]bool{}
+	for _, instance := range g.Instances {
+		existing[instance.ID] = true
+	}
+
+	for {
+		if err := sleep(ctx, rollingPollInterval); err != nil {
+			if err == context.DeadlineExceeded {
+				return fmt.Errorf("timed out after %v waiting for a replacement instance", p.CanaryTimeout)
+			}
+			return err
+		}
+
+		groups, err := cloud.DescribeAutoScalingGroups(ctx, []string{g.Name}, p.Scheme, p.Port, p.VersionURL)
+
+		if err != nil {
+			slog.Warn("failed to check for a replacement instance", "group", g.Name, "error", err)
+			continue
+		}
+
+		for _, current := range groups {
+			if current.Name != g.Name {
+				continue
+			}
+
+			for _, instance := range current.Instances {
+				if existing[instance.ID] || !current.IsHealthy(instance) {
+					continue
+				}
+
+				detail, err := cloud.GetDetail(ctx, instance.ID, p.Scheme, p.Port, p.VersionURL)
+
+				if err != nil {
+					slog.Info("replacement isn't ready", "group", g.Name, "instance", instance.ID, "error", err)
+					continue
+				}
+
+				if !want.accepts(*detail) {
+					return fmt.Errorf("replacement instance %s is running version %s, expected %s", instance.ID, detail.Version(), want)
+				}
+
+				slog.Info("replacement is running the expected version", "group", g.Name, "instance", instance.ID, "version", detail.Version())
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,604 @@
+package terminate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate/terminatetest"
+	"github.com/blang/semver"
+)
+
+func TestTerminateInBatches(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+
+	tests := []struct {
+		name            string
+		ids             []string
+		batchSize       int
+		cooldown        time.Duration
+		expectedBatches [][]string
+		expectedWaits   int
+	}{
+		{
+			name:            "Without a batch size, all instances are terminated at once.",
+			ids:             []string{"A", "B", "C", "D", "E"},
+			batchSize:       0,
+			cooldown:        time.Minute,
+			expectedBatches: [][]string{{"A", "B", "C", "D", "E"}},
+			expectedWaits:   0,
+		},
+		{
+			name:            "The last batch contains the remainder.",
+			ids:             []string{"A", "B", "C", "D", "E"},
+			batchSize:       2,
+			cooldown:        time.Minute,
+			expectedBatches: [][]string{{"A", "B"}, {"C", "D"}, {"E"}},
+			expectedWaits:   2,
+		},
+		{
+			name:            "Without a cooldown, batches are terminated without waiting.",
+			ids:             []string{"A", "B", "C"},
+			batchSize:       1,
+			cooldown:        0,
+			expectedBatches: [][]string{{"A"}, {"B"}, {"C"}},
+			expectedWaits:   0,
+		},
+	}
+
+	for _, test := range tests {
+		var waits []time.Duration
+		sleep = func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+
+		var batches [][]string
+		mp := terminatetest.CreateTestData(map[string]string{}, nil)
+		mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+			batches = append(batches, instanceIDs)
+			return nil
+		}
+
+		terminated, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, test.ids, Options{
+			TerminationBatchSize: test.batchSize,
+			TerminationCooldown:  test.cooldown,
+		})
+
+		if err != nil {
+			t.Errorf("For test \"%s\", expected no error, but got %v", test.name, err)
+		}
+
+		if !reflect.DeepEqual(terminated, test.ids) {
+			t.Errorf("For test \"%s\", expected %v to be terminated, but got %v", test.name, test.ids, terminated)
+		}
+
+		if !reflect.DeepEqual(batches, test.expectedBatches) {
+			t.Errorf("For test \"%s\", expected batches %v, but got %v", test.name, test.expectedBatches, batches)
+		}
+
+		if len(waits) != test.expectedWaits {
+			t.Errorf("For test \"%s\", expected %d waits, but got %v", test.name, test.expectedWaits, waits)
+		}
+
+		for _, w := range waits {
+			if w != test.cooldown {
+				t.Errorf("For test \"%s\", expected to wait %v, but waited %v", test.name, test.cooldown, w)
+			}
+		}
+	}
+}
+
+func TestTerminateInBatchesStopsWhenTheWaitIsInterrupted(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+
+	terminated, err := terminateInBatches(ctx, mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B", "C"}, Options{
+		TerminationBatchSize: 1,
+		TerminationCooldown:  time.Hour,
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the wait to be cancelled, but got %v", err)
+	}
+
+	if expected := []string{"A"}; !reflect.DeepEqual(terminated, expected) || !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected only the first batch %v to be terminated, but got %v", expected, mp.TerminatedInstances)
+	}
+}
+
+func TestDrainingBeforeTerminating(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+
+	var calls []string
+	sleep = func(ctx context.Context, d time.Duration) error {
+		calls = append(calls, "wait "+d.String())
+		return nil
+	}
+
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.DrainInstanceFunc = func(ctx context.Context, groupName string, instanceID string) error {
+		calls = append(calls, "drain "+groupName+" "+instanceID)
+		return nil
+	}
+	mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		calls = append(calls, "terminate "+strings.Join(instanceIDs, ","))
+		return nil
+	}
+
+	_, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B", "C"}, Options{
+		TerminationBatchSize: 2,
+		DrainBeforeTerminate: true,
+		DrainTimeout:         time.Minute,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"drain Group1 A",
+		"drain Group1 B",
+		"wait 1m0s",
+		"terminate A,B",
+		"drain Group1 C",
+		"wait 1m0s",
+		"terminate C",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, calls)
+	}
+}
+
+func TestDrainingFromTargetGroupsDoesNotWaitForTheTimeout(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) error {
+		t.Errorf("Expected the provider to wait for the instances to drain, but slept for %v", d)
+		return nil
+	}
+
+	var m sync.Mutex
+	drained := map[string]bool{}
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.DrainInstanceFunc = func(ctx context.Context, groupName string, instanceID string) error {
+		m.Lock()
+		defer m.Unlock()
+		drained[instanceID] = true
+		return nil
+	}
+	mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		m.Lock()
+		defer m.Unlock()
+		for _, id := range instanceIDs {
+			if !drained[id] {
+				t.Errorf("Expected %s to be drained before it was terminated", id)
+			}
+		}
+		return nil
+	}
+
+	_, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B", "C"}, Options{
+		DrainBeforeTerminate: true,
+		DrainMode:            integration.DrainModeTargetGroups,
+		DrainTimeout:         time.Minute,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(drained) != 3 {
+		t.Errorf("Expected every instance to be drained, but got %v", drained)
+	}
+}
+
+func TestInstancesWhichFailToDrainAreNotTerminated(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.DrainInstanceFunc = func(ctx context.Context, groupName string, instanceID string) error {
+		return errors.New("instance is not in service")
+	}
+
+	terminated, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B"}, Options{
+		DrainBeforeTerminate: true,
+		DrainTimeout:         time.Minute,
+	})
+
+	if err == nil {
+		t.Error("Expected the drain failure to be returned")
+	}
+
+	if len(terminated) != 0 || len(mp.TerminatedInstances) != 0 {
+		t.Errorf("Expected no instances to be terminated, but got %v", mp.TerminatedInstances)
+	}
+}
+
+func TestStandbyFirstMovesEachBatchIntoStandbyBeforeTerminating(t *testing.T) {
+	var calls []string
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.EnterStandbyFunc = func(ctx context.Context, groupName string, instanceIDs []string) error {
+		calls = append(calls, "standby "+groupName+" "+strings.Join(instanceIDs, ","))
+		return nil
+	}
+	mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		calls = append(calls, "terminate "+strings.Join(instanceIDs, ","))
+		return nil
+	}
+
+	_, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B", "C"}, Options{
+		TerminationBatchSize: 2,
+		StandbyFirst:         true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"standby Group1 A,B",
+		"terminate A,B",
+		"standby Group1 C",
+		"terminate C",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, calls)
+	}
+}
+
+func TestInstancesWhichFailToEnterStandbyAreNotTerminated(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.EnterStandbyFunc = func(ctx context.Context, groupName string, instanceIDs []string) error {
+		return errors.New("instance is not in service")
+	}
+
+	terminated, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B"}, Options{
+		StandbyFirst: true,
+	})
+
+	if err == nil {
+		t.Error("Expected the standby failure to be returned")
+	}
+
+	if len(terminated) != 0 || len(mp.TerminatedInstances) != 0 {
+		t.Errorf("Expected no instances to be terminated, but got %v", mp.TerminatedInstances)
+	}
+}
+
+func TestDetachModeTerminatesThroughTheAutoScalingGroup(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		t.Errorf("Expected the EC2 API not to be used, but %v were terminated", instanceIDs)
+		return nil
+	}
+	var decremented []bool
+	mp.TerminateGroupInstancesFunc = func(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error {
+		decremented = append(decremented, shouldDecrementDesiredCapacity)
+		return nil
+	}
+
+	terminated, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B"}, Options{
+		Detach:                   true,
+		DecrementDesiredCapacity: true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"A", "B"}; !reflect.DeepEqual(terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, terminated)
+	}
+
+	if expected := []bool{true}; !reflect.DeepEqual(decremented, expected) {
+		t.Errorf("Expected the desired capacity to be decremented in a single call, but got %v", decremented)
+	}
+}
+
+func TestDetachModeCompletesLifecycleHooks(t *testing.T) {
+	var calls []string
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.TerminateGroupInstancesFunc = func(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error {
+		calls = append(calls, "terminate "+strings.Join(instanceIDs, ","))
+		return nil
+	}
+	mp.CompleteLifecycleFunc = func(ctx context.Context, groupName string, instanceID string) error {
+		calls = append(calls, "complete "+groupName+" "+instanceID)
+		if instanceID == "A" {
+			return errors.New("no active lifecycle action found")
+		}
+		return nil
+	}
+
+	_, err := terminateInBatches(context.Background(), mp, integration.AutoScalingGroup{Name: "Group1"}, []string{"A", "B"}, Options{
+		Detach:                 true,
+		CompleteLifecycleHooks: true,
+	})
+
+	if err != nil {
+		t.Errorf("Expected failures to complete lifecycle hooks to be ignored, but got %v", err)
+	}
+
+	expected := []string{"terminate A,B", "complete Group1 A", "complete Group1 B"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, but got %v", expected, calls)
+	}
+}
+
+func TestSleepIsInterruptible(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := sleep(ctx, time.Hour); err == nil {
+		t.Error("Expected the cancelled wait to return an error")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the cancelled wait to return immediately, but it took %v", elapsed)
+	}
+}
+
+// withHealthyCount returns a copy of the group where only the first count instances are healthy.
+func withHealthyCount(g integration.AutoScalingGroup, count int) integration.AutoScalingGroup {
+	instances := make([]integration.Instance, len(g.Instances))
+	copy(instances, g.Instances)
+	for i := count; i < len(instances); i++ {
+		instances[i].HealthStatus = "Unhealthy"
+	}
+	g.Instances = instances
+	return g
+}
+
+func TestTerminateInBatchesStopsWhenHealthDrops(t *testing.T) {
+	tests := []struct {
+		name               string
+		healthyAfterBatch  int
+		expectedTerminated []string
+		expectedError      bool
+	}{
+		{
+			name:               "The group stays healthy, so every batch is terminated.",
+			healthyAfterBatch:  6,
+			expectedTerminated: []string{"A", "B", "C"},
+		},
+		{
+			name:               "The group's health drops below the floor after the first batch, so no more batches are terminated.",
+			healthyAfterBatch:  2,
+			expectedTerminated: []string{"A"},
+			expectedError:      true,
+		},
+	}
+
+	for _, test := range tests {
+		g := terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C", "D", "E", "F")
+
+		mp := terminatetest.CreateTestData(map[string]string{}, nil)
+		mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+			return []integration.AutoScalingGroup{withHealthyCount(g, test.healthyAfterBatch)}, nil
+		}
+
+		terminated, err := terminateInBatches(context.Background(), mp, g, []string{"A", "B", "C"}, Options{
+			TerminationBatchSize: 1,
+			MinimumInstanceCount: 2,
+		})
+
+		if (err != nil) != test.expectedError {
+			t.Errorf("For test \"%s\", expected an error %v, but got %v", test.name, test.expectedError, err)
+		}
+
+		if !reflect.DeepEqual(terminated, test.expectedTerminated) {
+			t.Errorf("For test \"%s\", expected %v to be terminated, but got %v", test.name, test.expectedTerminated, terminated)
+		}
+
+		if !reflect.DeepEqual(mp.TerminatedInstances, test.expectedTerminated) {
+			t.Errorf("For test \"%s\", expected calls to terminate %v, but got %v", test.name, test.expectedTerminated, mp.TerminatedInstances)
+		}
+	}
+}
+
+func TestTerminateInBatchesDoesNotCountTerminatedInstancesAsHealthy(t *testing.T) {
+	g := terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C", "D", "E", "F")
+
+	// The group keeps reporting the terminated instances as healthy.
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		return []integration.AutoScalingGroup{g}, nil
+	}
+
+	terminated, err := terminateInBatches(context.Background(), mp, g, []string{"A", "B", "C"}, Options{
+		TerminationBatchSize: 1,
+		MinimumInstanceCount: 4,
+	})
+
+	if err == nil {
+		t.Error("Expected an error, because terminating C would leave 3 healthy instances")
+	}
+
+	if expected := []string{"A", "B"}; !reflect.DeepEqual(terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, terminated)
+	}
+}
+
+func TestRollingWaitsForReplacementsBetweenBatches(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+
+	g := terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C", "D")
+	g.DesiredCapacity = 4
+
+	// The group keeps reporting the terminated instances as healthy, and the replacement for each batch is
+	// only launched by the second poll.
+	var events []string
+	describes := 0
+	terminations := 0
+	current := g
+	mp := terminatetest.CreateTestData(map[string]string{}, nil)
+	mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		describes++
+		events = append(events, "describe")
+		if describes%2 == 0 {
+			current = terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C", "D")
+			for i := 0; i < terminations; i++ {
+				current.Instances = append(current.Instances, integration.Instance{ID: fmt.Sprintf("R%d", i), LifecycleState: "InService", HealthStatus: "Healthy"})
+			}
+			current.DesiredCapacity = 4
+		}
+		return []integration.AutoScalingGroup{current}, nil
+	}
+	mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		events = append(events, "terminate")
+		terminations++
+		return nil
+	}
+
+	terminated, err := terminateInBatches(context.Background(), mp, g, []string{"A", "B", "C"}, Options{
+		Rolling:        true,
+		RollingTimeout: time.Minute,
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if expected := []string{"A", "B", "C"}; !reflect.DeepEqual(terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, terminated)
+	}
+
+	expected := []string{"terminate", "describe", "describe", "terminate", "describe", "describe", "terminate"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v, but got %v", expected, events)
+	}
+}
+
+func TestRollingAbortsTheGroupOnTimeout(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) error {
+		time.Sleep(time.Millisecond)
+		return ctx.Err()
+	}
+
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D"),
+		terminatetest.NewHealthyGroup("Group2", "1.1.0", "E", "F", "G", "H"),
+	}
+	groups[0].DesiredCapacity = 4
+	groups[1].DesiredCapacity = 4
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+	// Group1 never replaces its terminated instance, but Group2 replaces its instances straight away.
+	replaced := terminatetest.NewHealthyGroup("Group2", "1.1.0", "E", "F", "G", "H", "R1", "R2", "R3")
+	replaced.DesiredCapacity = 4
+	describe := mp.DescribeAutoScalingGroupsFunc
+	mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		if len(names) == 1 && names[0] == "Group1" {
+			return []integration.AutoScalingGroup{withHealthyCount(groups[0], 3)}, nil
+		}
+		if len(names) == 1 && names[0] == "Group2" {
+			return []integration.AutoScalingGroup{replaced}, nil
+		}
+		return describe(ctx, names, scheme, port, path)
+	}
+
+	result, err := terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		IsDryRun:             false,
+		Canonical:            "1.0.0",
+		Rolling:              true,
+		RollingTimeout:       20 * time.Millisecond,
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "Group1") {
+		t.Errorf("Expected the timeout of Group1 to be reported, but got %v", err)
+	}
+
+	// Group1 stops after its first batch, but Group2 is still processed.
+	expected := []string{"A", "E", "F", "G"}
+	if !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, result.Terminated)
+	}
+}
+
+func TestCanary(t *testing.T) {
+	defer func(s func(context.Context, time.Duration) error) { sleep = s }(sleep)
+	sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+
+	tests := []struct {
+		name                string
+		replacementVersion  string
+		expectedTerminated  []string
+		expectedErrorPrefix string
+	}{
+		{
+			name:               "When the replacement runs the canonical version, the rest of the instances are terminated.",
+			replacementVersion: "1.0.0",
+			expectedTerminated: []string{"A", "B", "C"},
+		},
+		{
+			name:                "When the replacement runs another version, the group is aborted.",
+			replacementVersion:  "1.1.0",
+			expectedTerminated:  []string{"A"},
+			expectedErrorPrefix: "1 of 1 groups failed, Group1: canary A failed",
+		},
+	}
+
+	for _, test := range tests {
+		g := terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D")
+		mp := terminatetest.NewMockProvider([]integration.AutoScalingGroup{g}, "1.1.0", nil, time.Now(), nil)
+
+		// The first check finds no replacement, the second finds Z has replaced A.
+		describe := mp.DescribeAutoScalingGroupsFunc
+		checks := 0
+		mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+			if len(names) == 0 {
+				return describe(ctx, names, scheme, port, path)
+			}
+			checks++
+			replaced := terminatetest.NewHealthyGroup("Group1", "1.1.0", "B", "C", "D")
+			if checks > 1 {
+				replaced.Instances = append(replaced.Instances, integration.Instance{ID: "Z", LifecycleState: "InService", HealthStatus: "Healthy"})
+			}
+			return []integration.AutoScalingGroup{replaced}, nil
+		}
+		mp.GetDetailFunc = func(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*integration.InstanceDetail, error) {
+			if instanceID != "Z" {
+				t.Errorf("For test \"%s\", expected only the replacement to be probed, but got %s", test.name, instanceID)
+			}
+			return &integration.InstanceDetail{ID: instanceID, VersionNumber: semver.MustParse(test.replacementVersion)}, nil
+		}
+
+		result, err := terminate(context.Background(), mp, Options{
+			MinimumInstanceCount: 1,
+			IsDryRun:             false,
+			Canonical:            "1.0.0",
+			Canary:               true,
+			CanaryTimeout:        time.Minute,
+		})
+
+		if test.expectedErrorPrefix == "" && err != nil {
+			t.Errorf("For test \"%s\", expected no error, but got %v", test.name, err)
+		}
+
+		if test.expectedErrorPrefix != "" && (err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorPrefix)) {
+			t.Errorf("For test \"%s\", expected an error starting %q, but got %v", test.name, test.expectedErrorPrefix, err)
+		}
+
+		if !reflect.DeepEqual(result.Terminated, test.expectedTerminated) {
+			t.Errorf("For test \"%s\", expected %v to be terminated, but got %v", test.name, test.expectedTerminated, result.Terminated)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package terminate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// now returns the current time, tests replace it to check freeze windows.
+var now = time.Now
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// FreezeWindow is a recurring period when nothing is terminated, e.g. a change freeze over the weekend. It's
+// parsed from one of:
+//
+//	Fri 17:00-Mon 09:00             from Friday 17:00 until Monday 09:00 each week
+//	Mon-Fri 09:00-17:00             from 09:00 until 17:00 on weekdays
+//	22:00-06:00                     from 22:00 until 06:00 the next morning, every day
+//
+// The times are UTC, unless the spec ends with the name of a time zone, e.g. Mon-Fri 09:00-17:00 Europe/London
+type FreezeWindow struct {
+	Spec     string
+	location *time.Location
+	// spans are the minutes of the week, from Sunday 00:00, the window starts and ends at.
+	spans []span
+}
+
+type span struct {
+	start, end int
+}
+
+// ParseFreezeWindow parses a freeze window spec, see FreezeWindow.
+func ParseFreezeWindow(spec string) (FreezeWindow, error) {
+	w := FreezeWindow{Spec: spec, location: time.UTC}
+	fields := strings.Fields(spec)
+
+	// Each of the forms ends with a time, so a last field without one is the time zone.
+	if len(fields) > 1 && !strings.Contains(fields[len(fields)-1], ":") {
+		location, err := time.LoadLocation(fields[len(fields)-1])
+		if err != nil {
+			return w, fmt.Errorf("invalid freeze window %q, %v", spec, err)
+		}
+		w.location = location
+		fields = fields[:len(fields)-1]
+	}
+
+	var err error
+	switch len(fields) {
+	case 1:
+		w.spans, err = parseDailySpans([]time.Weekday{0, 1, 2, 3, 4, 5, 6}, fields[0])
+	case 2:
+		var days []time.Weekday
+		days, err = parseDays(fields[0])
+		if err == nil {
+			w.spans, err = parseDailySpans(days, fields[1])
+		}
+	case 3:
+		w.spans, err = parseWeeklySpan(fields[0], fields[1], fields[2])
+	default:
+		err = fmt.Errorf("expected e.g. \"Fri 17:00-Mon 09:00\" or \"Mon-Fri 09:00-17:00\"")
+	}
+
+	if err != nil {
+		return w, fmt.Errorf("invalid freeze window %q, %v", spec, err)
+	}
+
+	return w, nil
+}
+
+// parseWeeklySpan parses "Fri 17:00-Mon 09:00", which is split by whitespace into "Fri", "17:00-Mon" and "09:00".
+func parseWeeklySpan(startDay, middle, endTime string) ([]span, error) {
+	parts := strings.SplitN(middle, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected e.g. \"Fri 17:00-Mon 09:00\"")
+	}
+
+	start, err := parseDayAndTime(startDay, parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseDayAndTime(parts[1], endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if start == end {
+		return nil, fmt.Errorf("the window starts and ends at %s %s", startDay, parts[0])
+	}
+
+	return []span{{start: start, end: end}}, nil
+}
+
+func parseDayAndTime(day, clock string) (int, error) {
+	d, ok := weekdays[strings.ToLower(day)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q, expected e.g. Mon", day)
+	}
+
+	m, err := parseClock(clock)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(d)*minutesPerDay + m, nil
+}
+
+// parseDays parses a day, e.g. Sat, or a range of days, e.g. Mon-Fri or Fri-Mon.
+func parseDays(s string) ([]time.Weekday, error) {
+	parts := strings.SplitN(s, "-", 2)
+
+	first, ok := weekdays[strings.ToLower(parts[0])]
+	if !ok {
+		return nil, fmt.Errorf("unknown day %q, expected e.g. Mon", parts[0])
+	}
+
+	if len(parts) == 1 {
+		return []time.Weekday{first}, nil
+	}
+
+	last, ok := weekdays[strings.ToLower(parts[1])]
+	if !ok {
+		return nil, fmt.Errorf("unknown day %q, expected e.g. Fri", parts[1])
+	}
+
+	days := []time.Weekday{first}
+	for d := first; d != last; {
+		d = (d + 1) % 7
+		days = append(days, d)
+	}
+
+	return days, nil
+}
+
+// parseDailySpans parses a range of times, e.g. 09:00-17:00, which applies to each of the days. When the end
+// is before the start, e.g. 22:00-06:00, the span ends the next day.
+func parseDailySpans(days []time.Weekday, times string) ([]span, error) {
+	parts := strings.SplitN(times, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("expected a range of times, e.g. 09:00-17:00, but got %q", times)
+	}
+
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if start == end {
+		return nil, fmt.Errorf("the window starts and ends at %s", parts[0])
+	}
+
+	spans := make([]span, len(days))
+	for i, d := range days {
+		day := int(d) * minutesPerDay
+		length := (end - start + minutesPerDay) % minutesPerDay
+		spans[i] = span{start: day + start, end: (day + start + length) % minutesPerWeek}
+	}
+
+	return spans, nil
+}
+
+// parseClock returns the minutes since midnight of a time, e.g. 17:30.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected e.g. 17:00", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains returns true when t is within the window.
+func (w FreezeWindow) Contains(t time.Time) bool {
+	if w.location != nil {
+		t = t.In(w.location)
+	}
+	m := int(t.Weekday())*minutesPerDay + t.Hour()*60 + t.Minute()
+
+	for _, s := range w.spans {
+		if s.start < s.end && m >= s.start && m < s.end {
+			return true
+		}
+		// The span wraps around the end of the week, e.g. Sat 22:00-Sun 06:00 is from Saturday 22:00 until the
+		// end of the week, and from the start of the week until Sunday 06:00.
+		if s.start >= s.end && (m >= s.start || m < s.end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w FreezeWindow) String() string {
+	return w.Spec
+}
+
+// frozen returns the first of the windows which contains t.
+func frozen(windows []FreezeWindow, t time.Time) (FreezeWindow, bool) {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return w, true
+		}
+	}
+	return FreezeWindow{}, false
+}
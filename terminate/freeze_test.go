@@ -0,0 +1,124 @@
+package terminate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestFreezeWindowContains(t *testing.T) {
+	// 2024-03-15 is a Friday.
+	friday := func(clock string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", "2024-03-15 "+clock)
+		return t
+	}
+
+	tests := []struct {
+		spec     string
+		at       time.Time
+		expected bool
+	}{
+		{spec: "Fri 17:00-Mon 09:00", at: friday("16:59"), expected: false},
+		{spec: "Fri 17:00-Mon 09:00", at: friday("17:00"), expected: true},
+		{spec: "Fri 17:00-Mon 09:00", at: friday("17:00").Add(24 * time.Hour), expected: true},
+		{spec: "Fri 17:00-Mon 09:00", at: friday("08:59").Add(72 * time.Hour), expected: true},
+		{spec: "Fri 17:00-Mon 09:00", at: friday("09:00").Add(72 * time.Hour), expected: false},
+		{spec: "Mon-Fri 09:00-17:00", at: friday("12:00"), expected: true},
+		{spec: "Mon-Fri 09:00-17:00", at: friday("17:00"), expected: false},
+		{spec: "Mon-Fri 09:00-17:00", at: friday("12:00").Add(24 * time.Hour), expected: false},
+		{spec: "Sat-Mon 09:00-17:00", at: friday("12:00").Add(48 * time.Hour), expected: true},
+		{spec: "Sat-Mon 09:00-17:00", at: friday("12:00").Add(96 * time.Hour), expected: false},
+		{spec: "22:00-06:00", at: friday("23:00"), expected: true},
+		{spec: "22:00-06:00", at: friday("05:59"), expected: true},
+		{spec: "22:00-06:00", at: friday("12:00"), expected: false},
+		{spec: "Sat 22:00-06:00", at: friday("02:00").Add(48 * time.Hour), expected: true},
+		{spec: "Mon-Fri 09:00-17:00 America/New_York", at: friday("12:00"), expected: false},
+		{spec: "Mon-Fri 09:00-17:00 America/New_York", at: friday("14:00"), expected: true},
+	}
+
+	for _, test := range tests {
+		w, err := ParseFreezeWindow(test.spec)
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.spec, err)
+		}
+
+		if actual := w.Contains(test.at); actual != test.expected {
+			t.Errorf("%s: expected %v to be in the window to be %v, but got %v", test.spec, test.at, test.expected, actual)
+		}
+	}
+}
+
+func TestInvalidFreezeWindows(t *testing.T) {
+	specs := []string{
+		"",
+		"Fri",
+		"Fri 17:00",
+		"Fri 17:00-Mon",
+		"Fri 17:00-Fri 17:00",
+		"Someday 09:00-17:00",
+		"Mon-Fri 09:00-09:00",
+		"Mon-Fri 9am-5pm",
+		"Mon-Fri 09:00-17:00 Nowhere/Special",
+	}
+
+	for _, spec := range specs {
+		if _, err := ParseFreezeWindow(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestNothingIsTerminatedInsideAFreezeWindow(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+
+	window, err := ParseFreezeWindow("Fri 17:00-Mon 09:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		at       string
+		isDryRun bool
+		expected int
+	}{
+		{name: "inside the window", at: "2024-03-16 12:00", expected: 0},
+		{name: "inside the window, during a dry run", at: "2024-03-16 12:00", isDryRun: true, expected: 0},
+		{name: "outside the window", at: "2024-03-13 12:00", expected: 2},
+	}
+
+	for _, test := range tests {
+		now = func() time.Time {
+			t, _ := time.Parse("2006-01-02 15:04", test.at)
+			return t
+		}
+		mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+
+		result, err := Run(context.Background(), mp, Options{
+			AutoScalingGroups:    []string{"Group2"},
+			MinimumInstanceCount: 2,
+			Canonical:            "1.1.0",
+			Mode:                 ModeCanonical,
+			IsDryRun:             test.isDryRun,
+			FreezeWindows:        []FreezeWindow{window},
+		})
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if len(result.Terminated) != test.expected {
+			t.Errorf("%s: expected %d instances to be terminated, but got %v", test.name, test.expected, result.Terminated)
+		}
+		if len(mp.TerminatedInstances) != test.expected {
+			t.Errorf("%s: expected %d calls to terminate, but got %v", test.name, test.expected, mp.TerminatedInstances)
+		}
+		frozen := len(result.NoActions) == 1 && result.NoActions[0].Group == "Group2" && result.NoActions[0].Reason == NoActionFrozen
+		if frozen != (test.expected == 0) {
+			t.Errorf("%s: expected Group2 to be left alone because of the freeze %v, but got %+v", test.name, test.expected == 0, result.NoActions)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package terminate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/a-h/terminator/integration"
+)
+
+// The actions of a plan.
+const (
+	actionTerminate = "terminate"
+	actionKeep      = "keep"
+)
+
+// Plan records what a dry run would do to each instance, so it can be reviewed before a real run. A nil plan
+// discards groups.
+type Plan struct {
+	// Note explains why nothing was planned, e.g. because terminations are frozen.
+	Note   string      `json:"note,omitempty"`
+	Groups []PlanGroup `json:"groups"`
+}
+
+// PlanGroup is what a dry run would do to the instances of a group.
+type PlanGroup struct {
+	Name            string         `json:"name"`
+	ExpectedVersion string         `json:"expectedVersion"`
+	Note            string         `json:"note,omitempty"`
+	Instances       []PlanInstance `json:"instances"`
+}
+
+// PlanInstance is what a dry run would do to an instance.
+type PlanInstance struct {
+	ID             string `json:"id"`
+	CurrentVersion string `json:"currentVersion"`
+	Action         string `json:"action"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// add records the instances of the group, and which of them would be terminated. The note explains why
+// nothing would be terminated, e.g. a safety check.
+func (pl *Plan) add(g integration.AutoScalingGroup, expected string, decisions []integration.TerminationDecision, note string) {
+	if pl == nil {
+		return
+	}
+
+	selected := map[string]integration.TerminationDecision{}
+	for _, d := range decisions {
+		selected[d.ID] = d
+	}
+
+	versions := map[string]string{}
+	for _, d := range g.InstanceDetails {
+		versions[d.ID] = d.Version()
+	}
+
+	pg := PlanGroup{Name: g.Name, ExpectedVersion: expected, Note: note, Instances: []PlanInstance{}}
+	for _, instance := range g.Instances {
+		pi := PlanInstance{ID: instance.ID, CurrentVersion: versions[instance.ID], Action: actionKeep}
+		if d, ok := selected[instance.ID]; ok {
+			pi.Action = actionTerminate
+			pi.Reason = string(d.Reason)
+		}
+		pg.Instances = append(pg.Instances, pi)
+	}
+
+	pl.Groups = append(pl.Groups, pg)
+}
+
+// WriteFile writes the plan to the file, as JSON when the file has a .json extension, otherwise as a table.
+func (pl *Plan) WriteFile(file string) error {
+	f, err := os.Create(file)
+
+	if err != nil {
+		return err
+	}
+
+	write := pl.writeTable
+	if strings.HasSuffix(file, ".json") {
+		write = pl.writeJSON
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (pl *Plan) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(pl)
+}
+
+func (pl *Plan) writeTable(w io.Writer) error {
+	if pl.Note != "" {
+		fmt.Fprintln(w, pl.Note)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tINSTANCE\tCURRENT\tEXPECTED\tACTION\tREASON")
+
+	for _, g := range pl.Groups {
+		if len(g.Instances) == 0 || g.Note != "" {
+			fmt.Fprintf(tw, "%s\t\t\t%s\t%s\t%s\n", g.Name, g.ExpectedVersion, actionKeep, g.Note)
+		}
+		if g.Note != "" {
+			continue
+		}
+		for _, i := range g.Instances {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", g.Name, i.ID, i.CurrentVersion, g.ExpectedVersion, i.Action, i.Reason)
+		}
+	}
+
+	return tw.Flush()
+}
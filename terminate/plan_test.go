@@ -0,0 +1,162 @@
+package terminate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestDryRunWritesThePlan(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.json")
+	mp := terminatetest.CreateTestData(map[string]string{"A": "1.1.0", "B": "1.1.0", "C": "1.1.0", "D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+
+	result, err := terminate(context.Background(), mp, Options{
+		IsDryRun:             true,
+		MinimumInstanceCount: 3,
+		Canonical:            "1.1.0",
+		Plan:                 true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := result.Plan.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual Plan
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatalf("Expected the plan to be JSON, but got %s, %v", string(b), err)
+	}
+
+	expected := Plan{
+		Groups: []PlanGroup{
+			{
+				Name:            "Group1",
+				ExpectedVersion: "1.1.0",
+				Note:            "not enough healthy instances",
+				Instances: []PlanInstance{
+					{ID: "A", CurrentVersion: "1.1.0", Action: actionKeep},
+					{ID: "B", CurrentVersion: "1.1.0", Action: actionKeep},
+					{ID: "C", CurrentVersion: "1.1.0", Action: actionKeep},
+				},
+			},
+			{
+				Name:            "Group2",
+				ExpectedVersion: "1.1.0",
+				Instances: []PlanInstance{
+					{ID: "D", CurrentVersion: "1.0.0", Action: actionTerminate, Reason: "version below canonical"},
+					{ID: "E", CurrentVersion: "1.1.0", Action: actionKeep},
+					{ID: "F", CurrentVersion: "1.1.0", Action: actionKeep},
+					{ID: "G", CurrentVersion: "1.1.0", Action: actionKeep},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected plan %+v, but got %s", expected, string(b))
+	}
+}
+
+func TestThePlanIsATableUnlessTheFileIsJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "plan.txt")
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+
+	result, err := terminate(context.Background(), mp, Options{
+		IsDryRun:             true,
+		AutoScalingGroups:    []string{"Group2"},
+		MinimumInstanceCount: 3,
+		Canonical:            "1.1.0",
+		Plan:                 true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := result.Plan.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "GROUP   INSTANCE  CURRENT  EXPECTED  ACTION     REASON\n" +
+		"Group2  D         1.0.0    1.1.0     terminate  version below canonical\n" +
+		"Group2  E         1.1.0    1.1.0     keep       \n" +
+		"Group2  F         1.1.0    1.1.0     keep       \n" +
+		"Group2  G         1.1.0    1.1.0     keep       \n"
+	if string(b) != expected {
+		t.Errorf("Expected the plan:\n%s\nbut got:\n%s", expected, string(b))
+	}
+}
+
+func TestThePlanIsOnlyMadeDuringADryRun(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+
+	result, _ := terminate(context.Background(), mp, Options{
+		AutoScalingGroups:    []string{"Group2"},
+		MinimumInstanceCount: 3,
+		Canonical:            "1.1.0",
+		Plan:                 true,
+	})
+
+	if result.Plan != nil {
+		t.Errorf("Expected no plan outside a dry run, but got %+v", result.Plan)
+	}
+}
+
+func TestAFrozenRunPlansNothing(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", "2024-03-16 12:00")
+		return t
+	}
+
+	window, err := ParseFreezeWindow("Fri 17:00-Mon 09:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+
+	result, err := terminate(context.Background(), mp, Options{
+		IsDryRun:             true,
+		AutoScalingGroups:    []string{"Group2"},
+		MinimumInstanceCount: 3,
+		Canonical:            "1.1.0",
+		FreezeWindows:        []FreezeWindow{window},
+		Plan:                 true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Plan{Note: "terminations are frozen during Fri 17:00-Mon 09:00", Groups: []PlanGroup{}}
+	if !reflect.DeepEqual(result.Plan, expected) {
+		t.Fatalf("Expected plan %+v, but got %+v", expected, result.Plan)
+	}
+
+	var table strings.Builder
+	if err := result.Plan.writeTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(table.String(), expected.Note+"\n") {
+		t.Errorf("Expected the table to start with the note, but got:\n%s", table.String())
+	}
+}
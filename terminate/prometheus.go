@@ -0,0 +1,77 @@
+package terminate
+
+import (
+	"github.com/a-h/terminator/integration"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetrics are updated by each run, so that a long-running terminator can be scraped by Prometheus.
+type PrometheusMetrics struct {
+	runs                prometheus.Counter
+	instancesTerminated *prometheus.CounterVec
+	probeFailures       *prometheus.CounterVec
+	mismatchedInstances *prometheus.GaugeVec
+}
+
+// NewPrometheusMetrics creates the metrics, and registers them with the registerer.
+func NewPrometheusMetrics(reg prometheus.Registerer) *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		runs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "terminator_runs_total",
+			Help: "The number of runs.",
+		}),
+		instancesTerminated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "terminator_instances_terminated_total",
+			Help: "The number of instances terminated from each group.",
+		}, []string{"group"}),
+		probeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "terminator_probe_failures_total",
+			Help: "The number of instances in each group whose version couldn't be read.",
+		}, []string{"group"}),
+		mismatchedInstances: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "terminator_mismatched_instances",
+			Help: "The number of instances in each group which weren't running the expected version in the last run.",
+		}, []string{"group"}),
+	}
+
+	reg.MustRegister(m.runs, m.instancesTerminated, m.probeFailures, m.mismatchedInstances)
+
+	return m
+}
+
+func (m *PrometheusMetrics) observeRun() {
+	if m == nil {
+		return
+	}
+	m.runs.Inc()
+}
+
+// observeGroup records the instances of the group which aren't running the expected version, and the
+// instances whose version couldn't be read, excluding those which failed a health check.
+func (m *PrometheusMetrics) observeGroup(g integration.AutoScalingGroup, mismatched int) {
+	if m == nil {
+		return
+	}
+
+	m.mismatchedInstances.WithLabelValues(g.Name).Set(float64(mismatched))
+
+	read := map[string]bool{}
+	for _, d := range g.InstanceDetails {
+		read[d.ID] = true
+	}
+
+	failures := 0
+	for _, instance := range g.Instances {
+		if !read[instance.ID] && !instance.FailedHealthCheck {
+			failures++
+		}
+	}
+	m.probeFailures.WithLabelValues(g.Name).Add(float64(failures))
+}
+
+func (m *PrometheusMetrics) observeTerminated(group string, count int) {
+	if m == nil {
+		return
+	}
+	m.instancesTerminated.WithLabelValues(group).Add(float64(count))
+}
@@ -0,0 +1,271 @@
+package terminate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+)
+
+// The outcomes of processing a group.
+const (
+	// OutcomePassed means the group needed no action, or its instances were (or in a dry run, would be) terminated.
+	OutcomePassed = "passed"
+	// OutcomeFailed means an error stopped the group being processed.
+	OutcomeFailed = "failed"
+	// OutcomeSkipped means a safety check stopped instances being terminated from the group.
+	OutcomeSkipped = "skipped"
+)
+
+// GroupOutcome records what happened to a group during a run.
+type GroupOutcome struct {
+	Name     string
+	Outcome  string
+	Message  string
+	Targets  []string
+	Duration time.Duration
+}
+
+// evaluation records the instances of a group, the version they were expected to run, and the instances
+// which were kept.
+type evaluation struct {
+	group    integration.AutoScalingGroup
+	expected string
+	kept     []integration.KeepDecision
+}
+
+// Report collects the outcome of each group processed during a run. A nil report discards outcomes.
+type Report struct {
+	dryRun      bool
+	groups      []GroupOutcome
+	evaluations map[string]evaluation
+}
+
+// evaluate records the instances of the group, and the version they were expected to run.
+func (r *Report) evaluate(g integration.AutoScalingGroup, expected string) {
+	if r == nil {
+		return
+	}
+	if r.evaluations == nil {
+		r.evaluations = map[string]evaluation{}
+	}
+	r.evaluations[g.Name] = evaluation{group: g, expected: expected}
+}
+
+// keep records the instances of the evaluated group which were kept.
+func (r *Report) keep(name string, kept []integration.KeepDecision) {
+	if r == nil {
+		return
+	}
+	e := r.evaluations[name]
+	e.kept = append(e.kept, kept...)
+	r.evaluations[name] = e
+}
+
+// NewReport creates a report to pass in the Options of a run, dryRun is recorded in the JSON report.
+func NewReport(dryRun bool) *Report {
+	return &Report{dryRun: dryRun}
+}
+
+// Groups returns the outcome of each group, in the order they were processed.
+func (r *Report) Groups() []GroupOutcome {
+	return r.groups
+}
+
+func (r *Report) add(name string, outcome string, message string, targets []string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.groups = append(r.groups, GroupOutcome{
+		Name:     name,
+		Outcome:  outcome,
+		Message:  message,
+		Targets:  targets,
+		Duration: duration,
+	})
+}
+
+func (r *Report) count(outcome string) (count int) {
+	for _, g := range r.groups {
+		if g.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the report as a JUnit XML document, with each group as a test case.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suite := junitTestSuite{
+		Name:     "terminator",
+		Tests:    len(r.groups),
+		Failures: r.count(OutcomeFailed),
+		Skipped:  r.count(OutcomeSkipped),
+	}
+
+	var total time.Duration
+	for _, g := range r.groups {
+		total += g.Duration
+
+		tc := junitTestCase{
+			ClassName: "terminator",
+			Name:      g.Name,
+			Time:      seconds(g.Duration),
+		}
+
+		switch g.Outcome {
+		case OutcomeFailed:
+			tc.Failure = &junitMessage{Message: g.Message}
+		case OutcomeSkipped:
+			tc.Skipped = &junitMessage{Message: g.Message}
+		default:
+			tc.SystemOut = g.Message
+		}
+
+		if len(g.Targets) > 0 {
+			tc.SystemOut = fmt.Sprintf("%s %v", g.Message, g.Targets)
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonReport struct {
+	DryRun bool        `json:"dryRun"`
+	Groups []jsonGroup `json:"groups"`
+}
+
+type jsonGroup struct {
+	Name            string         `json:"name"`
+	Outcome         string         `json:"outcome"`
+	Message         string         `json:"message"`
+	ExpectedVersion string         `json:"expectedVersion,omitempty"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	Instances       []jsonInstance `json:"instances"`
+	Targets         []string       `json:"targets"`
+	Kept            []jsonKept     `json:"kept"`
+}
+
+type jsonInstance struct {
+	ID                      string `json:"id"`
+	Version                 string `json:"version,omitempty"`
+	HealthStatus            string `json:"healthStatus"`
+	LifecycleState          string `json:"lifecycleState"`
+	InstanceType            string `json:"instanceType,omitempty"`
+	LaunchTemplateName      string `json:"launchTemplateName,omitempty"`
+	LaunchTemplateVersion   string `json:"launchTemplateVersion,omitempty"`
+	LaunchConfigurationName string `json:"launchConfigurationName,omitempty"`
+	Selected                bool   `json:"selected"`
+}
+
+type jsonKept struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// WriteJSON writes the report as a JSON document, listing the instances evaluated in each group, and which
+// were selected for termination.
+func WriteJSON(w io.Writer, r *Report) error {
+	doc := jsonReport{
+		DryRun: r.dryRun,
+		Groups: []jsonGroup{},
+	}
+
+	for _, g := range r.groups {
+		selected := map[string]bool{}
+		for _, id := range g.Targets {
+			selected[id] = true
+		}
+
+		jg := jsonGroup{
+			Name:            g.Name,
+			Outcome:         g.Outcome,
+			Message:         g.Message,
+			DurationSeconds: g.Duration.Seconds(),
+			Instances:       []jsonInstance{},
+			Targets:         g.Targets,
+			Kept:            []jsonKept{},
+		}
+		if jg.Targets == nil {
+			jg.Targets = []string{}
+		}
+
+		e := r.evaluations[g.Name]
+		jg.ExpectedVersion = e.expected
+
+		versions := map[string]string{}
+		for _, d := range e.group.InstanceDetails {
+			versions[d.ID] = d.Version()
+		}
+
+		for _, instance := range e.group.Instances {
+			jg.Instances = append(jg.Instances, jsonInstance{
+				ID:                      instance.ID,
+				Version:                 versions[instance.ID],
+				HealthStatus:            instance.HealthStatus,
+				LifecycleState:          instance.LifecycleState,
+				InstanceType:            instance.InstanceType,
+				LaunchTemplateName:      instance.LaunchTemplateName,
+				LaunchTemplateVersion:   instance.LaunchTemplateVersion,
+				LaunchConfigurationName: instance.LaunchConfigurationName,
+				Selected:                selected[instance.ID],
+			})
+		}
+
+		for _, k := range e.kept {
+			jg.Kept = append(jg.Kept, jsonKept{ID: k.ID, Version: k.Version, Reason: string(k.Reason)})
+		}
+
+		doc.Groups = append(doc.Groups, jg)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,154 @@
+package terminate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestJUnitReport(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		{
+			// Group1 has an instance out of service, so it's skipped.
+			Name: "Group1",
+			Instances: []integration.Instance{
+				{ID: "A", LifecycleState: "InService", HealthStatus: "Healthy"},
+				{ID: "B", LifecycleState: "InService", HealthStatus: "Healthy"},
+				{ID: "C", LifecycleState: "OutOfService", HealthStatus: "Healthy"},
+			},
+			InstanceDetails: integration.InstanceDetails{{ID: "A"}, {ID: "B"}, {ID: "C"}},
+		},
+		{
+			// Group2 matches the canonical version.
+			Name: "Group2",
+			Instances: []integration.Instance{
+				{ID: "D", LifecycleState: "InService", HealthStatus: "Healthy"},
+				{ID: "E", LifecycleState: "InService", HealthStatus: "Healthy"},
+			},
+			InstanceDetails: integration.InstanceDetails{{ID: "D"}, {ID: "E"}},
+		},
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.0.0", nil, time.Now(), nil)
+	r := &Report{}
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		IsDryRun:             true,
+		Canonical:            "0.0.0",
+		Report:               r,
+	})
+
+	// Add a group which failed.
+	r.add("Group3", OutcomeFailed, "access denied", []string{"F"}, time.Second)
+
+	buf := new(bytes.Buffer)
+	if err := WriteJUnit(buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	var suite junitTestSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("Failed to parse the report %s with error %v", buf.String(), err)
+	}
+
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("Expected 3 tests, 1 failure and 1 skipped, but got %d tests, %d failures and %d skipped",
+			suite.Tests, suite.Failures, suite.Skipped)
+	}
+
+	if len(suite.TestCases) != 3 {
+		t.Fatalf("Expected 3 test cases, but got %d in %s", len(suite.TestCases), buf.String())
+	}
+
+	if tc := suite.TestCases[0]; tc.Name != "Group1" || tc.Skipped == nil || tc.Failure != nil {
+		t.Errorf("Expected Group1 to be skipped, but got %+v", tc)
+	}
+
+	if tc := suite.TestCases[1]; tc.Name != "Group2" || tc.Skipped != nil || tc.Failure != nil {
+		t.Errorf("Expected Group2 to pass, but got %+v", tc)
+	}
+
+	if tc := suite.TestCases[2]; tc.Name != "Group3" || tc.Failure == nil || tc.Failure.Message != "access denied" {
+		t.Errorf("Expected Group3 to fail, but got %+v", tc)
+	}
+}
+
+func TestJSONReport(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C"),
+		terminatetest.NewHealthyGroup("Group2", "1.0.0", "D", "E"),
+	}
+	groups[0].Instances[0].InstanceType = "m5.large"
+	groups[0].Instances[0].LaunchTemplateName = "api"
+	groups[0].Instances[0].LaunchTemplateVersion = "3"
+	groups[1].Instances[0].LaunchConfigurationName = "web-v2"
+	mp := terminatetest.NewMockProvider(groups, "1.0.0", nil, time.Now(), nil)
+	r := &Report{dryRun: true}
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		IsDryRun:             true,
+		Canonical:            "1.0.0",
+		Report:               r,
+	})
+
+	buf := new(bytes.Buffer)
+	if err := WriteJSON(buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatalf("Failed to parse the report %s with error %v", buf.String(), err)
+	}
+
+	expected := jsonReport{
+		DryRun: true,
+		Groups: []jsonGroup{
+			{
+				Name:            "Group1",
+				Outcome:         OutcomePassed,
+				Message:         "dry run, would terminate instances",
+				ExpectedVersion: "1.0.0",
+				Instances: []jsonInstance{
+					{ID: "A", Version: "1.1.0", HealthStatus: "Healthy", LifecycleState: "InService", InstanceType: "m5.large", LaunchTemplateName: "api", LaunchTemplateVersion: "3", Selected: true},
+					{ID: "B", Version: "1.1.0", HealthStatus: "Healthy", LifecycleState: "InService", Selected: true},
+					{ID: "C", Version: "1.1.0", HealthStatus: "Healthy", LifecycleState: "InService", Selected: false},
+				},
+				Targets: []string{"A", "B"},
+				Kept:    []jsonKept{{ID: "C", Version: "1.1.0", Reason: "part of the minimum count"}},
+			},
+			{
+				Name:            "Group2",
+				Outcome:         OutcomePassed,
+				Message:         "no instances to terminate",
+				ExpectedVersion: "1.0.0",
+				Instances: []jsonInstance{
+					{ID: "D", Version: "1.0.0", HealthStatus: "Healthy", LifecycleState: "InService", LaunchConfigurationName: "web-v2", Selected: false},
+					{ID: "E", Version: "1.0.0", HealthStatus: "Healthy", LifecycleState: "InService", Selected: false},
+				},
+				Targets: []string{},
+				Kept: []jsonKept{
+					{ID: "D", Version: "1.0.0", Reason: "version matches"},
+					{ID: "E", Version: "1.0.0", Reason: "version matches"},
+				},
+			},
+		},
+	}
+
+	// The durations vary between runs.
+	for i := range actual.Groups {
+		actual.Groups[i].DurationSeconds = 0
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, actual)
+	}
+}
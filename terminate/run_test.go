@@ -0,0 +1,67 @@
+package terminate_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/a-h/terminator/terminate"
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestRun(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+
+	result, err := terminate.Run(context.Background(), mp, terminate.Options{
+		AutoScalingGroups:    []string{"Group2"},
+		MinimumInstanceCount: 2,
+		Canonical:            "1.1.0",
+		Mode:                 terminate.ModeCanonical,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(result.Terminated)
+	if expected := []string{"D", "E"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, result.Terminated)
+	}
+
+	sort.Strings(mp.TerminatedInstances)
+	if !reflect.DeepEqual(mp.TerminatedInstances, result.Terminated) {
+		t.Errorf("Expected the provider to terminate %v, but got %v", result.Terminated, mp.TerminatedInstances)
+	}
+}
+
+func TestRunDryRunReportsTheTargets(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+	r := terminate.NewReport(true)
+
+	result, err := terminate.Run(context.Background(), mp, terminate.Options{
+		IsDryRun:             true,
+		AutoScalingGroups:    []string{"Group2"},
+		MinimumInstanceCount: 3,
+		Canonical:            "1.1.0",
+		Mode:                 terminate.ModeCanonical,
+		Report:               r,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"D"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %v to be targeted, but got %v", expected, result.Terminated)
+	}
+
+	if len(mp.TerminatedInstances) > 0 {
+		t.Errorf("Expected nothing to be terminated during a dry run, but got %v", mp.TerminatedInstances)
+	}
+
+	groups := r.Groups()
+	if len(groups) != 1 || groups[0].Name != "Group2" || !reflect.DeepEqual(groups[0].Targets, []string{"D"}) {
+		t.Errorf("Expected the report to record Group2 targeting D, but got %+v", groups)
+	}
+}
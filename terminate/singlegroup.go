@@ -0,0 +1,88 @@
+package terminate
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/blang/semver"
+)
+
+// The strategies used to pick the group to process when --singleGroup is set.
+const (
+	// SelectionFirstAlphabetical picks the group whose name sorts first.
+	SelectionFirstAlphabetical = "firstAlphabetical"
+	// SelectionMostDrift picks the group with the most instances which don't match the canonical version.
+	SelectionMostDrift = "mostDrift"
+	// SelectionOldestInstances picks the group containing the oldest instance.
+	SelectionOldestInstances = "oldestInstances"
+)
+
+func IsValidSelection(selection string) bool {
+	switch selection {
+	case SelectionFirstAlphabetical, SelectionMostDrift, SelectionOldestInstances:
+		return true
+	}
+	return false
+}
+
+// selectGroup picks a single group using the selection strategy, the remaining groups are returned as deferred.
+// Ties are broken by the alphabetical order of the group names.
+func selectGroup(groups []integration.AutoScalingGroup, selection string, canonical semver.Version) (selected integration.AutoScalingGroup, deferred []integration.AutoScalingGroup) {
+	sorted := make([]integration.AutoScalingGroup, len(groups))
+	copy(sorted, groups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	best := 0
+	for i := 1; i < len(sorted); i++ {
+		switch selection {
+		case SelectionMostDrift:
+			if countDrift(sorted[i], canonical) > countDrift(sorted[best], canonical) {
+				best = i
+			}
+		case SelectionOldestInstances:
+			if oldest, ok := oldestLaunchTime(sorted[i]); ok {
+				if bestOldest, bestOK := oldestLaunchTime(sorted[best]); !bestOK || oldest.Before(bestOldest) {
+					best = i
+				}
+			}
+		}
+	}
+
+	deferred = append(deferred, sorted[:best]...)
+	deferred = append(deferred, sorted[best+1:]...)
+	return sorted[best], deferred
+}
+
+func countDrift(g integration.AutoScalingGroup, canonical semver.Version) (count int) {
+	for _, d := range g.InstanceDetails {
+		if !d.VersionNumber.Equals(canonical) {
+			count++
+		}
+	}
+	return count
+}
+
+func oldestLaunchTime(g integration.AutoScalingGroup) (oldest time.Time, ok bool) {
+	for _, d := range g.InstanceDetails {
+		if !ok || d.LaunchTime.Before(oldest) {
+			oldest, ok = d.LaunchTime, true
+		}
+	}
+	return oldest, ok
+}
+
+// limitToSingleGroup returns just the group picked by the selection strategy, reporting the others as deferred.
+func limitToSingleGroup(groups []integration.AutoScalingGroup, selection string, canonical semver.Version) []integration.AutoScalingGroup {
+	if len(groups) <= 1 {
+		return groups
+	}
+
+	selected, deferred := selectGroup(groups, selection, canonical)
+	slog.Info("selected a single group", "group", selected.Name, "selection", selection, "deferred", getGroupNames(deferred))
+
+	return []integration.AutoScalingGroup{selected}
+}
@@ -0,0 +1,107 @@
+package terminate
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate/terminatetest"
+	"github.com/blang/semver"
+)
+
+func createGroup(name string, versions []string, launchTimes []time.Time) integration.AutoScalingGroup {
+	g := integration.AutoScalingGroup{Name: name}
+
+	for i, v := range versions {
+		version, _ := semver.Make(v)
+		g.InstanceDetails = append(g.InstanceDetails, integration.InstanceDetail{
+			ID:            name + string(rune('A'+i)),
+			VersionNumber: version,
+			LaunchTime:    launchTimes[i],
+		})
+	}
+
+	return g
+}
+
+func TestSelectingASingleGroup(t *testing.T) {
+	now := time.Now()
+	groups := []integration.AutoScalingGroup{
+		createGroup("web", []string{"0.9.0", "0.9.0", "1.0.0"}, []time.Time{now, now, now}),
+		createGroup("db", []string{"0.9.0", "1.0.0"}, []time.Time{now.Add(-48 * time.Hour), now}),
+		createGroup("api", []string{"1.0.0", "1.0.0"}, []time.Time{now.Add(-1 * time.Hour), now}),
+	}
+	canonical, _ := semver.Make("1.0.0")
+
+	tests := []struct {
+		selection        string
+		expected         string
+		expectedDeferred []string
+	}{
+		{
+			selection:        SelectionFirstAlphabetical,
+			expected:         "api",
+			expectedDeferred: []string{"db", "web"},
+		},
+		{
+			selection:        SelectionMostDrift,
+			expected:         "web",
+			expectedDeferred: []string{"api", "db"},
+		},
+		{
+			selection:        SelectionOldestInstances,
+			expected:         "db",
+			expectedDeferred: []string{"api", "web"},
+		},
+	}
+
+	for _, test := range tests {
+		selected, deferred := selectGroup(groups, test.selection, canonical)
+
+		if selected.Name != test.expected {
+			t.Errorf("Using %s, expected group %s to be selected, but got %s", test.selection, test.expected, selected.Name)
+		}
+
+		if !reflect.DeepEqual(getGroupNames(deferred), test.expectedDeferred) {
+			t.Errorf("Using %s, expected groups %v to be deferred, but got %v", test.selection, test.expectedDeferred, getGroupNames(deferred))
+		}
+	}
+}
+
+func TestTerminatingASingleGroup(t *testing.T) {
+	tests := []struct {
+		selection            string
+		expectedTerminations []string
+	}{
+		{
+			// Group1 is selected, but isn't healthy, so nothing is terminated.
+			selection:            SelectionFirstAlphabetical,
+			expectedTerminations: []string{},
+		},
+		{
+			// Group2 has 4 instances which don't match the canonical version, Group1 has 3.
+			selection:            SelectionMostDrift,
+			expectedTerminations: []string{"D", "E", "F", "G"},
+		},
+	}
+
+	for _, test := range tests {
+		mp := terminatetest.CreateTestData(map[string]string{}, nil)
+
+		terminate(context.Background(), mp, Options{
+			MinimumInstanceCount: 0,
+			IsDryRun:             false,
+			Canonical:            "1.0.0",
+			SingleGroup:          true,
+			SingleGroupSelection: test.selection,
+		})
+
+		sort.Strings(mp.TerminatedInstances)
+		if !reflect.DeepEqual(mp.TerminatedInstances, test.expectedTerminations) {
+			t.Errorf("Using %s, expected %v to be terminated, but got %v", test.selection, test.expectedTerminations, mp.TerminatedInstances)
+		}
+	}
+}
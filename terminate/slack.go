@@ -0,0 +1,88 @@
+package terminate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+)
+
+// slackTimeout is how long to wait for Slack to accept a message.
+const slackTimeout = 10 * time.Second
+
+// slackMessage is the body of a Slack incoming webhook request.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// describeExpectedVersion describes the version which instances were expected to run.
+func describeExpectedVersion(p Options) string {
+	switch {
+	case p.Mode == ModeEnforceGroupModal:
+		return "the most common version in their group"
+	case p.VersionSource == integration.VersionSourceSSMInventory:
+		return "at least OS version " + p.MinimumOSVersion
+	case p.CanonicalRange.Range != nil:
+		return "a version in the range " + p.CanonicalRange.String()
+	case p.Canonical == CanonicalAuto:
+		return "the highest version in their group"
+	}
+	return "version " + p.Canonical
+}
+
+// formatSlackMessage lists the number and IDs of the instances terminated from each group.
+func formatSlackMessage(terminated map[string][]string, expected string) string {
+	groups := make([]string, 0, len(terminated))
+	for g := range terminated {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Terminator terminated instances not running %s\n", expected)
+	for _, g := range groups {
+		fmt.Fprintf(&sb, "• *%s*: %d instances `%s`\n", g, len(terminated[g]), strings.Join(terminated[g], ", "))
+	}
+
+	return sb.String()
+}
+
+// notifySlack posts the text to the Slack incoming webhook.
+func notifySlack(ctx context.Context, webhookURL string, text string) error {
+	body, err := json.Marshal(slackMessage{Text: text})
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, slackTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("slack returned HTTP status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package terminate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/terminator/terminate/terminatetest"
+)
+
+func TestSlackIsNotifiedOfTerminations(t *testing.T) {
+	var messages []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("Failed to decode the Slack message, %v", err)
+		}
+		messages = append(messages, m)
+	}))
+	defer server.Close()
+
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.0.0", "G": "1.0.0"}, nil)
+
+	_, err := terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		IsDryRun:             false,
+		Canonical:            "2.0.0",
+		SlackWebhookURL:      server.URL,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("Expected a single Slack message, but got %d", len(messages))
+	}
+
+	for _, expected := range []string{"2.0.0", "Group2", "3 instances", "D, E, F"} {
+		if !strings.Contains(messages[0].Text, expected) {
+			t.Errorf("Expected the message to contain %q, but got %q", expected, messages[0].Text)
+		}
+	}
+}
+
+func TestSlackFailuresDontFailTheRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := notifySlack(context.Background(), server.URL, "test"); err == nil {
+		t.Error("Expected the failed post to return an error")
+	}
+
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.0.0", "G": "1.0.0"}, nil)
+
+	result, err := terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		IsDryRun:             false,
+		Canonical:            "2.0.0",
+		SlackWebhookURL:      server.URL,
+	})
+
+	if err != nil || len(result.Terminated) != 3 {
+		t.Errorf("Expected the run to succeed despite Slack failing, but got %v and %v", result.Terminated, err)
+	}
+}
@@ -0,0 +1,899 @@
+// Package terminate finds the instances of EC2 auto scaling groups which aren't running the expected
+// version of an application and terminates them, so that the group replaces them.
+package terminate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/blang/semver"
+)
+
+// The modes of operation.
+const (
+	// ModeCanonical terminates instances which don't match the canonical version.
+	ModeCanonical = "canonical"
+	// ModeEnforceGroupModal terminates instances which don't match the most common version in their group.
+	ModeEnforceGroupModal = "enforceGroupModal"
+)
+
+// CanonicalAuto is the canonical version which evaluates each group independently, targeting the instances
+// running a lower version than the highest version of the healthy instances in the group.
+const CanonicalAuto = "auto"
+
+// ErrMaxTerminations is returned when the maxTerminations cap stopped instances from being terminated.
+var ErrMaxTerminations = errors.New("the maximum number of terminations was reached, the remaining instances were skipped")
+
+// ErrVersionSkew is returned by an advisory run when instances aren't running the expected version.
+var ErrVersionSkew = errors.New("instances aren't running the expected version")
+
+// ErrTooManyProbeFailures is returned when the versions of more than Options.MaxProbeFailureRate of the instances
+// couldn't be read, so the run was aborted rather than acting on partial data.
+var ErrTooManyProbeFailures = errors.New("the versions of too many instances couldn't be read, the run was aborted")
+
+// ErrInterrupted is returned when the context was cancelled, e.g. by Ctrl-C, during the run. Instances which were
+// already being terminated are finished, but the remaining batches and groups are skipped.
+var ErrInterrupted = errors.New("the run was interrupted, the remaining groups were skipped")
+
+// Options configures a run of the terminator.
+type Options struct {
+	// IsDryRun logs the instances which would be terminated without terminating them.
+	IsDryRun bool
+	// Advisory warns about the groups whose instances aren't all running the expected version, and returns
+	// ErrVersionSkew if there are any, but never terminates anything, e.g. to alert on drift.
+	Advisory bool
+	// MinimumInstanceCount is the number of healthy instances a group must have before any are terminated.
+	MinimumInstanceCount int
+	// MinimumHealthyPercentage is the percentage of the desired capacity which must remain healthy.
+	MinimumHealthyPercentage float64
+	// MaxTerminations, when set, caps the number of instances terminated across all groups, nil is unlimited.
+	MaxTerminations *int
+	// TerminationBatchSize is the number of instances terminated at a time, 0 terminates them all at once.
+	TerminationBatchSize int
+	// TerminationCooldown is the time waited between batches.
+	TerminationCooldown time.Duration
+	// Rolling waits for each batch to be replaced before terminating the next.
+	Rolling bool
+	// RollingTimeout is the time waited for each batch to be replaced.
+	RollingTimeout time.Duration
+	// Canary terminates a single instance first, and stops if its replacement doesn't become healthy.
+	Canary bool
+	// CanaryTimeout is the time waited for the canary's replacement.
+	CanaryTimeout time.Duration
+	// StandbyFirst moves each batch of instances into standby before terminating them, so that the group
+	// launches their replacements while they're still running, and its capacity doesn't dip.
+	StandbyFirst bool
+	// DrainBeforeTerminate stops instances receiving new connections before terminating them.
+	DrainBeforeTerminate bool
+	// DrainMode is how instances are drained, by moving them into standby, or deregistering them from their
+	// target groups.
+	DrainMode integration.DrainMode
+	// DrainTimeout is the time waited for the connections of drained instances to complete.
+	DrainTimeout time.Duration
+	// NoReplace suspends the Launch process of each group while its instances are terminated, so that they
+	// aren't replaced until the group's instances have all been terminated. It can't be used with Rolling or
+	// Canary, which wait for replacements.
+	NoReplace bool
+	// Detach terminates instances through the auto scaling group rather than EC2.
+	Detach bool
+	// DecrementDesiredCapacity reduces the desired capacity of the group as instances are detached.
+	DecrementDesiredCapacity bool
+	// CompleteLifecycleHooks continues the termination lifecycle hooks of instances waiting to terminate.
+	CompleteLifecycleHooks bool
+	// SNSTopicARN receives a notification for each terminated instance.
+	SNSTopicARN string
+	// SlackWebhookURL receives a summary of the run.
+	SlackWebhookURL string
+	// EmitMetrics publishes CloudWatch metrics for each group.
+	EmitMetrics bool
+	// MetricNamespace is the CloudWatch namespace of the metrics.
+	MetricNamespace string
+	// Scheme, Port and VersionURL locate the version endpoint of each instance.
+	Scheme     string
+	Port       int
+	VersionURL string
+	// AutoScalingGroups limits the run to the named groups, all groups are used when empty.
+	AutoScalingGroups []string
+	// ExcludeAutoScalingGroups skips the named groups.
+	ExcludeAutoScalingGroups []string
+	// Canonical is the version instances must run, or CanonicalAuto.
+	Canonical string
+	// CanonicalRange is the range of versions instances must run, it takes precedence over Canonical.
+	CanonicalRange VersionRange
+	// Mode is ModeCanonical or ModeEnforceGroupModal.
+	Mode string
+	// Direction limits the instances which are terminated to those whose version is below, or above, the
+	// version they're expected to run. Both are terminated when it's empty.
+	Direction integration.Direction
+	// SingleGroup limits each run to one group, chosen by SingleGroupSelection.
+	SingleGroup          bool
+	SingleGroupSelection string
+	// VersionSource is where instance versions are read from.
+	VersionSource integration.VersionSource
+	// VersionScheme determines how the versions of instances are compared with Canonical, the semver scheme
+	// is used when it's empty. Other schemes require a Canonical version.
+	VersionScheme integration.VersionScheme
+	// MinimumOSVersion is the OS version instances must run when VersionSource is SSM inventory.
+	MinimumOSVersion string
+	// Report collects the outcome of each group, it may be nil.
+	Report *Report
+	// Prometheus, when set, is updated with the outcome of each group.
+	Prometheus *PrometheusMetrics
+	// Plan, when set during a dry run, records what the run would do to each instance in Result.Plan.
+	Plan bool
+	// GroupOverrides replace the canonical version or minimum instance count of the named groups, e.g. during a
+	// phased rollout.
+	GroupOverrides map[string]GroupOverride
+	// FreezeWindows are the periods when nothing is terminated, even during a dry run.
+	FreezeWindows []FreezeWindow
+	// MaxProbeFailureRate, when greater than zero, is the largest fraction of instances, e.g. 0.3, whose version
+	// can fail to be read before a group is left alone, or across all of the groups, before the run is aborted,
+	// e.g. because a network issue is stopping most probes.
+	MaxProbeFailureRate float64
+	// Confirm, when set, is asked before the targets of each group are terminated, and the group is skipped
+	// unless it returns true. It isn't asked during a dry run.
+	Confirm func(group string, targets []string) bool
+}
+
+// GroupOverride replaces the options of a single group. Fields which aren't set use the value of the Options.
+type GroupOverride struct {
+	// Canonical replaces Options.Canonical when it isn't empty. CanonicalRange still takes precedence over it.
+	Canonical string
+	// MinimumInstanceCount replaces Options.MinimumInstanceCount when it isn't nil.
+	MinimumInstanceCount *int
+}
+
+// forGroup returns the options with the override of the group, if there is one, applied.
+func (p Options) forGroup(name string) Options {
+	o, ok := p.GroupOverrides[name]
+	if !ok {
+		return p
+	}
+
+	if o.Canonical != "" {
+		p.Canonical = o.Canonical
+	}
+	if o.MinimumInstanceCount != nil {
+		p.MinimumInstanceCount = *o.MinimumInstanceCount
+	}
+
+	return p
+}
+
+// VersionRange is a range of semantic versions, e.g. ">=1.2.0 <2.0.0". The zero value matches nothing
+// and is ignored.
+type VersionRange struct {
+	semver.Range
+	Expression string
+}
+
+// ParseVersionRange parses a range expression.
+func ParseVersionRange(expression string) (VersionRange, error) {
+	rng, err := semver.ParseRange(expression)
+
+	if err != nil {
+		return VersionRange{}, fmt.Errorf("invalid range %q, %v", expression, err)
+	}
+
+	return VersionRange{Range: rng, Expression: expression}, nil
+}
+
+func (r VersionRange) String() string {
+	return r.Expression
+}
+
+// Result describes a completed run.
+type Result struct {
+	// Terminated is the IDs of the instances which were terminated or, during a dry run, the IDs of the
+	// instances which would have been terminated.
+	Terminated []string
+	// Kept is the instances of the groups which were evaluated, but weren't terminated, and why.
+	Kept []KeptInstance
+	// NoActions is the groups which were left alone, and why.
+	NoActions []NoAction
+	// Summary counts what the run did.
+	Summary Summary
+	// Plan is what a dry run would do to each instance, when Options.Plan is set, otherwise it's nil.
+	Plan *Plan
+}
+
+// Summary counts the groups and instances of a run.
+type Summary struct {
+	// Groups is the number of groups which were processed.
+	Groups int
+	// Evaluated is the number of instances whose version was read.
+	Evaluated int
+	// Terminated is the number of instances which were terminated or, during a dry run, would have been.
+	Terminated int
+	// KeptForMinimum is the number of instances which were kept to leave the minimum count in their group.
+	KeptForMinimum int
+	// ProbeFailures is the number of instances whose version couldn't be read.
+	ProbeFailures int
+	// DryRun is set when nothing was terminated because the run was a dry run.
+	DryRun bool
+}
+
+// Add combines the counts of another run, e.g. in another region.
+func (s *Summary) Add(other Summary) {
+	s.Groups += other.Groups
+	s.Evaluated += other.Evaluated
+	s.Terminated += other.Terminated
+	s.KeptForMinimum += other.KeptForMinimum
+	s.ProbeFailures += other.ProbeFailures
+	s.DryRun = s.DryRun || other.DryRun
+}
+
+// NoActionReason explains why nothing was done to a group.
+type NoActionReason string
+
+// The reasons a group is left alone.
+const (
+	// NoActionBelowMinimum means the group doesn't have more healthy instances than the minimum.
+	NoActionBelowMinimum NoActionReason = "below_minimum"
+	// NoActionAllMatchCanonical means none of the group's instances need to be terminated.
+	NoActionAllMatchCanonical NoActionReason = "all_match_canonical"
+	// NoActionPartialDetails means some of the group's instances are unhealthy, or their details couldn't be
+	// read, so it isn't safe to change.
+	NoActionPartialDetails NoActionReason = "partial_details"
+	// NoActionFrozen means the run was inside a freeze window.
+	NoActionFrozen NoActionReason = "frozen"
+	// NoActionMaxTerminations means the maximum number of terminations was reached before the group.
+	NoActionMaxTerminations NoActionReason = "max_terminations"
+	// NoActionDeclined means the termination of the group's instances wasn't confirmed.
+	NoActionDeclined NoActionReason = "declined"
+	// NoActionProbeFailures means the versions of too many instances couldn't be read, see
+	// Options.MaxProbeFailureRate.
+	NoActionProbeFailures NoActionReason = "probe_failures"
+)
+
+// NoAction is a group which was left alone, and why.
+type NoAction struct {
+	// Group is the name of the group, or empty when every group was left alone, e.g. in a freeze window.
+	Group   string
+	Reason  NoActionReason
+	Message string
+}
+
+// log writes the decision, as a warning when the group may need attention.
+func (a NoAction) log() {
+	level := slog.LevelWarn
+	if a.Reason == NoActionAllMatchCanonical || a.Reason == NoActionFrozen {
+		level = slog.LevelInfo
+	}
+	slog.Log(context.Background(), level, "no action taken", "group", a.Group, "reason", string(a.Reason), "message", a.Message)
+}
+
+// KeptInstance is an instance which was deliberately kept, and the group it's in.
+type KeptInstance struct {
+	Group string
+	integration.KeepDecision
+}
+
+// Run terminates the instances of the auto scaling groups which aren't running the expected version.
+// The Result is returned alongside any error, since some instances may have been terminated before the
+// failure. ErrMaxTerminations is returned when the MaxTerminations cap was reached, and ErrVersionSkew when an
+// advisory run found instances which aren't running the expected version.
+func Run(ctx context.Context, cloud integration.CloudProvider, p Options) (Result, error) {
+	return terminate(ctx, cloud, p)
+}
+
+// terminate returns the IDs of the instances which were terminated or, during a dry run, the IDs of
+// the instances which would have been terminated, the instances which were kept, the groups which were left
+// alone, and the counts of the run.
+// They're returned alongside any error, since some instances may have been terminated before the failure.
+func terminate(ctx context.Context, cloud integration.CloudProvider, p Options) (Result, error) {
+	var maxTerminations int
+	if p.MaxTerminations != nil {
+		if *p.MaxTerminations < 0 {
+			return Result{Terminated: []string{}}, fmt.Errorf("invalid MaxTerminations %d, expected zero or more, or nil for unlimited", *p.MaxTerminations)
+		}
+		maxTerminations = *p.MaxTerminations
+	}
+
+	if p.IsDryRun {
+		slog.Info("[DRY RUN] Terminator activated. Searching for Sarah Connor...")
+	} else {
+		slog.Info("Terminator activated. Searching for Sarah Connor...")
+	}
+
+	p.Prometheus.observeRun()
+
+	var planned *Plan
+	if p.IsDryRun && p.Plan {
+		planned = &Plan{Groups: []PlanGroup{}}
+	}
+
+	if w, ok := frozen(p.FreezeWindows, now()); ok {
+		message := fmt.Sprintf("terminations are frozen during %s", w)
+		noActions := []NoAction{}
+		for _, name := range p.AutoScalingGroups {
+			noActions = append(noActions, NoAction{Group: name, Reason: NoActionFrozen, Message: message})
+		}
+		if len(noActions) == 0 {
+			noActions = append(noActions, NoAction{Reason: NoActionFrozen, Message: message})
+		}
+		for _, a := range noActions {
+			a.log()
+		}
+		if planned != nil {
+			planned.Note = message
+		}
+		return Result{Terminated: []string{}, NoActions: noActions, Summary: Summary{DryRun: p.IsDryRun}, Plan: planned}, nil
+	}
+
+	canonicalVersion, err := parseCanonical(p)
+	if err != nil {
+		return Result{Terminated: []string{}}, err
+	}
+
+	for name := range p.GroupOverrides {
+		if _, err := parseCanonical(p.forGroup(name)); err != nil {
+			return Result{Terminated: []string{}}, fmt.Errorf("failed to parse the override of %s, %v", name, err)
+		}
+	}
+
+	var minimumOSVersion semver.Version
+	if p.VersionSource == integration.VersionSourceSSMInventory {
+		minimumOSVersion, err = integration.ParseOSVersion(p.MinimumOSVersion)
+		if err != nil {
+			return Result{Terminated: []string{}}, fmt.Errorf("failed to parse minimum OS version, %v", err)
+		}
+	}
+
+	terminatedInstances := []string{}
+	kept := []KeptInstance{}
+	noActions := []NoAction{}
+	noAction := func(group string, reason NoActionReason, message string) {
+		a := NoAction{Group: group, Reason: reason, Message: message}
+		a.log()
+		noActions = append(noActions, a)
+	}
+	summary := Summary{DryRun: p.IsDryRun}
+
+	groups, err := cloud.DescribeAutoScalingGroups(
+		ctx,
+		p.AutoScalingGroups,
+		p.Scheme,
+		p.Port,
+		p.VersionURL)
+
+	if err != nil {
+		return Result{Terminated: []string{}}, fmt.Errorf("failed to get auto scaling groups, %v", err)
+	}
+
+	groups = excludeGroups(groups, p.ExcludeAutoScalingGroups, p.Report)
+
+	if p.SingleGroup {
+		groups = limitToSingleGroup(groups, p.SingleGroupSelection, canonicalVersion)
+	}
+
+	slog.Info("working on groups", "groups", getGroupNames(groups))
+
+	if failed, probed := countAllProbeFailures(groups); exceedsProbeFailureRate(failed, probed, p.MaxProbeFailureRate) {
+		message := fmt.Sprintf("%d of %d probes failed, more than the maximum rate of %v", failed, probed, p.MaxProbeFailureRate)
+		for _, g := range groups {
+			noAction(g.Name, NoActionProbeFailures, message)
+			p.Report.add(g.Name, OutcomeSkipped, message, nil, 0)
+		}
+		slog.Warn("terminator aborted", "reason", message)
+		if planned != nil {
+			planned.Note = message
+		}
+		return Result{Terminated: []string{}, NoActions: noActions, Summary: summary, Plan: planned}, ErrTooManyProbeFailures
+	}
+
+	var failures []string
+	terminatedByGroup := map[string][]string{}
+	capped := false
+	var metrics []integration.GroupMetrics
+	interrupted := false
+	var skipped []string
+	var skewed []string
+
+	for i, g := range groups {
+		if ctx.Err() != nil {
+			interrupted = true
+			skipped = getGroupNames(groups[i:])
+			for _, name := range skipped {
+				p.Report.add(name, OutcomeSkipped, "the run was interrupted", nil, 0)
+			}
+			break
+		}
+
+		groupStart := time.Now()
+		summary.Groups++
+		summary.Evaluated += len(g.InstanceDetails)
+		failed := countProbeFailures(g)
+		summary.ProbeFailures += failed
+
+		printVersionWarnings(g)
+
+		if exceedsProbeFailureRate(failed, len(g.Instances), p.MaxProbeFailureRate) {
+			message := fmt.Sprintf("%d of %d probes failed, more than the maximum rate of %v", failed, len(g.Instances), p.MaxProbeFailureRate)
+			noAction(g.Name, NoActionProbeFailures, message)
+			p.Report.add(g.Name, OutcomeSkipped, message, nil, time.Since(groupStart))
+			planned.add(g, "", nil, message)
+			continue
+		}
+
+		if p.CompleteLifecycleHooks {
+			completeLifecycleHooks(ctx, cloud, g, p.IsDryRun || p.Advisory)
+		}
+
+		gp, groupCanonical := p.forGroup(g.Name), canonicalVersion
+		if gp.Canonical != p.Canonical {
+			groupCanonical, _ = parseCanonical(gp)
+		}
+		decisions, keepDecisions, want, err := getTargets(g, gp, groupCanonical, minimumOSVersion)
+		for _, d := range decisions {
+			slog.Info("selected instance for termination", "group", g.Name, "instance", d.ID, "version", d.Version.String(), "reason", string(d.Reason))
+		}
+		targets := integration.DecisionIDs(decisions)
+		p.Report.evaluate(g, want.String())
+		keep := func(decisions ...integration.KeepDecision) {
+			for _, d := range decisions {
+				slog.Debug("kept instance", "group", g.Name, "instance", d.ID, "version", d.Version, "reason", string(d.Reason))
+				kept = append(kept, KeptInstance{Group: g.Name, KeepDecision: d})
+				if d.Reason == integration.KeepMinimumCount {
+					summary.KeptForMinimum++
+				}
+			}
+			p.Report.keep(g.Name, decisions)
+		}
+		keep(keepDecisions...)
+		mismatched := countMismatched(g.InstanceDetails, want)
+		p.Prometheus.observeGroup(g, mismatched)
+		metrics = append(metrics, integration.GroupMetrics{
+			Group:      g.Name,
+			Evaluated:  len(g.InstanceDetails),
+			Mismatched: mismatched,
+		})
+		if p.Advisory {
+			if mismatched > 0 {
+				message := fmt.Sprintf("%d of %d instances aren't running %s", mismatched, len(g.InstanceDetails), want)
+				slog.Warn("version skew detected", "group", g.Name, "mismatched", mismatched, "of", len(g.InstanceDetails), "expected", want.String())
+				p.Report.add(g.Name, OutcomeFailed, message, nil, time.Since(groupStart))
+				skewed = append(skewed, g.Name)
+			} else {
+				slog.Info("no version skew detected", "group", g.Name, "expected", want.String())
+				p.Report.add(g.Name, OutcomePassed, "no version skew", nil, time.Since(groupStart))
+			}
+			continue
+		}
+		if safetyErr, isSafetyError := err.(integration.SafetyError); isSafetyError {
+			if safetyErr.PartialDetails {
+				noAction(g.Name, NoActionPartialDetails, err.Error())
+			} else {
+				noAction(g.Name, NoActionBelowMinimum, err.Error())
+			}
+			p.Report.add(g.Name, OutcomeSkipped, err.Error(), nil, time.Since(groupStart))
+			planned.add(g, want.String(), nil, err.Error())
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to flag instances for removal", "group", g.Name, "error", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", g.Name, err))
+			p.Report.add(g.Name, OutcomeFailed, err.Error(), nil, time.Since(groupStart))
+			planned.add(g, want.String(), nil, err.Error())
+			continue
+		}
+
+		if len(targets) <= 0 {
+			noAction(g.Name, NoActionAllMatchCanonical, "no instances to terminate")
+			p.Report.add(g.Name, OutcomePassed, "no instances to terminate", nil, time.Since(groupStart))
+			planned.add(g, want.String(), nil, "")
+			continue
+		}
+
+		if remaining := maxTerminations - len(terminatedInstances); p.MaxTerminations != nil && len(targets) > remaining {
+			capped = true
+			slog.Warn("limiting the instances to stay within the maximum terminations", "group", g.Name, "targets", len(targets), "remaining", remaining, "maxTerminations", maxTerminations)
+			for _, id := range targets[remaining:] {
+				keep(integration.KeepDecision{ID: id, Reason: integration.KeepMaxTerminations, Version: versionOf(g, id)})
+			}
+			targets = targets[:remaining]
+		}
+
+		if len(targets) <= 0 {
+			noAction(g.Name, NoActionMaxTerminations, fmt.Sprintf("the maximum of %d terminations was reached", maxTerminations))
+			p.Report.add(g.Name, OutcomeSkipped, "the maximum number of terminations was reached", nil, time.Since(groupStart))
+			planned.add(g, want.String(), nil, "the maximum number of terminations was reached")
+			continue
+		}
+
+		slog.Info("terminating instances", "group", g.Name, "count", len(targets), "of", len(g.Instances), "instances", targets)
+
+		if p.IsDryRun {
+			terminatedInstances = append(terminatedInstances, targets...)
+			slog.Info("no action taken, set --isDryRun=false to execute", "group", g.Name)
+			p.Report.add(g.Name, OutcomePassed, "dry run, would terminate instances", targets, time.Since(groupStart))
+			planned.add(g, want.String(), decisions[:len(targets)], "")
+			continue
+		}
+
+		if p.Confirm != nil && !p.Confirm(g.Name, targets) {
+			noAction(g.Name, NoActionDeclined, "the termination was declined")
+			p.Report.add(g.Name, OutcomeSkipped, "the termination was declined", nil, time.Since(groupStart))
+			continue
+		}
+
+		terminated, err := terminateGroup(ctx, cloud, g, targets, want, gp)
+		terminatedInstances = append(terminatedInstances, terminated...)
+		p.Prometheus.observeTerminated(g.Name, len(terminated))
+		if len(terminated) > 0 {
+			terminatedByGroup[g.Name] = terminated
+		}
+
+		if err != nil && ctx.Err() != nil {
+			interrupted = true
+			slog.Warn("interrupted, the remaining instances of the group were skipped", "group", g.Name, "terminated", terminated)
+			p.Report.add(g.Name, OutcomeSkipped, "the run was interrupted", terminated, time.Since(groupStart))
+		} else if err != nil {
+			slog.Error("failed to terminate instances", "group", g.Name, "error", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", g.Name, err))
+			p.Report.add(g.Name, OutcomeFailed, "failed to terminate instances, "+err.Error(), targets, time.Since(groupStart))
+		} else {
+			slog.Info("complete", "group", g.Name)
+			p.Report.add(g.Name, OutcomePassed, "terminated instances", targets, time.Since(groupStart))
+		}
+	}
+
+	if interrupted {
+		slog.Warn("interrupted, the remaining groups were skipped", "terminated", terminatedInstances, "skipped", skipped)
+		// The notifications describe what was done before the interruption, so they're sent regardless.
+		ctx = context.WithoutCancel(ctx)
+	} else {
+		slog.Info("completed termination of all groups", "groups", getGroupNames(groups))
+	}
+
+	summary.Terminated = len(terminatedInstances)
+	slog.Info("summary", "groups", summary.Groups, "evaluated", summary.Evaluated, "terminated", summary.Terminated, "keptForMinimum", summary.KeptForMinimum, "probeFailures", summary.ProbeFailures, "dryRun", summary.DryRun)
+	result := Result{Terminated: terminatedInstances, Kept: kept, NoActions: noActions, Summary: summary, Plan: planned}
+
+	if p.EmitMetrics {
+		for i := range metrics {
+			metrics[i].Terminated = len(terminatedByGroup[metrics[i].Group])
+		}
+
+		if err := cloud.PutMetrics(ctx, p.MetricNamespace, metrics); err != nil {
+			slog.Warn("failed to emit metrics", "error", err)
+		}
+	}
+
+	if p.SNSTopicARN != "" && len(terminatedByGroup) > 0 {
+		if err := cloud.Notify(ctx, p.SNSTopicARN, terminatedByGroup); err != nil {
+			slog.Warn("failed to send the notification", "error", err)
+		}
+	}
+
+	if p.SlackWebhookURL != "" && len(terminatedByGroup) > 0 {
+		if err := notifySlack(ctx, p.SlackWebhookURL, formatSlackMessage(terminatedByGroup, describeExpectedVersion(p))); err != nil {
+			slog.Warn("failed to send the Slack message", "error", err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return result, fmt.Errorf("%d of %d groups failed, %s", len(failures), len(groups), strings.Join(failures, "; "))
+	}
+
+	if interrupted {
+		return result, ErrInterrupted
+	}
+
+	if len(skewed) > 0 {
+		slog.Warn("advisory run found version skew, nothing was terminated", "groups", skewed)
+		return result, ErrVersionSkew
+	}
+
+	if capped {
+		return result, ErrMaxTerminations
+	}
+
+	return result, nil
+}
+
+// countProbeFailures returns the number of instances of the group without details, because their version
+// couldn't be read. Instances which failed the health check weren't probed, so they aren't counted.
+func countProbeFailures(g integration.AutoScalingGroup) int {
+	read := map[string]bool{}
+	for _, d := range g.InstanceDetails {
+		read[d.ID] = true
+	}
+
+	failures := 0
+	for _, instance := range g.Instances {
+		if !read[instance.ID] && !instance.FailedHealthCheck {
+			failures++
+		}
+	}
+
+	return failures
+}
+
+// countAllProbeFailures returns the number of instances of the groups without details, and the number of
+// instances.
+func countAllProbeFailures(groups []integration.AutoScalingGroup) (failed int, probed int) {
+	for _, g := range groups {
+		failed += countProbeFailures(g)
+		probed += len(g.Instances)
+	}
+	return failed, probed
+}
+
+// exceedsProbeFailureRate returns true when more than the maximum rate of the probes failed. A maximum rate of
+// zero disables the check.
+func exceedsProbeFailureRate(failed int, probed int, maximum float64) bool {
+	return maximum > 0 && probed > 0 && float64(failed)/float64(probed) > maximum
+}
+
+// excludeGroups removes the groups whose names are excluded, recording them as skipped.
+func excludeGroups(groups []integration.AutoScalingGroup, excluded []string, r *Report) []integration.AutoScalingGroup {
+	if len(excluded) == 0 {
+		return groups
+	}
+
+	names := map[string]bool{}
+	for _, name := range excluded {
+		names[name] = true
+	}
+
+	included := []integration.AutoScalingGroup{}
+	for _, g := range groups {
+		if names[g.Name] {
+			slog.Info("skipping excluded group", "group", g.Name)
+			r.add(g.Name, OutcomeSkipped, "excluded", nil, 0)
+			continue
+		}
+		included = append(included, g)
+	}
+
+	return included
+}
+
+// completeLifecycleHooks completes the termination lifecycle hooks of the instances waiting in the
+// Terminating:Wait state, e.g. instances terminated by a previous run.
+func completeLifecycleHooks(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, isDryRun bool) {
+	for _, instance := range g.Instances {
+		if !instance.IsWaitingToTerminate() {
+			continue
+		}
+
+		if isDryRun {
+			slog.Info("[DRY RUN] would complete the lifecycle hooks", "group", g.Name, "instance", instance.ID)
+			continue
+		}
+
+		if err := cloud.CompleteLifecycle(ctx, g.Name, instance.ID); err != nil {
+			slog.Warn("failed to complete the lifecycle hooks", "group", g.Name, "instance", instance.ID, "error", err)
+		}
+	}
+}
+
+// countMismatched returns the number of instances which aren't running the wanted version.
+func countMismatched(details integration.InstanceDetails, want targetVersion) (count int) {
+	for _, d := range details {
+		if !want.accepts(d) {
+			count++
+		}
+	}
+	return count
+}
+
+// versionOf returns the version of the instance of the group, or an empty string if it's unknown.
+func versionOf(g integration.AutoScalingGroup, id string) string {
+	for _, d := range g.InstanceDetails {
+		if d.ID == id {
+			return d.Version()
+		}
+	}
+	return ""
+}
+
+// getHealthyInstanceDetails returns the details of the healthy instances in the group.
+func getHealthyInstanceDetails(g integration.AutoScalingGroup) integration.InstanceDetails {
+	healthy := map[string]bool{}
+	for _, instance := range g.Instances {
+		healthy[instance.ID] = g.IsHealthy(instance)
+	}
+
+	details := integration.InstanceDetails{}
+	for _, d := range g.InstanceDetails {
+		if healthy[d.ID] {
+			details = append(details, d)
+		}
+	}
+
+	return details
+}
+
+// targetVersion is the version which the instances of a group are expected to run.
+type targetVersion struct {
+	version semver.Version
+	// exact is set when instances must run exactly the version, rather than at least the version.
+	exact bool
+	// inRange, when set, accepts the versions which instances may run instead of the version.
+	inRange semver.Range
+	// expression describes the inRange range, e.g. ">=1.2.0 <2.0.0".
+	expression string
+	// comparator, when set, compares instances with the raw version instead, for versions which aren't semantic
+	// versions.
+	comparator integration.VersionComparator
+	raw        string
+}
+
+// accepts returns true if the instance is running a version which doesn't need to be terminated.
+func (t targetVersion) accepts(d integration.InstanceDetail) bool {
+	if t.comparator != nil {
+		n, err := t.comparator.Compare(d.Version(), t.raw)
+		return err == nil && n == 0
+	}
+	v := d.VersionNumber
+	if t.inRange != nil {
+		return t.inRange(v)
+	}
+	if t.exact {
+		return v.EQ(t.version)
+	}
+	return v.GTE(t.version)
+}
+
+func (t targetVersion) String() string {
+	if t.comparator != nil {
+		return t.raw
+	}
+	if t.inRange != nil {
+		return t.expression
+	}
+	if t.exact {
+		return t.version.String()
+	}
+	return ">=" + t.version.String()
+}
+
+// terminateGroup terminates the targets of the group, suspending its launches first in NoReplace mode. Launches
+// are always resumed, even when the termination failed or was interrupted.
+func terminateGroup(ctx context.Context, cloud integration.CloudProvider, g integration.AutoScalingGroup, targets []string, want targetVersion, p Options) (terminated []string, err error) {
+	if p.NoReplace {
+		processes := []string{integration.ProcessLaunch}
+		if err := cloud.SuspendProcesses(ctx, g.Name, processes); err != nil {
+			return nil, err
+		}
+		slog.Info("suspended launches", "group", g.Name)
+
+		defer func() {
+			if resumeErr := cloud.ResumeProcesses(context.WithoutCancel(ctx), g.Name, processes); resumeErr != nil {
+				slog.Error("failed to resume launches, the group won't replace instances until they're resumed", "group", g.Name, "error", resumeErr)
+				err = errors.Join(err, resumeErr)
+				return
+			}
+			slog.Info("resumed launches", "group", g.Name)
+		}()
+	}
+
+	if p.Canary {
+		return terminateWithCanary(ctx, cloud, g, targets, want, p)
+	}
+
+	return terminateInBatches(ctx, cloud, g, targets, p)
+}
+
+// getTargets selects the instances of the group to terminate according to the mode, and returns the instances
+// which are kept, and the version the remaining instances are expected to run.
+func getTargets(g integration.AutoScalingGroup, p Options, canonical semver.Version, minimumOSVersion semver.Version) ([]integration.TerminationDecision, []integration.KeepDecision, targetVersion, error) {
+	var want targetVersion
+	g.Direction = p.Direction
+
+	switch {
+	case p.Mode == ModeEnforceGroupModal:
+		want = targetVersion{version: g.InstanceDetails.ModalVersion(), exact: true}
+		slog.Info("found the most common version in the group", "group", g.Name, "version", want.version.String())
+	case p.VersionSource == integration.VersionSourceSSMInventory:
+		want = targetVersion{version: minimumOSVersion}
+	case p.CanonicalRange.Range != nil:
+		want = targetVersion{inRange: p.CanonicalRange.Range, expression: p.CanonicalRange.String()}
+	case p.Canonical == CanonicalAuto:
+		want = targetVersion{version: NewVersionDetails(getHealthyInstanceDetails(g)).Highest}
+		slog.Info("found the highest version of the healthy instances in the group", "group", g.Name, "version", want.version.String())
+	case !p.VersionScheme.IsSemver():
+		want = targetVersion{comparator: p.VersionScheme.Comparator(), raw: p.Canonical}
+	default:
+		want = targetVersion{version: canonical, exact: true}
+	}
+
+	if want.comparator != nil {
+		targets, kept, err := g.GetMismatchedInstances(want.raw, want.comparator, p.MinimumInstanceCount, p.MinimumHealthyPercentage)
+		return targets, kept, want, err
+	}
+
+	if want.inRange != nil {
+		targets, kept, err := g.GetInstancesOutsideRange(want.inRange, p.MinimumInstanceCount, p.MinimumHealthyPercentage)
+		return targets, kept, want, err
+	}
+
+	if want.exact {
+		targets, kept, err := g.GetTargetInstances(want.version, p.MinimumInstanceCount, p.MinimumHealthyPercentage)
+		return targets, kept, want, err
+	}
+
+	targets, kept, err := g.GetOutdatedInstances(want.version, p.MinimumInstanceCount, p.MinimumHealthyPercentage)
+	return targets, kept, want, err
+}
+
+// parseCanonical returns the canonical version of the options, when it's a semantic version, checking it's
+// valid in the version scheme otherwise.
+func parseCanonical(p Options) (semver.Version, error) {
+	if !p.VersionScheme.IsSemver() {
+		if err := p.VersionScheme.Comparator().Validate(p.Canonical); err != nil {
+			return semver.Version{}, fmt.Errorf("failed to parse canonical version, %v", err)
+		}
+		return semver.Version{}, nil
+	}
+
+	if p.Canonical == CanonicalAuto {
+		return semver.Version{}, nil
+	}
+
+	v, err := semver.Make(p.Canonical)
+	if err != nil {
+		return v, fmt.Errorf("failed to parse canonical version, %v", err)
+	}
+
+	return v, nil
+}
+
+// printVersionWarnings reports the instances which returned versions that had to be coerced into semantic versions.
+func printVersionWarnings(g integration.AutoScalingGroup) {
+	for _, d := range g.InstanceDetails {
+		for _, w := range d.Warnings {
+			slog.Warn(w, "group", g.Name, "instance", d.ID, "interpretedAs", d.VersionNumber.String())
+		}
+	}
+}
+
+func getGroupNames(grps []integration.AutoScalingGroup) []string {
+	names := make([]string, len(grps))
+
+	for i, g := range grps {
+		names[i] = g.Name
+	}
+
+	return names
+}
+
+func getDetails(ctx context.Context, cloud integration.CloudProvider, instances []integration.Instance, scheme string, port int, path string) (integration.InstanceDetails, error) {
+	details := integration.InstanceDetails{}
+
+	for _, instance := range instances {
+		detail, err := cloud.GetDetail(ctx, instance.ID, scheme, port, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		details = append(details, *detail)
+	}
+
+	sort.Sort(details)
+
+	return details, nil
+}
+
+// TakeAtMost returns at most the first most details once they're sorted lowest version, and then oldest, first,
+// so the same instances are taken whatever order they're passed in. The details passed in aren't modified.
+func TakeAtMost(details integration.InstanceDetails, most int) integration.InstanceDetails {
+	sorted := make(integration.InstanceDetails, len(details))
+	copy(sorted, details)
+	sort.Stable(sorted)
+
+	if len(sorted) <= most {
+		return sorted
+	}
+
+	return sorted[:most]
+}
@@ -0,0 +1,1479 @@
+package terminate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate/terminatetest"
+	"github.com/blang/semver"
+)
+
+func testIsHealthy(t *testing.T) {
+	instances := []integration.Instance{
+		integration.Instance{
+			ID:             "A",
+			LifecycleState: "InService",
+			HealthStatus:   "Healthy",
+		},
+		integration.Instance{
+			ID:             "B",
+			LifecycleState: "OutOfService",
+			HealthStatus:   "Healthy",
+		},
+		integration.Instance{
+			ID:             "C",
+			LifecycleState: "InService",
+			HealthStatus:   "Unhealthy",
+		},
+	}
+
+	actual := make([]bool, len(instances))
+
+	for idx, in := range instances {
+		actual[idx] = in.IsHealthy()
+	}
+
+	expected := []bool{true, false, false}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected the health of instances %+v to be %+v, but was %+v",
+			instances,
+			expected, actual)
+	}
+}
+
+func TestSuite(t *testing.T) {
+	tests := []struct {
+		name                 string
+		customVersions       map[string]string
+		customTimes          map[string]time.Time
+		p                    Options
+		expectedTerminations []string
+		expectedNoActions    map[string]NoActionReason
+	}{
+		{
+			name:           "Given a minimum instance count of 0, remove all unmatching instances from a healthy auto scaling group.",
+			customVersions: map[string]string{},
+			p: Options{
+				MinimumInstanceCount: 0,
+				VersionURL:           "",
+				IsDryRun:             false,
+				Canonical:            "5.0.0",
+			},
+			// Group1 has an unhealthy instance, therefore group is considered unhealthy as a whole, and ignored.
+			// All instances in Group2 don't match the canonical version of 5.0.0 and are therefore terminated.
+			expectedTerminations: []string{"D", "E", "F", "G"},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionPartialDetails},
+		},
+		{
+			name:           "Only delete items in Group2, because of the filter.",
+			customVersions: map[string]string{},
+			p: Options{
+				MinimumInstanceCount: 0,
+				VersionURL:           "",
+				IsDryRun:             false,
+				AutoScalingGroups:    []string{"Group2"}, // Filter to Group2
+				Canonical:            "1.0.0",
+			},
+			// Group1 is ignored, due to the filter.
+			expectedTerminations: []string{"D", "E", "F", "G"},
+			expectedNoActions:    map[string]NoActionReason{},
+		},
+		{
+			name:           "Don't delete if isDryRun is set to true.",
+			customVersions: map[string]string{},
+			p: Options{
+				MinimumInstanceCount: 0,
+				VersionURL:           "",
+				IsDryRun:             true,
+				Canonical:            "1.0.0",
+			},
+			expectedTerminations: []string{},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionPartialDetails},
+		},
+		{
+			name:           "Don't do anything to the group if all instances match the canonical version",
+			customVersions: map[string]string{},
+			p: Options{
+				MinimumInstanceCount: 1,
+				VersionURL:           "",
+				IsDryRun:             false,
+				Canonical:            "0.0.0",
+			},
+			expectedTerminations: []string{},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionPartialDetails, "Group2": NoActionAllMatchCanonical},
+		},
+		{
+			name:           "Don't do anything to the group if you would leave the cluster unhealthy.",
+			customVersions: map[string]string{},
+			p: Options{
+				MinimumInstanceCount: 2,
+				VersionURL:           "",
+				IsDryRun:             false,
+				Canonical:            "1.0.0",
+			},
+			// Group1 only has two healthy servers.
+			// Group2 has DEFG, so it can lose 2
+			expectedTerminations: []string{"D", "E"},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionBelowMinimum},
+		},
+		{
+			name: "Delete the old versions",
+			customVersions: map[string]string{
+				"A": "1.0.0",
+				"B": "1.0.0",
+				"C": "1.0.0",
+				"D": "0.9.9",
+				"E": "1.0.0",
+				"F": "1.0.0",
+				"G": "1.0.0",
+			},
+			p: Options{
+				MinimumInstanceCount: 3,
+				VersionURL:           "/version",
+				IsDryRun:             false,
+				Canonical:            "1.0.0",
+			},
+			expectedTerminations: []string{"D"},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionBelowMinimum},
+		},
+		{
+			name: "Delete the new versions (Canonical is set to older version). Ignore any unhealthy groups.",
+			customVersions: map[string]string{
+				"A": "0.9.9",
+				"B": "1.0.0",
+				"C": "1.0.0",
+				"D": "1.0.0",
+				"E": "1.0.0",
+				"F": "1.0.0",
+				"G": "0.9.9",
+			},
+			p: Options{
+				MinimumInstanceCount: 1,
+				VersionURL:           "/version",
+				IsDryRun:             false,
+				Canonical:            "0.9.9",
+			},
+			// Group1 is ignored because C is OutOfService.
+			// G remains inservice, as it matches the canonical version.
+			expectedTerminations: []string{"D", "E", "F"},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionPartialDetails},
+		},
+		{
+			name: "Don't delete too many old versions and potentially take the service down!",
+			customVersions: map[string]string{
+				"F": "1.4.0",
+			},
+			p: Options{
+				MinimumInstanceCount: 3,
+				VersionURL:           "/version",
+				IsDryRun:             false,
+				Canonical:            "1.0.0",
+			},
+			// Group1 should be left alone completely, because all versions are equal.
+			// Group2 has 4 healthy, active servers, only one of which is running the latest version.
+			// So, only one server should be taken out... that server should be the oldest.
+			expectedTerminations: []string{"D"},
+			expectedNoActions:    map[string]NoActionReason{"Group1": NoActionBelowMinimum},
+		},
+	}
+
+	for _, test := range tests {
+		mp := terminatetest.CreateTestData(test.customVersions, test.customTimes)
+
+		// Act.
+		result, _ := terminate(context.Background(), mp, test.p)
+
+		// Assert.
+		sort.Strings(test.expectedTerminations)
+		sort.Strings(mp.TerminatedInstances)
+		if !equal(mp.TerminatedInstances, test.expectedTerminations) {
+			t.Errorf("For test \"%s\" with paramaters %+v and custom version map %v, expected %+v to be terminated, but got %+v",
+				test.name, test.p, test.customVersions, test.expectedTerminations, mp.TerminatedInstances)
+		}
+
+		noActions := map[string]NoActionReason{}
+		for _, a := range result.NoActions {
+			noActions[a.Group] = a.Reason
+		}
+		if !reflect.DeepEqual(noActions, test.expectedNoActions) {
+			t.Errorf("For test \"%s\", expected the groups to be left alone for the reasons %v, but got %v", test.name, test.expectedNoActions, noActions)
+		}
+	}
+}
+
+func equal(a []string, b []string) bool {
+	if a == nil && b == nil {
+		return true
+	}
+
+	if a == nil && b != nil {
+		return false
+	}
+
+	if a != nil && b == nil {
+		return false
+	}
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestThatInitialVersionsAreLow(t *testing.T) {
+	initial := semver.Version{}
+	any, _ := semver.Make("0.0.1")
+
+	if !any.GT(initial) {
+		t.Error("version 0.0.1 should be higher than the default of 0.0.0.")
+	}
+}
+
+func TestTakeAtMost(t *testing.T) {
+	v1, _ := semver.New("1.0.0")
+	details := integration.InstanceDetails{
+		{
+			ID:            "A",
+			VersionNumber: *v1,
+			LaunchTime:    time.Now(),
+		},
+		{
+			ID:            "B",
+			VersionNumber: *v1,
+			LaunchTime:    time.Now(),
+		},
+		{
+			ID:            "C",
+			VersionNumber: *v1,
+			LaunchTime:    time.Now(),
+		},
+	}
+
+	tests := []struct {
+		take     int
+		expected int
+	}{
+		{
+			take:     1,
+			expected: 1,
+		},
+		{
+			take:     2,
+			expected: 2,
+		},
+
+		{
+			take:     3,
+			expected: 3,
+		},
+
+		{
+			take:     4,
+			expected: 3,
+		},
+	}
+
+	for _, test := range tests {
+		result := TakeAtMost(details, test.take)
+
+		if len(result) != test.expected {
+			t.Errorf("Expected to take %d, but took %d.", test.expected, len(result))
+		}
+	}
+}
+
+func TestTakeAtMostIsDeterministicForUnsortedInput(t *testing.T) {
+	v1, _ := semver.New("1.0.0")
+	v2, _ := semver.New("1.1.0")
+	now := time.Now()
+
+	details := integration.InstanceDetails{
+		{ID: "newest", VersionNumber: *v1, LaunchTime: now},
+		{ID: "upgraded", VersionNumber: *v2, LaunchTime: now.Add(-3 * time.Hour)},
+		{ID: "oldest", VersionNumber: *v1, LaunchTime: now.Add(-2 * time.Hour)},
+		{ID: "middle", VersionNumber: *v1, LaunchTime: now.Add(-1 * time.Hour)},
+	}
+
+	tests := []struct {
+		take     int
+		expected []string
+	}{
+		{take: 1, expected: []string{"oldest"}},
+		{take: 2, expected: []string{"oldest", "middle"}},
+		{take: 3, expected: []string{"oldest", "middle", "newest"}},
+		{take: 5, expected: []string{"oldest", "middle", "newest", "upgraded"}},
+	}
+
+	for _, test := range tests {
+		result := TakeAtMost(details, test.take)
+
+		ids := []string{}
+		for _, d := range result {
+			ids = append(ids, d.ID)
+		}
+
+		if !reflect.DeepEqual(ids, test.expected) {
+			t.Errorf("Taking %d, expected %v, but got %v", test.take, test.expected, ids)
+		}
+	}
+
+	if details[0].ID != "newest" {
+		t.Errorf("Expected the input to be left in its original order, but got %v first", details[0].ID)
+	}
+}
+
+func TestSortingInstanceDetailsByTime(t *testing.T) {
+	v, _ := semver.New("1.0.0")
+
+	instanceDetails := integration.InstanceDetails{
+		integration.InstanceDetail{
+			ID:            "A",
+			VersionNumber: *v,
+			LaunchTime:    time.Now().Add(3 * time.Hour),
+		},
+		integration.InstanceDetail{
+			ID:            "B",
+			VersionNumber: *v,
+			LaunchTime:    time.Now().Add(-1 * time.Hour),
+		},
+		integration.InstanceDetail{
+			ID:            "C",
+			VersionNumber: *v,
+			LaunchTime:    time.Now(),
+		},
+	}
+
+	sort.Sort(instanceDetails)
+
+	orderedIds := make([]string, len(instanceDetails))
+	for idx, v := range instanceDetails {
+		orderedIds[idx] = v.ID
+	}
+
+	expected := []string{"B", "C", "A"}
+	if !reflect.DeepEqual(orderedIds, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, orderedIds)
+	}
+}
+
+func TestSemverParsingFromGitDescribe(t *testing.T) {
+	// Test the output from `git describe --tags --long`
+	_, err := semver.New("0.0.3-0-g03d102e")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSortingInstanceDetailsByTimeAndVersion(t *testing.T) {
+	v1, _ := semver.New("1.0.0")
+	v2, _ := semver.New("2.0.0")
+
+	instanceDetails := integration.InstanceDetails{
+		integration.InstanceDetail{
+			ID:            "A",
+			VersionNumber: *v2,
+			LaunchTime:    time.Now().Add(-3 * time.Hour),
+		},
+		integration.InstanceDetail{
+			ID:            "B",
+			VersionNumber: *v1,
+			LaunchTime:    time.Now().Add(-1 * time.Hour),
+		},
+		integration.InstanceDetail{
+			ID:            "C",
+			VersionNumber: *v1,
+			LaunchTime:    time.Now(),
+		},
+	}
+
+	sort.Sort(instanceDetails)
+
+	orderedIds := make([]string, len(instanceDetails))
+	for idx, v := range instanceDetails {
+		orderedIds[idx] = v.ID
+	}
+
+	// Should be B, C, A because:
+	//  B is v1 and an hour old
+	//  C is V1 and is seconds old
+	//  A is 3 hours hold, but is V2, so this takes precedence.
+	expected := []string{"B", "C", "A"}
+	if !reflect.DeepEqual(orderedIds, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, orderedIds)
+	}
+}
+
+func TestTerminatingOutdatedOperatingSystems(t *testing.T) {
+	// Group2 is running a mix of old and new OS images.
+	mp := terminatetest.CreateTestData(map[string]string{
+		"D": "2.0.0",
+		"E": "1.0.0",
+		"F": "20.4.0",
+		"G": "1.0.0",
+	}, nil)
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 2,
+		IsDryRun:             false,
+		Canonical:            "1.0.0",
+		VersionSource:        integration.VersionSourceSSMInventory,
+		MinimumOSVersion:     "2",
+	})
+
+	sort.Strings(mp.TerminatedInstances)
+	expected := []string{"E", "G"}
+	if !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected the instances with old OS images %+v to be terminated, but got %+v", expected, mp.TerminatedInstances)
+	}
+}
+
+func TestEnforcingTheGroupModalVersion(t *testing.T) {
+	// G is a rogue instance in an otherwise uniform group.
+	mp := terminatetest.CreateTestData(map[string]string{
+		"D": "1.0.0",
+		"E": "1.0.0",
+		"F": "1.0.0",
+		"G": "2.0.0",
+	}, nil)
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 3,
+		IsDryRun:             false,
+		Canonical:            "2.0.0",
+		Mode:                 ModeEnforceGroupModal,
+	})
+
+	expected := []string{"G"}
+	if !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected the outlier %+v to be terminated, but got %+v", expected, mp.TerminatedInstances)
+	}
+}
+
+func TestModalVersion(t *testing.T) {
+	v1, _ := semver.Make("1.0.0")
+	v2, _ := semver.Make("2.0.0")
+
+	tests := []struct {
+		name     string
+		versions []semver.Version
+		expected semver.Version
+	}{
+		{
+			name:     "One outlier.",
+			versions: []semver.Version{v1, v2, v1},
+			expected: v1,
+		},
+		{
+			name:     "Ties are broken by the highest version.",
+			versions: []semver.Version{v1, v2, v2, v1},
+			expected: v2,
+		},
+		{
+			name:     "Empty groups have no version.",
+			expected: semver.Version{},
+		},
+	}
+
+	for _, test := range tests {
+		details := integration.InstanceDetails{}
+		for _, v := range test.versions {
+			details = append(details, integration.InstanceDetail{VersionNumber: v})
+		}
+
+		if actual := details.ModalVersion(); !actual.Equals(test.expected) {
+			t.Errorf("For test \"%s\", expected %s, but got %s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestInstancesWhichAreAlreadyTerminatingDontCountTowardsTheMinimum(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		integration.AutoScalingGroup{
+			Name: "Group1",
+			Instances: []integration.Instance{
+				integration.Instance{ID: "A", LifecycleState: "InService", HealthStatus: "Healthy"},
+				integration.Instance{ID: "B", LifecycleState: "InService", HealthStatus: "Healthy"},
+				integration.Instance{ID: "C", LifecycleState: "InService", HealthStatus: "Healthy"},
+				// D was terminated by a previous run, but is still shutting down.
+				integration.Instance{ID: "D", LifecycleState: "Terminating:Wait", HealthStatus: "Unhealthy"},
+			},
+			InstanceDetails: integration.InstanceDetails{
+				integration.InstanceDetail{ID: "A"},
+				integration.InstanceDetail{ID: "B"},
+				integration.InstanceDetail{ID: "C"},
+				integration.InstanceDetail{ID: "D"},
+			},
+		},
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 2,
+		IsDryRun:             false,
+		Canonical:            "1.0.0",
+	})
+
+	// Only 3 instances are serving, so only 1 can be terminated while leaving 2.
+	expected := []string{"A"}
+	if !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected %+v to be terminated, but got %+v", expected, mp.TerminatedInstances)
+	}
+}
+
+func TestTerminationFailuresAreReturned(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{
+		"D": "1.0.0",
+		"E": "1.0.0",
+		"F": "1.0.0",
+		"G": "1.0.0",
+	}, nil)
+	mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+		return errors.New("UnauthorizedOperation")
+	}
+
+	result, err := terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 2,
+		IsDryRun:             false,
+		Canonical:            "2.0.0",
+	})
+
+	if err == nil {
+		t.Fatal("Expected the failure to terminate instances to be returned, but got no error")
+	}
+
+	if !strings.Contains(err.Error(), "Group2") || !strings.Contains(err.Error(), "UnauthorizedOperation") {
+		t.Errorf("Expected the error to name the group and the cause, but got %v", err)
+	}
+
+	if len(result.Terminated) != 0 {
+		t.Errorf("Expected no instances to be reported as terminated, but got %+v", result.Terminated)
+	}
+}
+
+func TestMaxTerminationsStopsTerminatingMidRun(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D"),
+		terminatetest.NewHealthyGroup("Group2", "1.1.0", "E", "F", "G", "H"),
+		terminatetest.NewHealthyGroup("Group3", "1.1.0", "I", "J", "K", "L"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+	maxTerminations := 4
+	result, err := terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		MaxTerminations:      &maxTerminations,
+		IsDryRun:             false,
+		Canonical:            "1.0.0",
+	})
+
+	if err != ErrMaxTerminations {
+		t.Errorf("Expected the cap to be reported, but got %v", err)
+	}
+
+	// Group1 has 3 instances terminated, leaving room for 1 in Group2, and none in Group3.
+	expected := []string{"A", "B", "C", "E"}
+	if !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected %+v to be terminated, but got %+v", expected, mp.TerminatedInstances)
+	}
+
+	if !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %+v to be returned, but got %+v", expected, result.Terminated)
+	}
+
+	if len(result.NoActions) != 1 || result.NoActions[0].Group != "Group3" || result.NoActions[0].Reason != NoActionMaxTerminations {
+		t.Errorf("Expected Group3 to be left alone because the maximum terminations was reached, but got %+v", result.NoActions)
+	}
+}
+
+func TestMaxTerminations(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxTerminations    *int
+		expectedTerminated []string
+		expectedError      bool
+	}{
+		{
+			name:               "Without a cap, every target is terminated.",
+			expectedTerminated: []string{"A", "B", "C"},
+		},
+		{
+			name:               "A cap of zero terminates nothing.",
+			maxTerminations:    new(int),
+			expectedTerminated: []string{},
+			expectedError:      true,
+		},
+		{
+			name:               "A negative cap is rejected.",
+			maxTerminations:    func() *int { n := -1; return &n }(),
+			expectedTerminated: []string{},
+			expectedError:      true,
+		},
+	}
+
+	for _, test := range tests {
+		groups := []integration.AutoScalingGroup{
+			terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D"),
+		}
+		mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+		result, err := Run(context.Background(), mp, Options{
+			MinimumInstanceCount: 1,
+			MaxTerminations:      test.maxTerminations,
+			IsDryRun:             false,
+			Canonical:            "1.0.0",
+		})
+
+		if (err != nil) != test.expectedError {
+			t.Errorf("For test \"%s\", expected an error %v, but got %v", test.name, test.expectedError, err)
+		}
+
+		if !reflect.DeepEqual(result.Terminated, test.expectedTerminated) {
+			t.Errorf("For test \"%s\", expected %v to be terminated, but got %v", test.name, test.expectedTerminated, result.Terminated)
+		}
+	}
+}
+
+func TestRunReturnsTheKeptInstances(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C"),
+		terminatetest.NewHealthyGroup("Group2", "1.1.0", "D", "E", "F"),
+		terminatetest.NewHealthyGroup("Group3", "1.0.0", "G"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+	maxTerminations := 3
+	result, err := Run(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		MaxTerminations:      &maxTerminations,
+		IsDryRun:             true,
+		Canonical:            "1.0.0",
+	})
+
+	if err != ErrMaxTerminations {
+		t.Errorf("Expected the cap to be reported, but got %v", err)
+	}
+
+	kept := map[string]integration.KeepReason{}
+	for _, k := range result.Kept {
+		kept[k.Group+"/"+k.ID] = k.Reason
+	}
+
+	expected := map[string]integration.KeepReason{
+		"Group1/C": integration.KeepMinimumCount,
+		"Group2/E": integration.KeepMaxTerminations,
+		"Group2/F": integration.KeepMinimumCount,
+		"Group3/G": integration.KeepVersionMatches,
+	}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("Expected %v, but got %v", expected, kept)
+	}
+}
+
+func TestRunSummarisesTheGroups(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C"),
+		terminatetest.NewHealthyGroup("Group2", "1.0.0", "D", "E"),
+		terminatetest.NewHealthyGroup("Group3", "1.0.0", "F", "G", "H"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.0.0", nil, time.Now(), nil)
+	describe := mp.DescribeAutoScalingGroupsFunc
+	mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+		groups, err := describe(ctx, names, scheme, port, path)
+		// The version of H couldn't be read.
+		groups[2].InstanceDetails = groups[2].InstanceDetails[:2]
+		groups[2].AllowPartialDetails = true
+		return groups, err
+	}
+
+	result, err := Run(context.Background(), mp, Options{
+		MinimumInstanceCount: 2,
+		Canonical:            "1.1.0",
+		Mode:                 ModeCanonical,
+		IsDryRun:             true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A is terminated from Group1, and the rest of the instances are kept to leave 2 in each group, apart from
+	// H, whose version is unknown.
+	expected := Summary{
+		Groups:         3,
+		Evaluated:      7,
+		Terminated:     1,
+		KeptForMinimum: 6,
+		ProbeFailures:  1,
+		DryRun:         true,
+	}
+
+	if !reflect.DeepEqual(result.Summary, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, result.Summary)
+	}
+}
+
+func TestTooManyProbeFailures(t *testing.T) {
+	tests := []struct {
+		name              string
+		rate              float64
+		expectedErr       error
+		expectedGroups    []string
+		expectedNoActions map[string]NoActionReason
+	}{
+		{
+			name:           "no maximum",
+			expectedGroups: []string{"Group1", "Group2"},
+		},
+		{
+			name:           "under the maximum",
+			rate:           0.5,
+			expectedGroups: []string{"Group1", "Group2"},
+		},
+		{
+			name:              "over the maximum for a group",
+			rate:              0.3,
+			expectedGroups:    []string{"Group2"},
+			expectedNoActions: map[string]NoActionReason{"Group1": NoActionProbeFailures},
+		},
+		{
+			name:              "over the maximum across the groups",
+			rate:              0.1,
+			expectedErr:       ErrTooManyProbeFailures,
+			expectedNoActions: map[string]NoActionReason{"Group1": NoActionProbeFailures, "Group2": NoActionProbeFailures},
+		},
+	}
+
+	for _, test := range tests {
+		groups := []integration.AutoScalingGroup{
+			terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C", "D", "E"),
+			terminatetest.NewHealthyGroup("Group2", "1.0.0", "F", "G", "H", "I", "J"),
+		}
+		mp := terminatetest.NewMockProvider(groups, "1.0.0", nil, time.Now(), nil)
+		describe := mp.DescribeAutoScalingGroupsFunc
+		mp.DescribeAutoScalingGroupsFunc = func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+			groups, err := describe(ctx, names, scheme, port, path)
+			// The versions of D and E couldn't be read, 2 of the 5 instances in Group1, and 2 of the 10 overall.
+			groups[0].InstanceDetails = groups[0].InstanceDetails[:3]
+			groups[0].AllowPartialDetails = true
+			return groups, err
+		}
+
+		result, err := Run(context.Background(), mp, Options{
+			MinimumInstanceCount: 1,
+			Canonical:            "1.1.0",
+			Mode:                 ModeCanonical,
+			MaxProbeFailureRate:  test.rate,
+		})
+
+		if err != test.expectedErr {
+			t.Errorf("%s: expected error %v, but got %v", test.name, test.expectedErr, err)
+		}
+
+		terminatedGroups := []string{}
+		for _, id := range mp.TerminatedInstances {
+			group := "Group1"
+			if id >= "F" {
+				group = "Group2"
+			}
+			if len(terminatedGroups) == 0 || terminatedGroups[len(terminatedGroups)-1] != group {
+				terminatedGroups = append(terminatedGroups, group)
+			}
+		}
+		if test.expectedGroups == nil {
+			test.expectedGroups = []string{}
+		}
+		if !reflect.DeepEqual(terminatedGroups, test.expectedGroups) {
+			t.Errorf("%s: expected instances to be terminated in %v, but got %v", test.name, test.expectedGroups, mp.TerminatedInstances)
+		}
+
+		noActions := map[string]NoActionReason{}
+		for _, a := range result.NoActions {
+			noActions[a.Group] = a.Reason
+		}
+		if test.expectedNoActions == nil {
+			test.expectedNoActions = map[string]NoActionReason{}
+		}
+		if !reflect.DeepEqual(noActions, test.expectedNoActions) {
+			t.Errorf("%s: expected %v to be left alone, but got %+v", test.name, test.expectedNoActions, result.NoActions)
+		}
+	}
+}
+
+func TestGroupOverridesReplaceTheCanonicalVersionAndMinimumCount(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C"),
+		terminatetest.NewHealthyGroup("Group2", "1.0.0", "D", "E", "F"),
+		terminatetest.NewHealthyGroup("Group3", "1.0.0", "G", "H", "I"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.0.0", nil, time.Now(), nil)
+	two := 2
+
+	result, err := Run(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		Canonical:            "1.0.0",
+		Mode:                 ModeCanonical,
+		GroupOverrides: map[string]GroupOverride{
+			"Group2": {Canonical: "1.1.0"},
+			"Group3": {Canonical: "1.1.0", MinimumInstanceCount: &two},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(result.Terminated)
+	if expected := []string{"D", "E", "G"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, result.Terminated)
+	}
+}
+
+func TestInvalidGroupOverridesFailBeforeAnythingIsTerminated(t *testing.T) {
+	mp := terminatetest.NewMockProvider([]integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B"),
+	}, "1.0.0", nil, time.Now(), nil)
+
+	_, err := Run(context.Background(), mp, Options{
+		MinimumInstanceCount: 1,
+		Canonical:            "1.1.0",
+		Mode:                 ModeCanonical,
+		GroupOverrides:       map[string]GroupOverride{"Group1": {Canonical: "latest"}},
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "Group1") {
+		t.Errorf("Expected an error describing the override, but got %v", err)
+	}
+	if len(mp.TerminatedInstances) > 0 {
+		t.Errorf("Expected nothing to be terminated, but got %v", mp.TerminatedInstances)
+	}
+}
+
+func TestMinimumHealthyPercentage(t *testing.T) {
+	tests := []struct {
+		name                     string
+		minimumInstanceCount     int
+		minimumHealthyPercentage float64
+		expected                 int
+	}{
+		{
+			name:                     "Half of a group of 10 leaves 5.",
+			minimumInstanceCount:     1,
+			minimumHealthyPercentage: 50,
+			expected:                 5,
+		},
+		{
+			name:                     "Partial instances are rounded up.",
+			minimumInstanceCount:     1,
+			minimumHealthyPercentage: 25,
+			expected:                 7,
+		},
+		{
+			name:                     "A larger absolute minimum is used instead of the percentage.",
+			minimumInstanceCount:     8,
+			minimumHealthyPercentage: 50,
+			expected:                 2,
+		},
+		{
+			name:                     "Without a percentage, the absolute minimum is used.",
+			minimumInstanceCount:     3,
+			minimumHealthyPercentage: 0,
+			expected:                 7,
+		},
+	}
+
+	for _, test := range tests {
+		groups := []integration.AutoScalingGroup{
+			terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D", "E", "F", "G", "H", "I", "J"),
+		}
+		mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+		terminate(context.Background(), mp, Options{
+			MinimumInstanceCount:     test.minimumInstanceCount,
+			MinimumHealthyPercentage: test.minimumHealthyPercentage,
+			IsDryRun:                 false,
+			Canonical:                "1.0.0",
+		})
+
+		if len(mp.TerminatedInstances) != test.expected {
+			t.Errorf("For test \"%s\", expected %d instances to be terminated, but got %+v", test.name, test.expected, mp.TerminatedInstances)
+		}
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	tests := []struct {
+		name      string
+		isDryRun  bool
+		canonical string
+		expected  map[string][]string
+	}{
+		{
+			name:      "Terminated instances are published.",
+			isDryRun:  false,
+			canonical: "2.0.0",
+			expected:  map[string][]string{"Group2": {"D", "E", "F"}},
+		},
+		{
+			name:      "Nothing is published during a dry run.",
+			isDryRun:  true,
+			canonical: "2.0.0",
+			expected:  nil,
+		},
+		{
+			name:      "Nothing is published when no instances were terminated.",
+			isDryRun:  false,
+			canonical: "1.0.0",
+			expected:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.0.0", "G": "1.0.0"}, nil)
+
+		var actual map[string][]string
+		mp.NotifyFunc = func(ctx context.Context, topicARN string, terminated map[string][]string) error {
+			actual = terminated
+			return nil
+		}
+
+		terminate(context.Background(), mp, Options{
+			MinimumInstanceCount: 1,
+			IsDryRun:             test.isDryRun,
+			Canonical:            test.canonical,
+			SNSTopicARN:          "arn:aws:sns:eu-west-1:123456789012:terminator",
+		})
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("For test \"%s\", expected %v to be published, but got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestMetricsAreEmitted(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{"A": "1.0.0", "B": "1.0.0", "C": "1.0.0", "D": "1.0.0", "E": "1.0.0", "F": "2.0.0", "G": "2.0.0"}, nil)
+
+	var namespace string
+	var actual []integration.GroupMetrics
+	mp.PutMetricsFunc = func(ctx context.Context, ns string, metrics []integration.GroupMetrics) error {
+		namespace, actual = ns, metrics
+		return nil
+	}
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 2,
+		IsDryRun:             false,
+		Canonical:            "2.0.0",
+		EmitMetrics:          true,
+		MetricNamespace:      "Terminator",
+	})
+
+	// Group1 is skipped because C is out of service, Group2 has D and E terminated.
+	expected := []integration.GroupMetrics{
+		{Group: "Group1", Evaluated: 3, Mismatched: 3, Terminated: 0},
+		{Group: "Group2", Evaluated: 4, Mismatched: 2, Terminated: 2},
+	}
+
+	if namespace != "Terminator" || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %+v in the Terminator namespace, but got %+v in %q", expected, actual, namespace)
+	}
+}
+
+func TestExcludingGroups(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("web-asg", "1.1.0", "A", "B"),
+		terminatetest.NewHealthyGroup("bastion-asg", "1.1.0", "C", "D"),
+		terminatetest.NewHealthyGroup("api-asg", "1.1.0", "E", "F"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+	r := &Report{}
+
+	terminate(context.Background(), mp, Options{
+		MinimumInstanceCount:     1,
+		IsDryRun:                 false,
+		Canonical:                "1.0.0",
+		ExcludeAutoScalingGroups: []string{"bastion-asg"},
+		Report:                   r,
+	})
+
+	expected := []string{"A", "E"}
+	if !reflect.DeepEqual(mp.TerminatedInstances, expected) {
+		t.Errorf("Expected %v to be terminated, but got %v", expected, mp.TerminatedInstances)
+	}
+
+	if len(r.groups) != 3 || r.groups[0].Name != "bastion-asg" || r.groups[0].Outcome != OutcomeSkipped {
+		t.Errorf("Expected the excluded group to be reported as skipped, but got %+v", r.groups)
+	}
+}
+
+func TestSortingInstanceDetailsIsDeterministic(t *testing.T) {
+	v1, _ := semver.Make("1.0.0")
+	v2, _ := semver.Make("2.0.0")
+	v3, _ := semver.Make("3.0.0")
+	now := time.Now()
+
+	details := integration.InstanceDetails{
+		{ID: "A", VersionNumber: v2, LaunchTime: now.Add(-1 * time.Hour)},
+		{ID: "B", VersionNumber: v1, LaunchTime: now},
+		{ID: "C", VersionNumber: v3, LaunchTime: now.Add(-5 * time.Hour)},
+		{ID: "D", VersionNumber: v1, LaunchTime: now.Add(-2 * time.Hour)},
+		{ID: "E", VersionNumber: v2, LaunchTime: now.Add(-3 * time.Hour)},
+		{ID: "F", VersionNumber: v1, LaunchTime: now.Add(-4 * time.Hour)},
+	}
+
+	// Oldest first within each version.
+	expected := []string{"F", "D", "B", "E", "A", "C"}
+
+	// Every starting order sorts to the same result.
+	for offset := 0; offset < len(details); offset++ {
+		rotated := append(append(integration.InstanceDetails{}, details[offset:]...), details[:offset]...)
+		sort.Sort(rotated)
+
+		actual := make([]string, len(rotated))
+		for i, d := range rotated {
+			actual[i] = d.ID
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Starting from offset %d, expected %v, but got %v", offset, expected, actual)
+		}
+	}
+
+	// Less is a strict weak ordering.
+	for i := range details {
+		for j := range details {
+			if details.Less(i, j) && details.Less(j, i) {
+				t.Errorf("%s and %s are both less than each other", details[i].ID, details[j].ID)
+			}
+		}
+	}
+}
+
+func TestTerminationsNeverExceedTheMinimumInstanceCount(t *testing.T) {
+	for minimum := 0; minimum <= 5; minimum++ {
+		mp := terminatetest.CreateTestData(map[string]string{
+			"D": "0.9.0",
+			"E": "0.9.0",
+			"F": "0.9.0",
+			"G": "0.9.0",
+		}, nil)
+
+		terminate(context.Background(), mp, Options{
+			MinimumInstanceCount: minimum,
+			IsDryRun:             false,
+			Canonical:            "1.0.0",
+			AutoScalingGroups:    []string{"Group2"},
+		})
+
+		// Group2 has 4 instances.
+		maximum := 4 - minimum
+		if maximum < 0 {
+			maximum = 0
+		}
+
+		if len(mp.TerminatedInstances) > maximum {
+			t.Errorf("With a minimum of %d, expected at most %d instances to be terminated, but got %v",
+				minimum, maximum, mp.TerminatedInstances)
+		}
+	}
+}
+
+func TestAutoCanonicalTargetsTheHighestVersionInEachGroup(t *testing.T) {
+	tests := []struct {
+		name                 string
+		customVersions       map[string]string
+		expectedTerminations []string
+	}{
+		{
+			name: "Group2 is fully up to date.",
+			customVersions: map[string]string{
+				"D": "1.2.0",
+				"E": "1.2.0",
+				"F": "1.2.0",
+				"G": "1.2.0",
+			},
+			expectedTerminations: []string{},
+		},
+		{
+			name: "Group2 is mid-upgrade.",
+			customVersions: map[string]string{
+				"D": "1.1.0",
+				"E": "1.2.0",
+				"F": "1.1.0",
+				"G": "1.2.0",
+			},
+			expectedTerminations: []string{"D", "F"},
+		},
+	}
+
+	for _, test := range tests {
+		mp := terminatetest.CreateTestData(test.customVersions, nil)
+
+		terminate(context.Background(), mp, Options{
+			MinimumInstanceCount: 2,
+			IsDryRun:             false,
+			Canonical:            CanonicalAuto,
+		})
+
+		sort.Strings(mp.TerminatedInstances)
+		if !reflect.DeepEqual(mp.TerminatedInstances, test.expectedTerminations) {
+			t.Errorf("For test \"%s\", expected %v to be terminated, but got %v", test.name, test.expectedTerminations, mp.TerminatedInstances)
+		}
+	}
+}
+
+func TestCompletingLifecycleHooks(t *testing.T) {
+	tests := []struct {
+		name                   string
+		completeLifecycleHooks bool
+		isDryRun               bool
+		expected               []string
+	}{
+		{
+			name:                   "Instances waiting to terminate have their hooks completed.",
+			completeLifecycleHooks: true,
+			expected:               []string{"Group1/D"},
+		},
+		{
+			name:                   "Dry runs don't complete hooks.",
+			completeLifecycleHooks: true,
+			isDryRun:               true,
+		},
+		{
+			name: "Hooks aren't completed unless enabled.",
+		},
+	}
+
+	for _, test := range tests {
+		g := terminatetest.NewHealthyGroup("Group1", "1.0.0", "A", "B", "C")
+		g.Instances = append(g.Instances,
+			integration.Instance{ID: "D", LifecycleState: "Terminating:Wait", HealthStatus: "Unhealthy"},
+			integration.Instance{ID: "E", LifecycleState: "Terminating", HealthStatus: "Unhealthy"})
+		mp := terminatetest.NewMockProvider([]integration.AutoScalingGroup{g}, "1.0.0", nil, time.Now(), nil)
+
+		var completed []string
+		mp.CompleteLifecycleFunc = func(ctx context.Context, groupName string, instanceID string) error {
+			completed = append(completed, groupName+"/"+instanceID)
+			return nil
+		}
+
+		_, err := terminate(context.Background(), mp, Options{
+			MinimumInstanceCount:   1,
+			IsDryRun:               test.isDryRun,
+			Canonical:              "1.0.0",
+			CompleteLifecycleHooks: test.completeLifecycleHooks,
+		})
+
+		if err != nil {
+			t.Errorf("For test \"%s\", unexpected error %v", test.name, err)
+		}
+
+		if !reflect.DeepEqual(completed, test.expected) {
+			t.Errorf("For test \"%s\", expected %v to be completed, but got %v", test.name, test.expected, completed)
+		}
+	}
+}
+
+func TestCanonicalRange(t *testing.T) {
+	g := integration.AutoScalingGroup{Name: "Group1"}
+	for id, version := range map[string]string{"A": "1.1.9", "B": "1.2.0", "C": "1.9.3", "D": "2.0.0", "E": "1.5.0"} {
+		g.Instances = append(g.Instances, integration.Instance{ID: id, LifecycleState: "InService", HealthStatus: "Healthy"})
+		g.InstanceDetails = append(g.InstanceDetails, integration.InstanceDetail{ID: id, VersionNumber: semver.MustParse(version)})
+	}
+	sort.Sort(g.InstanceDetails)
+	mp := terminatetest.NewMockProvider([]integration.AutoScalingGroup{g}, "1.0.0", nil, time.Now(), nil)
+
+	r, err := ParseVersionRange(">=1.2.0 <2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := terminate(context.Background(), mp, Options{
+		MinimumInstanceCount: 3,
+		IsDryRun:             true,
+		Canonical:            "1.0.0",
+		CanonicalRange:       r,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(result.Terminated)
+	if expected := []string{"A", "D"}; !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected only the instances outside the range %v to be terminated, but got %v", expected, result.Terminated)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name               string
+		isDryRun           bool
+		answer             bool
+		expectedAsked      []string
+		expectedTerminated []string
+	}{
+		{
+			name:               "Accepted groups are terminated.",
+			answer:             true,
+			expectedAsked:      []string{"Group2"},
+			expectedTerminated: []string{"D", "E"},
+		},
+		{
+			name:               "Declined groups are skipped.",
+			answer:             false,
+			expectedAsked:      []string{"Group2"},
+			expectedTerminated: []string{},
+		},
+		{
+			name:               "The operator isn't asked during a dry run.",
+			isDryRun:           true,
+			expectedTerminated: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.0.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+		r := &Report{}
+
+		var asked []string
+		terminate(context.Background(), mp, Options{
+			IsDryRun:             test.isDryRun,
+			AutoScalingGroups:    []string{"Group2"},
+			MinimumInstanceCount: 2,
+			Canonical:            "1.1.0",
+			Report:               r,
+			Confirm: func(group string, targets []string) bool {
+				asked = append(asked, group)
+				return test.answer
+			},
+		})
+
+		if !reflect.DeepEqual(asked, test.expectedAsked) {
+			t.Errorf("For test \"%s\", expected to ask about %v, but asked about %v", test.name, test.expectedAsked, asked)
+		}
+		sort.Strings(mp.TerminatedInstances)
+		if !reflect.DeepEqual(mp.TerminatedInstances, test.expectedTerminated) {
+			t.Errorf("For test \"%s\", expected %v to be terminated, but got %v", test.name, test.expectedTerminated, mp.TerminatedInstances)
+		}
+		if !test.answer && !test.isDryRun && r.groups[0].Outcome != OutcomeSkipped {
+			t.Errorf("For test \"%s\", expected the declined group to be skipped, but got %v", test.name, r.groups[0].Outcome)
+		}
+	}
+}
+
+func TestInterruptingStopsBeforeTheNextGroup(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D"),
+		terminatetest.NewHealthyGroup("Group2", "1.1.0", "E", "F", "G", "H"),
+		terminatetest.NewHealthyGroup("Group3", "1.1.0", "I", "J", "K", "L"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls [][]string
+	mp.TerminateInstancesFunc = func(callCtx context.Context, instanceIDs []string) error {
+		calls = append(calls, instanceIDs)
+		cancel()
+		if callCtx.Err() != nil {
+			t.Errorf("Expected the termination in progress not to be cancelled")
+		}
+		return nil
+	}
+
+	r := &Report{}
+	result, err := terminate(ctx, mp, Options{
+		MinimumInstanceCount: 1,
+		Canonical:            "1.0.0",
+		Report:               r,
+	})
+
+	if err != ErrInterrupted {
+		t.Errorf("Expected the interruption to be reported, but got %v", err)
+	}
+
+	expected := []string{"A", "B", "C"}
+	if !reflect.DeepEqual(calls, [][]string{expected}) || !reflect.DeepEqual(result.Terminated, expected) {
+		t.Errorf("Expected only Group1 to be terminated, but got the calls %v", calls)
+	}
+
+	outcomes := map[string]string{}
+	for _, g := range r.groups {
+		outcomes[g.Name] = g.Outcome
+	}
+	expectedOutcomes := map[string]string{"Group1": OutcomePassed, "Group2": OutcomeSkipped, "Group3": OutcomeSkipped}
+	if !reflect.DeepEqual(outcomes, expectedOutcomes) {
+		t.Errorf("Expected the outcomes %v, but got %v", expectedOutcomes, outcomes)
+	}
+}
+
+func TestNoReplaceSuspendsLaunchesWhileTerminating(t *testing.T) {
+	tests := []struct {
+		name         string
+		terminateErr error
+	}{
+		{name: "Termination succeeds."},
+		{name: "Termination fails.", terminateErr: errors.New("UnauthorizedOperation")},
+	}
+
+	for _, test := range tests {
+		var calls []string
+		mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+		mp.SuspendProcessesFunc = func(ctx context.Context, groupName string, processes []string) error {
+			calls = append(calls, "suspend "+groupName+" "+strings.Join(processes, ","))
+			return nil
+		}
+		mp.TerminateInstancesFunc = func(ctx context.Context, instanceIDs []string) error {
+			calls = append(calls, "terminate "+strings.Join(instanceIDs, ","))
+			return test.terminateErr
+		}
+		mp.ResumeProcessesFunc = func(ctx context.Context, groupName string, processes []string) error {
+			calls = append(calls, "resume "+groupName+" "+strings.Join(processes, ","))
+			return nil
+		}
+
+		_, err := Run(context.Background(), mp, Options{
+			AutoScalingGroups:    []string{"Group2"},
+			MinimumInstanceCount: 3,
+			Canonical:            "1.1.0",
+			Mode:                 ModeCanonical,
+			NoReplace:            true,
+		})
+
+		if (err != nil) != (test.terminateErr != nil) {
+			t.Errorf("For test \"%s\", expected the error %v, but got %v", test.name, test.terminateErr, err)
+		}
+
+		expected := []string{"suspend Group2 Launch", "terminate D", "resume Group2 Launch"}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("For test \"%s\", expected %v, but got %v", test.name, expected, calls)
+		}
+	}
+}
+
+func TestNoReplaceDoesNotTerminateWhenLaunchesCannotBeSuspended(t *testing.T) {
+	mp := terminatetest.CreateTestData(map[string]string{"D": "1.0.0", "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+	mp.SuspendProcessesFunc = func(ctx context.Context, groupName string, processes []string) error {
+		return errors.New("AccessDenied")
+	}
+	resumed := false
+	mp.ResumeProcessesFunc = func(ctx context.Context, groupName string, processes []string) error {
+		resumed = true
+		return nil
+	}
+
+	_, err := Run(context.Background(), mp, Options{
+		AutoScalingGroups:    []string{"Group2"},
+		MinimumInstanceCount: 3,
+		Canonical:            "1.1.0",
+		Mode:                 ModeCanonical,
+		NoReplace:            true,
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Expected the failure to suspend launches to be reported, but got %v", err)
+	}
+
+	if len(mp.TerminatedInstances) > 0 || resumed {
+		t.Errorf("Expected nothing to be terminated or resumed, but got %v and %v", mp.TerminatedInstances, resumed)
+	}
+}
+
+func TestInterruptingStopsBeforeTheNextBatch(t *testing.T) {
+	groups := []integration.AutoScalingGroup{
+		terminatetest.NewHealthyGroup("Group1", "1.1.0", "A", "B", "C", "D"),
+	}
+	mp := terminatetest.NewMockProvider(groups, "1.1.0", nil, time.Now(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mp.TerminateInstancesFunc = func(callCtx context.Context, instanceIDs []string) error {
+		mp.TerminatedInstances = append(mp.TerminatedInstances, instanceIDs...)
+		cancel()
+		return nil
+	}
+
+	result, err := terminate(ctx, mp, Options{
+		MinimumInstanceCount: 1,
+		TerminationBatchSize: 1,
+		Canonical:            "1.0.0",
+	})
+
+	if err != ErrInterrupted {
+		t.Errorf("Expected the interruption to be reported, but got %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Terminated, []string{"A"}) || !reflect.DeepEqual(mp.TerminatedInstances, []string{"A"}) {
+		t.Errorf("Expected only the first batch to be terminated, but got %v", mp.TerminatedInstances)
+	}
+}
+
+func TestAdvisoryRunsNeverTerminate(t *testing.T) {
+	tests := []struct {
+		name             string
+		versionOfD       string
+		canonical        string
+		expectedErr      error
+		expectedOutcomes map[string]string
+	}{
+		{
+			name:             "Version skew is reported.",
+			versionOfD:       "1.0.0",
+			canonical:        "1.1.0",
+			expectedErr:      ErrVersionSkew,
+			expectedOutcomes: map[string]string{"Group1": OutcomePassed, "Group2": OutcomeFailed},
+		},
+		{
+			name:             "Groups without version skew pass.",
+			versionOfD:       "1.1.0",
+			canonical:        "1.1.0",
+			expectedOutcomes: map[string]string{"Group1": OutcomePassed, "Group2": OutcomePassed},
+		},
+	}
+
+	for _, test := range tests {
+		mp := terminatetest.CreateTestData(map[string]string{"A": "1.1.0", "B": "1.1.0", "C": "1.1.0", "D": test.versionOfD, "E": "1.1.0", "F": "1.1.0", "G": "1.1.0"}, nil)
+		r := &Report{}
+
+		result, err := terminate(context.Background(), mp, Options{
+			Advisory:               true,
+			IsDryRun:               false,
+			MinimumInstanceCount:   1,
+			CompleteLifecycleHooks: true,
+			Canonical:              test.canonical,
+			Report:                 r,
+		})
+
+		if err != test.expectedErr {
+			t.Errorf("For test \"%s\", expected the error %v, but got %v", test.name, test.expectedErr, err)
+		}
+		if len(result.Terminated) > 0 || len(mp.TerminatedInstances) > 0 {
+			t.Errorf("For test \"%s\", expected nothing to be terminated, but got %v", test.name, mp.TerminatedInstances)
+		}
+
+		outcomes := map[string]string{}
+		for _, g := range r.groups {
+			outcomes[g.Name] = g.Outcome
+		}
+		if !reflect.DeepEqual(outcomes, test.expectedOutcomes) {
+			t.Errorf("For test \"%s\", expected the outcomes %v, but got %v", test.name, test.expectedOutcomes, outcomes)
+		}
+	}
+}
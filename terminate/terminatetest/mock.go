@@ -0,0 +1,297 @@
+// Package terminatetest provides a mock CloudProvider for testing code which uses terminator.
+package terminatetest
+
+import (
+	"context"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/blang/semver"
+)
+
+// NewMockProvider creates a provider which returns the groups, and for instances looked up by GetInstanceDetails,
+// the default version and launch time, unless they have alternatives.
+func NewMockProvider(groups []integration.AutoScalingGroup,
+	defaultVersionNumber string, alternativeVersionNumbers map[string]string,
+	defaultLaunchTime time.Time, alternativeLaunchTimes map[string]time.Time) *MockProvider {
+	mp := &MockProvider{
+		DescribeAutoScalingGroupsFunc: func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+			if len(names) > 0 {
+				result := []integration.AutoScalingGroup{}
+
+				for _, n := range names {
+					for _, g := range groups {
+						if g.Name == n {
+							result = append(result, g)
+						}
+					}
+				}
+
+				return result, nil
+			}
+
+			return groups, nil
+		},
+		GetDetailFunc: func(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*integration.InstanceDetail, error) {
+			return nil, nil
+		},
+		GetInstanceDetailsFunc: func(ctx context.Context, instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (integration.InstanceDetails, error) {
+			result := integration.InstanceDetails{}
+
+			for _, instance := range instances {
+				instanceID := aws.StringValue(instance.InstanceId)
+
+				vs := defaultVersionNumber
+
+				if av, ok := alternativeVersionNumbers[instanceID]; ok {
+					vs = av
+				}
+
+				lt := defaultLaunchTime
+
+				if alt, ok := alternativeLaunchTimes[instanceID]; ok {
+					lt = alt
+				}
+
+				version, err := semver.Make(vs)
+
+				if err != nil {
+					return nil, err
+				}
+
+				detail := integration.InstanceDetail{
+					ID:            instanceID,
+					VersionNumber: version,
+					LaunchTime:    lt,
+				}
+
+				result = append(result, detail)
+			}
+
+			return result, nil
+		},
+		TerminatedInstances: []string{},
+	}
+
+	return mp
+}
+
+// MockProvider implements integration.CloudProvider with functions, so tests can replace any of its methods. By
+// default, terminated instances are recorded in TerminatedInstances.
+type MockProvider struct {
+	TerminatedInstances           []string
+	DescribeAutoScalingGroupsFunc func(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error)
+	GetInstanceDetailsFunc        func(ctx context.Context, instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (integration.InstanceDetails, error)
+	GetDetailFunc                 func(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*integration.InstanceDetail, error)
+	TerminateInstancesFunc        func(ctx context.Context, instanceIDs []string) error
+	TerminateGroupInstancesFunc   func(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error
+	CompleteLifecycleFunc         func(ctx context.Context, groupName string, instanceID string) error
+	DrainInstanceFunc             func(ctx context.Context, groupName string, instanceID string) error
+	EnterStandbyFunc              func(ctx context.Context, groupName string, instanceIDs []string) error
+	SuspendProcessesFunc          func(ctx context.Context, groupName string, processes []string) error
+	ResumeProcessesFunc           func(ctx context.Context, groupName string, processes []string) error
+	NotifyFunc                    func(ctx context.Context, topicARN string, terminated map[string][]string) error
+	PutMetricsFunc                func(ctx context.Context, namespace string, metrics []integration.GroupMetrics) error
+}
+
+func (p *MockProvider) DescribeAutoScalingGroups(ctx context.Context, names []string, scheme string, port int, path string) ([]integration.AutoScalingGroup, error) {
+	return p.DescribeAutoScalingGroupsFunc(ctx, names, scheme, port, path)
+}
+
+func (p *MockProvider) GetInstanceDetails(ctx context.Context, instances []*autoscaling.Instance, groupName string, scheme string, port int, path string) (integration.InstanceDetails, error) {
+	return p.GetInstanceDetailsFunc(ctx, instances, groupName, scheme, port, path)
+}
+
+func (p *MockProvider) GetDetail(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*integration.InstanceDetail, error) {
+	return p.GetDetailFunc(ctx, instanceID, scheme, port, endpoint)
+}
+
+func (p *MockProvider) TerminateInstances(ctx context.Context, instanceIDs []string) error {
+	if p.TerminateInstancesFunc != nil {
+		return p.TerminateInstancesFunc(ctx, instanceIDs)
+	}
+
+	p.TerminatedInstances = append(p.TerminatedInstances, instanceIDs...)
+
+	return nil
+}
+
+func (p *MockProvider) TerminateGroupInstances(ctx context.Context, instanceIDs []string, shouldDecrementDesiredCapacity bool) error {
+	if p.TerminateGroupInstancesFunc != nil {
+		return p.TerminateGroupInstancesFunc(ctx, instanceIDs, shouldDecrementDesiredCapacity)
+	}
+
+	p.TerminatedInstances = append(p.TerminatedInstances, instanceIDs...)
+
+	return nil
+}
+
+func (p *MockProvider) CompleteLifecycle(ctx context.Context, groupName string, instanceID string) error {
+	if p.CompleteLifecycleFunc != nil {
+		return p.CompleteLifecycleFunc(ctx, groupName, instanceID)
+	}
+
+	return nil
+}
+
+func (p *MockProvider) DrainInstance(ctx context.Context, groupName string, instanceID string) error {
+	if p.DrainInstanceFunc != nil {
+		return p.DrainInstanceFunc(ctx, groupName, instanceID)
+	}
+
+	return nil
+}
+
+func (p *MockProvider) EnterStandby(ctx context.Context, groupName string, instanceIDs []string) error {
+	if p.EnterStandbyFunc != nil {
+		return p.EnterStandbyFunc(ctx, groupName, instanceIDs)
+	}
+
+	return nil
+}
+
+func (p *MockProvider) SuspendProcesses(ctx context.Context, groupName string, processes []string) error {
+	if p.SuspendProcessesFunc != nil {
+		return p.SuspendProcessesFunc(ctx, groupName, processes)
+	}
+
+	return nil
+}
+
+func (p *MockProvider) ResumeProcesses(ctx context.Context, groupName string, processes []string) error {
+	if p.ResumeProcessesFunc != nil {
+		return p.ResumeProcessesFunc(ctx, groupName, processes)
+	}
+
+	return nil
+}
+
+func (p *MockProvider) Notify(ctx context.Context, topicARN string, terminated map[string][]string) error {
+	if p.NotifyFunc != nil {
+		return p.NotifyFunc(ctx, topicARN, terminated)
+	}
+
+	return nil
+}
+
+func (p *MockProvider) PutMetrics(ctx context.Context, namespace string, metrics []integration.GroupMetrics) error {
+	if p.PutMetricsFunc != nil {
+		return p.PutMetricsFunc(ctx, namespace, metrics)
+	}
+
+	return nil
+}
+
+// CreateTestData creates a provider with two groups. Group1 has instances A, B and C, where C is out of service,
+// and Group2 has the healthy instances D, E, F and G. The versions and launch times of the instances are
+// customVersions and customTimes, or 1.1.0 and now by default.
+func CreateTestData(customVersions map[string]string, customTimes map[string]time.Time) *MockProvider {
+	aVer, _ := semver.Make(customVersions["A"])
+	bVer, _ := semver.Make(customVersions["B"])
+	cVer, _ := semver.Make(customVersions["C"])
+	dVer, _ := semver.Make(customVersions["D"])
+	eVer, _ := semver.Make(customVersions["E"])
+	fVer, _ := semver.Make(customVersions["F"])
+	gVer, _ := semver.Make(customVersions["G"])
+
+	groups := []integration.AutoScalingGroup{
+		integration.AutoScalingGroup{
+			Name: "Group1",
+			Instances: []integration.Instance{
+				integration.Instance{
+					ID:             "A",
+					LifecycleState: "InService",
+					HealthStatus:   "Healthy",
+				},
+				integration.Instance{
+					ID:             "B",
+					LifecycleState: "InService",
+					HealthStatus:   "Healthy",
+				},
+				integration.Instance{
+					ID:             "C",
+					LifecycleState: "OutOfService",
+					HealthStatus:   "Healthy",
+				},
+			},
+			InstanceDetails: integration.InstanceDetails{
+				integration.InstanceDetail{
+					ID:            "A",
+					VersionNumber: aVer,
+					LaunchTime:    customTimes["A"],
+				},
+				integration.InstanceDetail{
+					ID:            "B",
+					VersionNumber: bVer,
+					LaunchTime:    customTimes["B"],
+				},
+				integration.InstanceDetail{
+					ID:            "C",
+					VersionNumber: cVer,
+					LaunchTime:    customTimes["C"],
+				},
+			},
+		},
+		integration.AutoScalingGroup{
+			Name: "Group2",
+			Instances: []integration.Instance{
+				integration.Instance{
+					ID:             "D",
+					LifecycleState: "InService",
+					HealthStatus:   "Healthy",
+				},
+				integration.Instance{
+					ID:             "E",
+					LifecycleState: "InService",
+					HealthStatus:   "Healthy",
+				},
+				integration.Instance{
+					ID:             "F",
+					LifecycleState: "InService",
+					HealthStatus:   "Healthy",
+				},
+				integration.Instance{
+					ID:             "G",
+					LifecycleState: "InService",
+					HealthStatus:   "Healthy",
+				},
+			},
+			InstanceDetails: integration.InstanceDetails{
+				integration.InstanceDetail{
+					ID:            "D",
+					VersionNumber: dVer,
+					LaunchTime:    customTimes["D"],
+				},
+				integration.InstanceDetail{
+					ID:            "E",
+					VersionNumber: eVer,
+					LaunchTime:    customTimes["E"],
+				},
+				integration.InstanceDetail{
+					ID:            "F",
+					VersionNumber: fVer,
+					LaunchTime:    customTimes["F"],
+				},
+				integration.InstanceDetail{
+					ID:            "G",
+					VersionNumber: gVer,
+					LaunchTime:    customTimes["G"],
+				},
+			},
+		},
+	}
+
+	return NewMockProvider(groups, "1.1.0", customVersions, time.Now(), customTimes)
+}
+
+// NewHealthyGroup creates a group of healthy instances which are all running the version.
+func NewHealthyGroup(name string, version string, ids ...string) integration.AutoScalingGroup {
+	g := integration.AutoScalingGroup{Name: name}
+	for _, id := range ids {
+		g.Instances = append(g.Instances, integration.Instance{ID: id, LifecycleState: "InService", HealthStatus: "Healthy"})
+		g.InstanceDetails = append(g.InstanceDetails, integration.InstanceDetail{ID: id, VersionNumber: semver.MustParse(version)})
+	}
+	return g
+}
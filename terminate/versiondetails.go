@@ -0,0 +1,68 @@
+package terminate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/blang/semver"
+)
+
+// VersionDetails summarises the spread of versions across a set of instances.
+type VersionDetails struct {
+	// Canonical is the most common version.
+	Canonical    semver.Version
+	CanonicalIDs []string
+	Highest      semver.Version
+	HighestIDs   []string
+	Lowest       semver.Version
+	LowestIDs    []string
+}
+
+// QueryVersionDetails probes all of the instances for their version, and summarises the versions found.
+func QueryVersionDetails(ctx context.Context, cloud integration.CloudProvider, instances []integration.Instance, scheme string, port int, path string) (VersionDetails, error) {
+	details, err := getDetails(ctx, cloud, instances, scheme, port, path)
+
+	if err != nil {
+		return VersionDetails{}, err
+	}
+
+	return NewVersionDetails(details), nil
+}
+
+// NewVersionDetails summarises the versions of the instances.
+func NewVersionDetails(details integration.InstanceDetails) VersionDetails {
+	vd := VersionDetails{
+		Canonical: details.ModalVersion(),
+	}
+
+	for i, d := range details {
+		if i == 0 || d.VersionNumber.GT(vd.Highest) {
+			vd.Highest = d.VersionNumber
+		}
+		if i == 0 || d.VersionNumber.LT(vd.Lowest) {
+			vd.Lowest = d.VersionNumber
+		}
+	}
+
+	for _, d := range details {
+		if d.VersionNumber.Equals(vd.Canonical) {
+			vd.CanonicalIDs = append(vd.CanonicalIDs, d.ID)
+		}
+		if d.VersionNumber.Equals(vd.Highest) {
+			vd.HighestIDs = append(vd.HighestIDs, d.ID)
+		}
+		if d.VersionNumber.Equals(vd.Lowest) {
+			vd.LowestIDs = append(vd.LowestIDs, d.ID)
+		}
+	}
+
+	return vd
+}
+
+func (vd VersionDetails) String() string {
+	return fmt.Sprintf("most common version %s %v, highest %s %v, lowest %s %v",
+		vd.Canonical, vd.CanonicalIDs,
+		vd.Highest, vd.HighestIDs,
+		vd.Lowest, vd.LowestIDs)
+}
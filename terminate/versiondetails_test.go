@@ -0,0 +1,57 @@
+package terminate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/a-h/terminator/integration"
+	"github.com/a-h/terminator/terminate/terminatetest"
+	"github.com/blang/semver"
+)
+
+func TestQueryVersionDetails(t *testing.T) {
+	versions := map[string]string{
+		"A": "1.0.0",
+		"B": "1.1.0",
+		"C": "1.0.0",
+		"D": "0.9.0",
+		"E": "1.1.0",
+		"F": "1.0.0",
+	}
+	mp := terminatetest.NewMockProvider(nil, "1.0.0", nil, time.Now(), nil)
+	mp.GetDetailFunc = func(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*integration.InstanceDetail, error) {
+		v, err := semver.Make(versions[instanceID])
+		return &integration.InstanceDetail{ID: instanceID, VersionNumber: v}, err
+	}
+
+	instances := []integration.Instance{{ID: "A"}, {ID: "B"}, {ID: "C"}, {ID: "D"}, {ID: "E"}, {ID: "F"}}
+
+	vd, err := QueryVersionDetails(context.Background(), mp, instances, "http", 80, "/version")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vd.Canonical.String() != "1.0.0" || !reflect.DeepEqual(vd.CanonicalIDs, []string{"A", "C", "F"}) {
+		t.Errorf("Expected the most common version to be 1.0.0 on A, C and F, but got %s %v", vd.Canonical, vd.CanonicalIDs)
+	}
+
+	if vd.Highest.String() != "1.1.0" || !reflect.DeepEqual(vd.HighestIDs, []string{"B", "E"}) {
+		t.Errorf("Expected the highest version to be 1.1.0 on B and E, but got %s %v", vd.Highest, vd.HighestIDs)
+	}
+
+	if vd.Lowest.String() != "0.9.0" || !reflect.DeepEqual(vd.LowestIDs, []string{"D"}) {
+		t.Errorf("Expected the lowest version to be 0.9.0 on D, but got %s %v", vd.Lowest, vd.LowestIDs)
+	}
+
+	mp.GetDetailFunc = func(ctx context.Context, instanceID string, scheme string, port int, endpoint string) (*integration.InstanceDetail, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	if _, err := QueryVersionDetails(context.Background(), mp, instances, "http", 80, "/version"); err == nil {
+		t.Error("Expected an error when an instance can't be probed.")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// versionOverrideParams collects the --versionOverrides flag, a comma-separated list of instance IDs and the
+// versions to treat them as running, e.g. i-0abc=1.2.0,i-0def=1.1.0. The flag can be repeated.
+type versionOverrideParams map[string]string
+
+func (v versionOverrideParams) String() string {
+	var overrides []string
+
+	for id, version := range v {
+		overrides = append(overrides, id+"="+version)
+	}
+	sort.Strings(overrides)
+
+	return strings.Join(overrides, ",")
+}
+
+func (v versionOverrideParams) Set(value string) error {
+	for _, override := range strings.Split(value, ",") {
+		parts := strings.SplitN(override, "=", 2)
+
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("version override %q should be in the form \"instanceID=version\"", override)
+		}
+
+		v[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVersionOverrideParams(t *testing.T) {
+	overrides := versionOverrideParams{}
+
+	if err := overrides.Set("i-1=1.2.0, i-2=1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := overrides.Set("i-3=1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := versionOverrideParams{"i-1": "1.2.0", "i-2": "1.1.0", "i-3": "1.0.0"}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected %v, but got %v", expected, overrides)
+	}
+
+	if actual := overrides.String(); actual != "i-1=1.2.0,i-2=1.1.0,i-3=1.0.0" {
+		t.Errorf("Expected the overrides to be listed in order, but got %q", actual)
+	}
+
+	for _, invalid := range []string{"i-1", "=1.2.0", "i-1="} {
+		if err := overrides.Set(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// versionRegexParam is a flag which holds a regular expression with a single capture group, which extracts the
+// version from the response of the version endpoint, e.g. `Version: (\S+)`
+type versionRegexParam struct {
+	*regexp.Regexp
+}
+
+func (r *versionRegexParam) String() string {
+	if r.Regexp == nil {
+		return ""
+	}
+	return r.Regexp.String()
+}
+
+func (r *versionRegexParam) Set(value string) error {
+	re, err := regexp.Compile(value)
+
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q, %v", value, err)
+	}
+
+	if re.NumSubexp() != 1 {
+		return fmt.Errorf("invalid pattern %q, it must have exactly one capture group, but has %d, use (?:...) for groups which shouldn't be captured", value, re.NumSubexp())
+	}
+
+	r.Regexp = re
+	return nil
+}
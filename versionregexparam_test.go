@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestVersionRegexParam(t *testing.T) {
+	var r versionRegexParam
+	if err := r.Set(`Version: (?:v)?(\S+)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.String() != `Version: (?:v)?(\S+)` {
+		t.Errorf("Expected the pattern to be kept, but got %q", r.String())
+	}
+
+	for _, invalid := range []string{`Version: \S+`, `(\d+)\.(\d+)`, `Version: (\S+`} {
+		var r versionRegexParam
+		if err := r.Set(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}